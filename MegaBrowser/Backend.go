@@ -0,0 +1,66 @@
+package megabrowser
+
+import "fmt"
+
+/*
+Backend is a storage, which the browser can work on instead of a Mega account, e.g. a public folder or a local directory, see WithBackend. Nodes of a backend are identified by hashes, whose format is up to the backend.
+
+The browser works on a Mega account through the adapter created by NewMegaBackend, so that it resolves paths and downloads files of an account the same way as of any other backend. Only Publisher works on the account client and filesystem directly, because uploads need their nodes.
+
+Children of a file node are its previous versions, which backends without version history do not have. Download transfers a file to dstPath, sending the number of written bytes to progress, if it is not nil, and closing it when finished, also if the download fails.
+*/
+type Backend interface {
+	Root() Node
+	Children(hash string) ([]Node, error)
+	Stat(hash string) (Node, error)
+	Download(hash string, dstPath string, progress *chan int) error
+}
+
+// megaBackend adapts a Mega client and its filesystem to the Backend interface.
+type megaBackend struct {
+	client      StorageClient
+	fs          Fs
+	getChildren getChildrenFunc
+}
+
+// NewMegaBackend creates a Backend for a Mega account, whose client has to be logged in already. The browser creates one for its client and filesystem, see WithClient and WithFs.
+func NewMegaBackend(client StorageClient, fs Fs) Backend {
+	return &megaBackend{
+		client:      client,
+		fs:          fs,
+		getChildren: getChildren,
+	}
+}
+
+// Root returns the root node of the account cloud drive. Returns nil, if the filesystem is not loaded.
+func (mb *megaBackend) Root() Node {
+	root := mb.fs.GetRoot()
+	if root == nil {
+		return nil
+	}
+	return describeNode(mb.fs, root)
+}
+
+func (mb *megaBackend) Children(hash string) ([]Node, error) {
+	return mb.getChildren(mb.fs, hash)
+}
+
+// Stat returns the node of given hash, whose parent is not known.
+func (mb *megaBackend) Stat(hash string) (Node, error) {
+	node := mb.fs.HashLookup(hash)
+	if node == nil {
+		return nil, fmt.Errorf("could not find node: %s", hash)
+	}
+	return describeNode(mb.fs, node), nil
+}
+
+func (mb *megaBackend) Download(hash string, dstPath string, progress *chan int) error {
+	node := mb.fs.HashLookup(hash)
+	if node == nil {
+		if progress != nil {
+			close(*progress)
+		}
+		return fmt.Errorf("could not find node: %s", hash)
+	}
+	return mb.client.DownloadFile(node, dstPath, progress)
+}
@@ -0,0 +1,109 @@
+package megabrowser
+
+import (
+	"sync"
+	"time"
+)
+
+// BandwidthLimits are download rate limits in bytes per second, 0 means no limit. Total limits all concurrent downloads together, PerFile every single download.
+type BandwidthLimits struct {
+	Total   int64
+	PerFile int64
+}
+
+/*
+Bandwidth limits the download rate of a MegaDownloader, see MegaDownloader.SetBandwidth. Normal limits apply, unless background priority is on, see SetBackground, in which case background limits apply instead, e.g. so that updates running in the background do not disturb other applications.
+
+Limits and priority may be changed at any time, also while downloads are running. Files are downloaded in chunks, so the rate is kept on average, with single chunks arriving at full speed. Cached files are not limited, see DownloadCache.
+*/
+type Bandwidth struct {
+	mutex        sync.Mutex
+	normal       BandwidthLimits
+	background   BandwidthLimits
+	inBackground bool
+	total        rateSchedule
+	now          func() time.Time
+	sleep        sleepFunc
+}
+
+// bandwidthTransfer limits the rate of a single download.
+type bandwidthTransfer struct {
+	bandwidth *Bandwidth
+	file      rateSchedule
+}
+
+// rateSchedule holds the time, until which the bytes passed so far are paid off at the limited rate.
+type rateSchedule struct {
+	next time.Time
+}
+
+// NewBandwidth creates download rate limits with given normal and background limits. Background priority is off.
+func NewBandwidth(normal BandwidthLimits, background BandwidthLimits) *Bandwidth {
+	return &Bandwidth{
+		normal:     normal,
+		background: background,
+		now:        time.Now,
+		sleep:      time.Sleep,
+	}
+}
+
+// SetLimits replaces the normal and background limits.
+func (b *Bandwidth) SetLimits(normal BandwidthLimits, background BandwidthLimits) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.normal = normal
+	b.background = background
+}
+
+// SetBackground turns background priority on or off.
+func (b *Bandwidth) SetBackground(background bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.inBackground = background
+}
+
+// Limits returns the limits in effect, which are the background ones, if background priority is on.
+func (b *Bandwidth) Limits() BandwidthLimits {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.limits()
+}
+
+func (b *Bandwidth) limits() BandwidthLimits {
+	if b.inBackground {
+		return b.background
+	}
+	return b.normal
+}
+
+// newTransfer starts limiting the rate of a single download.
+func (b *Bandwidth) newTransfer() *bandwidthTransfer {
+	return &bandwidthTransfer{bandwidth: b}
+}
+
+// wait blocks, until n downloaded bytes fit both the total and the file limit.
+func (bt *bandwidthTransfer) wait(n int) {
+	b := bt.bandwidth
+	b.mutex.Lock()
+	now := b.now()
+	limits := b.limits()
+	delay := max(b.total.reserve(now, n, limits.Total), bt.file.reserve(now, n, limits.PerFile))
+	b.mutex.Unlock()
+
+	if delay > 0 {
+		b.sleep(delay)
+	}
+}
+
+// reserve accounts n bytes at given limit and returns how long to wait, until they are paid off. Time not used by earlier transfers is not saved for later ones.
+func (rs *rateSchedule) reserve(now time.Time, n int, limit int64) time.Duration {
+	if limit <= 0 {
+		rs.next = time.Time{}
+		return 0
+	}
+	if rs.next.Before(now) {
+		rs.next = now
+	}
+	rs.next = rs.next.Add(time.Duration(int64(n) * int64(time.Second) / limit))
+	return rs.next.Sub(now)
+}
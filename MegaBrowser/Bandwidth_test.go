@@ -0,0 +1,131 @@
+package megabrowser
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBandwidthWait(t *testing.T) {
+	tests := []struct {
+		name         string
+		normal       BandwidthLimits
+		background   BandwidthLimits
+		inBackground bool
+		expDelays    []time.Duration
+	}{
+		{
+			name: "should not wait, if there are no limits",
+		},
+		{
+			name:      "should share total limit by every transfer",
+			normal:    BandwidthLimits{Total: 100},
+			expDelays: []time.Duration{500 * time.Millisecond, time.Second, 1500 * time.Millisecond},
+		},
+		{
+			name:      "should limit every transfer separately, if only file limit is set",
+			normal:    BandwidthLimits{PerFile: 100},
+			expDelays: []time.Duration{500 * time.Millisecond, 500 * time.Millisecond, time.Second},
+		},
+		{
+			name:         "should apply background limits, if background priority is on",
+			normal:       BandwidthLimits{Total: 100},
+			background:   BandwidthLimits{Total: 50},
+			inBackground: true,
+			expDelays:    []time.Duration{time.Second, 2 * time.Second, 3 * time.Second},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bandwidth := NewBandwidth(test.normal, test.background)
+			bandwidth.SetBackground(test.inBackground)
+			now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+			bandwidth.now = func() time.Time {
+				return now
+			}
+			var delays []time.Duration
+			bandwidth.sleep = func(d time.Duration) {
+				delays = append(delays, d)
+			}
+			first := bandwidth.newTransfer()
+			second := bandwidth.newTransfer()
+
+			first.wait(50)
+			second.wait(50)
+			first.wait(50)
+
+			assert.Equal(t, test.expDelays, delays)
+		})
+	}
+}
+
+func TestBandwidthSetLimitsWhileTransferring(t *testing.T) {
+	bandwidth := NewBandwidth(BandwidthLimits{Total: 100}, BandwidthLimits{})
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	bandwidth.now = func() time.Time {
+		return now
+	}
+	var delays []time.Duration
+	bandwidth.sleep = func(d time.Duration) {
+		delays = append(delays, d)
+	}
+	transfer := bandwidth.newTransfer()
+	transfer.wait(100)
+	now = now.Add(time.Second)
+
+	bandwidth.SetLimits(BandwidthLimits{Total: 200}, BandwidthLimits{Total: 10})
+	transfer.wait(100)
+	now = now.Add(500 * time.Millisecond)
+	bandwidth.SetBackground(true)
+	transfer.wait(10)
+
+	assert.Equal(t, []time.Duration{time.Second, 500 * time.Millisecond, time.Second}, delays)
+	assert.Equal(t, BandwidthLimits{Total: 10}, bandwidth.Limits())
+}
+
+func TestDownloadFromBackendWithBandwidth(t *testing.T) {
+	repository := writeLocalRepository(t)
+	bandwidth := NewBandwidth(BandwidthLimits{PerFile: int64(len("content"))}, BandwidthLimits{})
+	var slept time.Duration
+	bandwidth.sleep = func(d time.Duration) {
+		slept += d
+	}
+	var output bytes.Buffer
+	downloader := NewMegaDownloader()
+	downloader.progressOutput = &output
+	downloader.SetBandwidth(bandwidth)
+	backend := NewLocalBackend(repository)
+	node, err := backend.Stat(expDirName + "/" + expFileName)
+	require.Nil(t, err)
+
+	err = downloader.DownloadFromBackend(backend, node, filepath.Join(t.TempDir(), expFileName))
+
+	require.Nil(t, err)
+	assert.InDelta(t, time.Second, slept, float64(10*time.Millisecond))
+	assert.Equal(t, "<progress>100</progress>\n", output.String())
+}
+
+func TestNewMegaBrowserFromConfigWithBandwidth(t *testing.T) {
+	cfg, err := LoadConfig("", func(key string) string {
+		return map[string]string{
+			EnvLocalDir:   t.TempDir(),
+			EnvBandwidth:  "1000",
+			EnvBackground: "true",
+		}[key]
+	})
+	require.Nil(t, err)
+	cfg.Bandwidth.BackgroundLimit = 100
+
+	browser, err := NewMegaBrowserFromConfig(cfg)
+
+	require.Nil(t, err)
+	downloader := browser.downloader.(*MegaDownloader)
+	require.NotNil(t, downloader.bandwidth)
+	assert.Equal(t, BandwidthLimits{Total: 100}, downloader.bandwidth.Limits())
+	downloader.bandwidth.SetBackground(false)
+	assert.Equal(t, BandwidthLimits{Total: 1000}, downloader.bandwidth.Limits())
+}
@@ -0,0 +1,273 @@
+package megabrowser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	cacheObjectsDir = "objects"
+	cacheUsedDir    = "used"
+	cacheSumsDir    = "sums"
+)
+
+// cacheDirMode is the mode of directories created by the cache, which other users may read, but not modify.
+const cacheDirMode = 0755
+
+/*
+DownloadCache is a content cache shared by downloaders of several installs on one machine, see MegaDownloader.SetCache. Files are stored under a key derived from their node hash and size, so a file replaced in Mega, which gets a new hash, is never served from the cache. Only files of Mega accounts and public links are cached, because hashes of nodes of other backends, e.g. paths of a LocalBackend, do not change with their content.
+
+Files are placed at their target paths as hard links to the cached copies, if possible, and copied otherwise. A hard linked file must not be modified in place, which would modify the cached copy as well; a cache created with link set to false always copies files.
+
+The SHA256 checksum of every file is recorded when it is added, and a file placed at its target path is checked against it and its expected size, so that a cached copy modified after it was added is downloaded again instead of being used.
+
+The cache keeps its size under maxSize bytes by removing the least recently used files after every new file, maxSize 0 means no limit. The time of the last use of a file is kept apart from the file, because hard linked files share their modification time.
+
+Several processes may use the same cache directory at once: files are added by renaming complete temporary files, and a file removed while another process links it is downloaded again by that process.
+*/
+type DownloadCache struct {
+	dir      string
+	maxSize  int64
+	link     bool
+	mutex    sync.Mutex
+	linkFile linkFileFunc
+	now      func() time.Time
+}
+
+type linkFileFunc func(oldname string, newname string) error
+
+// cacheEntry is a cached file considered for eviction.
+type cacheEntry struct {
+	key      string
+	size     int64
+	lastUsed time.Time
+}
+
+// NewDownloadCache creates a cache in given directory, which is created when the first file is added.
+func NewDownloadCache(dir string, maxSize int64, link bool) *DownloadCache {
+	return &DownloadCache{
+		dir:      dir,
+		maxSize:  maxSize,
+		link:     link,
+		linkFile: os.Link,
+		now:      time.Now,
+	}
+}
+
+// cacheKey returns the key of the file of given node.
+func cacheKey(node Node) string {
+	key := sha256.Sum256([]byte(node.GetHash() + "\x00" + strconv.FormatInt(node.GetSize(), 10)))
+	return hex.EncodeToString(key[:])
+}
+
+/*
+get places the cached file of given key at dstPath and checks it against its recorded checksum, sending its size to progress. It is not closed, so that the caller can download the file, if it is not cached.
+
+Returns false if the file is not cached, its size or checksum is not the expected one, in which case it is removed from the cache and dstPath, or it could not be placed at dstPath.
+*/
+func (dc *DownloadCache) get(key string, size int64, dstPath string, progress *chan int) bool {
+	objectPath := dc.objectPath(key)
+	info, err := os.Stat(objectPath)
+	if err != nil {
+		return false
+	}
+	if info.Size() != size {
+		dc.remove(key)
+		return false
+	}
+
+	expected, err := os.ReadFile(dc.sumPath(key))
+	if err != nil {
+		dc.remove(key)
+		return false
+	}
+
+	linked := dc.link && dc.linkFile(objectPath, dstPath) == nil
+	if !linked {
+		err = copyFile(dstPath, objectPath)
+		if err != nil {
+			_ = os.Remove(dstPath)
+			return false
+		}
+	}
+	// the placed file is checked, so that a file modified after the check is never used
+	checksum, err := fileChecksum(dstPath)
+	if err != nil || checksum != string(expected) {
+		_ = os.Remove(dstPath)
+		dc.remove(key)
+		return false
+	}
+	dc.touch(key)
+	if progress != nil && size > 0 {
+		*progress <- int(size)
+	}
+	return true
+}
+
+/*
+put adds the downloaded file at srcPath to the cache under given key, recording its checksum, and evicts the least recently used files, if the cache grew over its maximum size.
+
+Returns an error if the file could not be copied into the cache directory.
+*/
+func (dc *DownloadCache) put(key string, srcPath string) error {
+	for _, dir := range []string{cacheObjectsDir, cacheUsedDir, cacheSumsDir} {
+		err := os.MkdirAll(filepath.Join(dc.dir, dir), cacheDirMode)
+		if err != nil {
+			return err
+		}
+	}
+
+	checksum, err := fileChecksum(srcPath)
+	if err != nil {
+		return err
+	}
+	err = dc.store(dc.sumPath(key), func(tmpPath string) error {
+		return os.WriteFile(tmpPath, []byte(checksum), 0644)
+	})
+	if err != nil {
+		return err
+	}
+	err = dc.store(dc.objectPath(key), func(tmpPath string) error {
+		return copyFile(tmpPath, srcPath)
+	})
+	if err != nil {
+		return err
+	}
+	dc.touch(key)
+	return dc.evict()
+}
+
+// store writes a file with write to a temporary file in the directory of dstPath and renames it to dstPath, so that other processes never see a partial file.
+func (dc *DownloadCache) store(dstPath string, write func(tmpPath string) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(dstPath), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	err = write(tmp.Name())
+	if err == nil {
+		err = os.Rename(tmp.Name(), dstPath)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// evict removes the least recently used files, until the cache is not bigger than its maximum size.
+func (dc *DownloadCache) evict() error {
+	if dc.maxSize <= 0 {
+		return nil
+	}
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	entries, total, err := dc.entries()
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].lastUsed.Before(entries[j].lastUsed)
+	})
+	for _, entry := range entries {
+		if total <= dc.maxSize {
+			break
+		}
+		dc.remove(entry.key)
+		total -= entry.size
+	}
+	return nil
+}
+
+// entries lists cached files with their total size. Temporary files of downloads in progress are skipped.
+func (dc *DownloadCache) entries() ([]cacheEntry, int64, error) {
+	files, err := os.ReadDir(filepath.Join(dc.dir, cacheObjectsDir))
+	if err != nil {
+		return nil, 0, err
+	}
+	var entries []cacheEntry
+	var total int64
+	for _, file := range files {
+		if !file.Type().IsRegular() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		info, err := file.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		entry := cacheEntry{key: file.Name(), size: info.Size(), lastUsed: info.ModTime()}
+		if used, err := os.Stat(dc.usedPath(entry.key)); err == nil {
+			entry.lastUsed = used.ModTime()
+		}
+		entries = append(entries, entry)
+		total += entry.size
+	}
+	return entries, total, nil
+}
+
+// touch records the current time as the last use of the cached file of given key.
+func (dc *DownloadCache) touch(key string) {
+	now := dc.now()
+	usedPath := dc.usedPath(key)
+	if os.Chtimes(usedPath, now, now) == nil {
+		return
+	}
+	file, err := os.Create(usedPath)
+	if err != nil {
+		return
+	}
+	file.Close()
+	_ = os.Chtimes(usedPath, now, now)
+}
+
+// remove removes the cached file of given key. Files already linked to target paths are not affected.
+func (dc *DownloadCache) remove(key string) {
+	_ = os.Remove(dc.objectPath(key))
+	_ = os.Remove(dc.usedPath(key))
+	_ = os.Remove(dc.sumPath(key))
+}
+
+func (dc *DownloadCache) objectPath(key string) string {
+	return filepath.Join(dc.dir, cacheObjectsDir, key)
+}
+
+func (dc *DownloadCache) usedPath(key string) string {
+	return filepath.Join(dc.dir, cacheUsedDir, key)
+}
+
+func (dc *DownloadCache) sumPath(key string) string {
+	return filepath.Join(dc.dir, cacheSumsDir, key)
+}
+
+// copyFile copies a file at srcPath to dstPath, replacing it if it exists.
+func copyFile(dstPath string, srcPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return err
+	}
+	err = copyWithProgress(dst, src, nil)
+	closeErr := dst.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return err
+}
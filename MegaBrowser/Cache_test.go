@@ -0,0 +1,210 @@
+package megabrowser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/t3rm1n4l/go-mega"
+)
+
+// cachingClient writes content of its node to every download and counts them.
+type cachingClient struct {
+	mockClient
+	node      *mockNode
+	content   string
+	downloads int
+}
+
+func TestDownloadFileWithCache(t *testing.T) {
+	tests := []struct {
+		name      string
+		link      bool
+		expLinked bool
+	}{
+		{
+			name:      "should link cached file, if links are enabled",
+			link:      true,
+			expLinked: true,
+		},
+		{
+			name: "should copy cached file, if links are disabled",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cache := NewDownloadCache(t.TempDir(), 0, test.link)
+			client := &cachingClient{node: &mockNode{hash: expFileHash, size: 7}, content: "content"}
+			first := newCachingDownloader(cache)
+			second := newCachingDownloader(cache)
+			firstPath := filepath.Join(t.TempDir(), "app", expFileName)
+			secondPath := filepath.Join(t.TempDir(), "app", expFileName)
+
+			require.Nil(t, first.DownloadFromBackend(NewMegaBackend(client, &mockFs{}), client.node, firstPath))
+			err := second.DownloadFromBackend(NewMegaBackend(client, &mockFs{}), client.node, secondPath)
+
+			require.Nil(t, err)
+			assert.Equal(t, 1, client.downloads)
+			content, err := os.ReadFile(secondPath)
+			require.Nil(t, err)
+			assert.Equal(t, "content", string(content))
+			cached, err := os.Stat(cache.objectPath(cacheKey(client.node)))
+			require.Nil(t, err)
+			downloaded, err := os.Stat(secondPath)
+			require.Nil(t, err)
+			assert.Equal(t, test.expLinked, os.SameFile(cached, downloaded))
+		})
+	}
+}
+
+func TestDownloadFileWithCacheDownloadsChangedFile(t *testing.T) {
+	cache := NewDownloadCache(t.TempDir(), 0, true)
+	client := &cachingClient{node: &mockNode{hash: expFileHash, size: 7}, content: "content"}
+	downloader := newCachingDownloader(cache)
+	localPath := filepath.Join(t.TempDir(), expFileName)
+	require.Nil(t, downloader.DownloadFromBackend(NewMegaBackend(client, &mockFs{}), client.node, localPath))
+
+	client.node = &mockNode{hash: "newHash", size: 11}
+	client.content = "new content"
+	err := downloader.DownloadFromBackend(NewMegaBackend(client, &mockFs{}), client.node, localPath)
+
+	require.Nil(t, err)
+	assert.Equal(t, 2, client.downloads)
+	content, err := os.ReadFile(localPath)
+	require.Nil(t, err)
+	assert.Equal(t, "new content", string(content))
+}
+
+func TestDownloadCacheGetRemovesFileOfUnexpectedSize(t *testing.T) {
+	cache := NewDownloadCache(t.TempDir(), 0, true)
+	srcPath := writeCacheSource(t, "content")
+	require.Nil(t, cache.put("key", srcPath))
+
+	ok := cache.get("key", 3, filepath.Join(t.TempDir(), "file"), nil)
+
+	assert.False(t, ok)
+	assert.NoFileExists(t, cache.objectPath("key"))
+}
+
+func TestDownloadCacheGetRemovesModifiedFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(t *testing.T, cache *DownloadCache)
+	}{
+		{
+			name: "should remove file, if its content changed",
+			modify: func(t *testing.T, cache *DownloadCache) {
+				require.Nil(t, os.WriteFile(cache.objectPath("key"), []byte("tampers"), 0644))
+			},
+		},
+		{
+			name: "should remove file, if its checksum is missing",
+			modify: func(t *testing.T, cache *DownloadCache) {
+				require.Nil(t, os.Remove(cache.sumPath("key")))
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cache := NewDownloadCache(t.TempDir(), 0, true)
+			require.Nil(t, cache.put("key", writeCacheSource(t, "content")))
+			test.modify(t, cache)
+			dstPath := filepath.Join(t.TempDir(), "file")
+
+			ok := cache.get("key", 7, dstPath, nil)
+
+			assert.False(t, ok)
+			assert.NoFileExists(t, dstPath)
+			assert.NoFileExists(t, cache.objectPath("key"))
+			assert.NoFileExists(t, cache.sumPath("key"))
+		})
+	}
+}
+
+func TestDownloadCachePutCreatesDirectoriesOthersCannotModify(t *testing.T) {
+	cache := NewDownloadCache(filepath.Join(t.TempDir(), "cache"), 0, true)
+
+	err := cache.put("key", writeCacheSource(t, "content"))
+
+	require.Nil(t, err)
+	for _, dir := range []string{"", cacheObjectsDir, cacheUsedDir, cacheSumsDir} {
+		info, err := os.Stat(filepath.Join(cache.dir, dir))
+		require.Nil(t, err)
+		assert.Zero(t, info.Mode().Perm()&0022, dir)
+	}
+}
+
+func TestDownloadCacheEvictsLeastRecentlyUsedFiles(t *testing.T) {
+	cache := NewDownloadCache(t.TempDir(), 10, true)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+	srcPath := writeCacheSource(t, "1234")
+	require.Nil(t, cache.put("first", srcPath))
+	require.Nil(t, cache.put("second", srcPath))
+	// Using the first file makes the second one the least recently used.
+	require.True(t, cache.get("first", 4, filepath.Join(t.TempDir(), "file"), nil))
+
+	err := cache.put("third", srcPath)
+
+	require.Nil(t, err)
+	assert.FileExists(t, cache.objectPath("first"))
+	assert.NoFileExists(t, cache.objectPath("second"))
+	assert.NoFileExists(t, cache.usedPath("second"))
+	assert.FileExists(t, cache.objectPath("third"))
+}
+
+func TestDownloadCacheFallsBackToCopy(t *testing.T) {
+	cache := NewDownloadCache(t.TempDir(), 0, true)
+	cache.linkFile = func(oldname string, newname string) error {
+		return os.ErrPermission
+	}
+	require.Nil(t, cache.put("key", writeCacheSource(t, "content")))
+	dstPath := filepath.Join(t.TempDir(), "file")
+
+	ok := cache.get("key", 7, dstPath, nil)
+
+	require.True(t, ok)
+	content, err := os.ReadFile(dstPath)
+	require.Nil(t, err)
+	assert.Equal(t, "content", string(content))
+}
+
+func TestNewMegaBrowserFromConfigWithCache(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Account = AccountConfig{Login: login, Password: pass}
+	cfg.RootNode = rootNodeName
+	cfg.Cache = CacheConfig{Dir: t.TempDir(), MaxSize: 1024, Copy: true}
+
+	browser, err := NewMegaBrowserFromConfig(cfg)
+
+	require.Nil(t, err)
+	downloader := browser.downloader.(*MegaDownloader)
+	require.NotNil(t, downloader.cache)
+	assert.Equal(t, int64(1024), downloader.cache.maxSize)
+	assert.False(t, downloader.cache.link)
+}
+
+func newCachingDownloader(cache *DownloadCache) *MegaDownloader {
+	downloader := NewMegaDownloader()
+	downloader.progressOutput = nil
+	downloader.SetCache(cache)
+	return downloader
+}
+
+func writeCacheSource(t *testing.T, content string) string {
+	srcPath := filepath.Join(t.TempDir(), "source")
+	require.Nil(t, os.WriteFile(srcPath, []byte(content), 0666))
+	return srcPath
+}
+
+func (c *cachingClient) DownloadFile(src *mega.Node, dstpath string, progress *chan int) error {
+	defer close(*progress)
+	c.downloads++
+	return os.WriteFile(dstpath, []byte(c.content), 0666)
+}
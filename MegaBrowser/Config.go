@@ -0,0 +1,679 @@
+package megabrowser
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/t3rm1n4l/go-mega"
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables overriding the values read from a configuration file.
+const (
+	EnvLogin         = "MEGA_LOGIN"
+	EnvPassword      = "MEGA_PASSWORD"
+	EnvCredentials   = "MEGA_CREDENTIALS_FILE"
+	EnvRootNode      = "MEGA_ROOT"
+	EnvTargetDir     = "MEGA_TARGET_DIR"
+	EnvConcurrency   = "MEGA_CONCURRENCY"
+	EnvRetryAttempts = "MEGA_RETRY_ATTEMPTS"
+	EnvRetryDelay    = "MEGA_RETRY_DELAY"
+	EnvProgress      = "MEGA_PROGRESS"
+	EnvSessionFile   = "MEGA_SESSION_FILE"
+	EnvPublicLink    = "MEGA_PUBLIC_LINK"
+	EnvAnonymous     = "MEGA_ANONYMOUS"
+	EnvLocalDir      = "MEGA_LOCAL_DIR"
+	EnvManifest      = "MEGA_MANIFEST"
+	EnvManifestKey   = "MEGA_MANIFEST_KEY"
+	EnvCacheDir      = "MEGA_CACHE_DIR"
+	EnvPeers         = "MEGA_PEERS"
+	EnvBandwidth     = "MEGA_BANDWIDTH_LIMIT"
+	EnvBackground    = "MEGA_BACKGROUND"
+	EnvQuotaWait     = "MEGA_QUOTA_WAIT"
+	EnvLogLevel      = "MEGA_LOG_LEVEL"
+	EnvLogFormat     = "MEGA_LOG_FORMAT"
+	EnvMetricsListen = "MEGA_METRICS_LISTEN"
+	EnvMetricsFile   = "MEGA_METRICS_FILE"
+	EnvReportFile    = "MEGA_REPORT_FILE"
+	EnvDownloadRoot  = "MEGA_DOWNLOAD_ROOT"
+)
+
+// Environment variables read at login time only, which cannot be set in a configuration file. EnvSessionPassphrase holds the passphrase, which encrypts the session file, EnvMFACode holds a multi-factor authentication code for accounts requiring one.
+const (
+	EnvSessionPassphrase = "MEGA_SESSION_PASSPHRASE"
+	EnvMFACode           = "MEGA_MFA_CODE"
+)
+
+// Config describes the browser and downloader settings of a single deployment.
+type Config struct {
+	// PublicLink is a public folder or file link, which is browsed instead of an account, if set. A file link is browsed as a folder containing only that file, see NewPublicLink.
+	PublicLink string `json:"publicLink" yaml:"publicLink" toml:"publicLink"`
+	// Anonymous makes the browser read-only, without logging in to an account, so the account and session settings are ignored. Without PublicLink or LocalDir only mirrors can be browsed, Initialize fails with ErrAccountRequired otherwise.
+	Anonymous bool `json:"anonymous" yaml:"anonymous" toml:"anonymous"`
+	// LocalDir is a local directory, which is browsed instead of an account, if set, e.g. for offline testing, see LocalBackend.
+	LocalDir    string          `json:"localDir" yaml:"localDir" toml:"localDir"`
+	Account     AccountConfig   `json:"account" yaml:"account" toml:"account"`
+	RootNode    string          `json:"rootNode" yaml:"rootNode" toml:"rootNode"`
+	TargetDir   string          `json:"targetDir" yaml:"targetDir" toml:"targetDir"`
+	Filters     FiltersConfig   `json:"filters" yaml:"filters" toml:"filters"`
+	Concurrency int             `json:"concurrency" yaml:"concurrency" toml:"concurrency"`
+	Retry       RetryConfig     `json:"retry" yaml:"retry" toml:"retry"`
+	Progress    ProgressConfig  `json:"progress" yaml:"progress" toml:"progress"`
+	Session     SessionConfig   `json:"session" yaml:"session" toml:"session"`
+	Cache       CacheConfig     `json:"cache" yaml:"cache" toml:"cache"`
+	Bandwidth   BandwidthConfig `json:"bandwidth" yaml:"bandwidth" toml:"bandwidth"`
+	Quota       QuotaConfig     `json:"quota" yaml:"quota" toml:"quota"`
+	Log         LogConfig       `json:"log" yaml:"log" toml:"log"`
+	Metrics     MetricsConfig   `json:"metrics" yaml:"metrics" toml:"metrics"`
+	Report      ReportConfig    `json:"report" yaml:"report" toml:"report"`
+	// DownloadRoot confines every download to the directory, e.g. the install directory, see MegaDownloader.SetRootDir. Downloads are not confined, if it is empty.
+	DownloadRoot string `json:"downloadRoot" yaml:"downloadRoot" toml:"downloadRoot"`
+	// Releases makes the browser work on the current release of the project, see WithReleases.
+	Releases bool `json:"releases" yaml:"releases" toml:"releases"`
+	// Manifest is the name of the manifest in the project root, which downloaded files are verified against, see WithManifest.
+	Manifest string `json:"manifest" yaml:"manifest" toml:"manifest"`
+	// ManifestKey is the base64 encoded ed25519 public key, which the manifest signature is verified with, see WithManifestKey.
+	ManifestKey string `json:"manifestKey" yaml:"manifestKey" toml:"manifestKey"`
+	// Mirrors are tried in the given order, if the primary source is unavailable, see WithMirrors. They require Manifest and ManifestKey.
+	Mirrors []MirrorConfig `json:"mirrors" yaml:"mirrors" toml:"mirrors"`
+	// Peers makes clients in a local network share downloaded files, see WithPeers. It requires Manifest.
+	Peers PeersConfig `json:"peers" yaml:"peers" toml:"peers"`
+
+	// getenv reads the environment variables needed at login time, e.g. EnvSessionPassphrase. It is the function given to LoadConfig, or os.Getenv, if it is nil.
+	getenv func(string) string
+}
+
+// MirrorConfig describes a single mirror of the project. Type is "http", "s3" or "local". URL is the base URL of an HTTP mirror, Dir the directory of a local mirror and S3 the bucket of an S3-compatible mirror.
+type MirrorConfig struct {
+	Type string         `json:"type" yaml:"type" toml:"type"`
+	URL  string         `json:"url" yaml:"url" toml:"url"`
+	Dir  string         `json:"dir" yaml:"dir" toml:"dir"`
+	S3   S3MirrorConfig `json:"s3" yaml:"s3" toml:"s3"`
+}
+
+// AccountConfig holds credentials for the Mega repository. If CredentialsFile is set, login and password are read from that file at login time instead, see FileCredentialProvider.
+type AccountConfig struct {
+	Login           string `json:"login" yaml:"login" toml:"login"`
+	Password        string `json:"password" yaml:"password" toml:"password"`
+	CredentialsFile string `json:"credentialsFile" yaml:"credentialsFile" toml:"credentialsFile"`
+}
+
+// FiltersConfig holds path.Match patterns, matched against file paths relative to the project root. A file is synchronized, if it matches any of Include patterns (or Include is empty) and none of Exclude patterns.
+type FiltersConfig struct {
+	Include []string `json:"include" yaml:"include" toml:"include"`
+	Exclude []string `json:"exclude" yaml:"exclude" toml:"exclude"`
+}
+
+// RetryConfig specifies how many times a failed download is attempted and how long to wait between attempts.
+type RetryConfig struct {
+	Attempts int      `json:"attempts" yaml:"attempts" toml:"attempts"`
+	Delay    Duration `json:"delay" yaml:"delay" toml:"delay"`
+}
+
+// ProgressConfig specifies whether download progress is printed to the standard output.
+type ProgressConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled" toml:"enabled"`
+}
+
+// SessionConfig specifies where the authenticated session is saved between runs and for how long it may be resumed. Sessions are not saved, if File is empty.
+type SessionConfig struct {
+	File   string   `json:"file" yaml:"file" toml:"file"`
+	MaxAge Duration `json:"maxAge" yaml:"maxAge" toml:"maxAge"`
+}
+
+// CacheConfig specifies a download cache shared by several installs on one machine, see DownloadCache. Files are not cached, if Dir is empty. MaxSize is in bytes, 0 means no limit. Copy makes the cache copy files instead of hard linking them.
+type CacheConfig struct {
+	Dir     string `json:"dir" yaml:"dir" toml:"dir"`
+	MaxSize int64  `json:"maxSize" yaml:"maxSize" toml:"maxSize"`
+	Copy    bool   `json:"copy" yaml:"copy" toml:"copy"`
+}
+
+// BandwidthConfig limits the download rate in bytes per second, 0 means no limit, see Bandwidth. Limit applies to all downloads together, PerFile to every single one. If Background is true, background limits apply instead, e.g. for updates running while the user works or plays.
+type BandwidthConfig struct {
+	Limit             int64 `json:"limit" yaml:"limit" toml:"limit"`
+	PerFile           int64 `json:"perFile" yaml:"perFile" toml:"perFile"`
+	BackgroundLimit   int64 `json:"backgroundLimit" yaml:"backgroundLimit" toml:"backgroundLimit"`
+	BackgroundPerFile int64 `json:"backgroundPerFile" yaml:"backgroundPerFile" toml:"backgroundPerFile"`
+	Background        bool  `json:"background" yaml:"background" toml:"background"`
+}
+
+// QuotaConfig specifies how long a download over the transfer quota waits in total for the quota to reset, before it fails, see MegaDownloader.SetQuotaWait. It fails at once, if MaxWait is 0.
+type QuotaConfig struct {
+	MaxWait Duration `json:"maxWait" yaml:"maxWait" toml:"maxWait"`
+}
+
+// LogConfig specifies logging of the browser and the downloader to the standard error output. Level is "debug", "info", "warn" or "error", nothing is logged, if it is empty. Format is "text", the default, or "json".
+type LogConfig struct {
+	Level  string `json:"level" yaml:"level" toml:"level"`
+	Format string `json:"format" yaml:"format" toml:"format"`
+}
+
+// MetricsConfig specifies export of the browser and downloader metrics, see Metrics. Listen is an address, on which the command line tool serves them over HTTP, File a file, to which it writes them after every command, e.g. for a node exporter textfile collector. Metrics are counted, if either is set.
+type MetricsConfig struct {
+	Listen string `json:"listen" yaml:"listen" toml:"listen"`
+	File   string `json:"file" yaml:"file" toml:"file"`
+}
+
+// ReportConfig specifies a file, to which the command line tool writes the UpdateReport of every command updating files, as JSON, also if the command fails.
+type ReportConfig struct {
+	File string `json:"file" yaml:"file" toml:"file"`
+}
+
+// PeersConfig specifies sharing of downloaded files with other clients in a local network, see Peers. URLs are base URLs of known peers, Discovery is a UDP multicast address, e.g. "239.255.77.77:7077", on which peers find each other, see PeerDiscovery. A client serving files listens on Listen address and announces itself as URL every Interval, which other clients accept only with the LAN IP address of the client as its host, e.g. "http://192.168.1.10:7077".
+type PeersConfig struct {
+	URLs      []string `json:"urls" yaml:"urls" toml:"urls"`
+	Discovery string   `json:"discovery" yaml:"discovery" toml:"discovery"`
+	Listen    string   `json:"listen" yaml:"listen" toml:"listen"`
+	URL       string   `json:"url" yaml:"url" toml:"url"`
+	Interval  Duration `json:"interval" yaml:"interval" toml:"interval"`
+}
+
+// Duration is a time.Duration, which is read from configuration files in time.ParseDuration format, e.g. "1m30s".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// DefaultConfig returns configuration with default values, which are used for every setting missing from a configuration file.
+func DefaultConfig() *Config {
+	return &Config{
+		TargetDir:   ".",
+		Concurrency: 1,
+		Retry: RetryConfig{
+			Attempts: 1,
+		},
+		Progress: ProgressConfig{
+			Enabled: true,
+		},
+		Peers: PeersConfig{
+			Interval: Duration(30 * time.Second),
+		},
+	}
+}
+
+/*
+LoadConfig reads configuration from a file and applies environment variable overrides to it. File format is chosen by its extension: .json, .yaml, .yml or .toml. If configPath is empty, only the defaults and environment variables are used.
+
+Returns an error if:
+
+	failed to read or parse the file
+	file extension is not supported
+	any of environment variables has an invalid value
+	resulting configuration is invalid, see Config.Validate
+*/
+func LoadConfig(configPath string, getenv func(string) string) (*Config, error) {
+	cfg := DefaultConfig()
+	if configPath != "" {
+		err := readConfigFile(configPath, cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := cfg.applyEnv(getenv)
+	if err != nil {
+		return nil, err
+	}
+	cfg.getenv = getenv
+
+	err = cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+/*
+Validate checks whether configuration contains everything needed to build a browser.
+
+Returns an error if:
+
+	neither public link, local directory, login and password, credentials file nor session file is set, and the session is not anonymous
+	both public link and local directory are set
+	root node is empty, while neither public link nor local directory is set, and the session is not anonymous
+	concurrency or retry attempts is lower than 1
+	retry delay, session max age, cache max size, quota max wait or any bandwidth limit is negative
+	any of filter patterns is malformed
+	mirrors are set without a manifest, or any of them is incomplete
+	peers are set without a manifest, or listen address is set without URL or positive interval
+	log level or format is unknown
+*/
+func (c *Config) Validate() error {
+	if c.PublicLink != "" && c.LocalDir != "" {
+		return fmt.Errorf("config: public link and local directory must not be set together")
+	}
+	if c.PublicLink == "" && c.LocalDir == "" && !c.Anonymous {
+		if c.Account.CredentialsFile == "" && c.Session.File == "" && (c.Account.Login == "" || c.Account.Password == "") {
+			return fmt.Errorf("config: account login and password, credentials file or session file must be set")
+		}
+		if c.RootNode == "" {
+			return fmt.Errorf("config: root node must be set")
+		}
+	}
+	if c.Concurrency < 1 {
+		return fmt.Errorf("config: concurrency must be at least 1, got %d", c.Concurrency)
+	}
+	if c.Retry.Attempts < 1 {
+		return fmt.Errorf("config: retry attempts must be at least 1, got %d", c.Retry.Attempts)
+	}
+	if c.Retry.Delay < 0 {
+		return fmt.Errorf("config: retry delay must not be negative")
+	}
+	if c.Session.MaxAge < 0 {
+		return fmt.Errorf("config: session max age must not be negative")
+	}
+	if c.Cache.MaxSize < 0 {
+		return fmt.Errorf("config: cache max size must not be negative")
+	}
+	if c.Bandwidth.Limit < 0 || c.Bandwidth.PerFile < 0 || c.Bandwidth.BackgroundLimit < 0 || c.Bandwidth.BackgroundPerFile < 0 {
+		return fmt.Errorf("config: bandwidth limits must not be negative")
+	}
+	if c.Quota.MaxWait < 0 {
+		return fmt.Errorf("config: quota max wait must not be negative")
+	}
+	if len(c.Mirrors) > 0 && c.Manifest == "" {
+		return fmt.Errorf("config: mirrors require a manifest")
+	}
+	if len(c.Mirrors) > 0 && c.ManifestKey == "" {
+		return fmt.Errorf("config: mirrors require a manifest key")
+	}
+	if c.ManifestKey != "" {
+		_, err := c.manifestKey()
+		if err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+	}
+	for i, mirror := range c.Mirrors {
+		_, err := mirror.newMirror()
+		if err != nil {
+			return fmt.Errorf("config: mirror %d: %w", i+1, err)
+		}
+	}
+	if c.Peers.enabled() && c.Manifest == "" {
+		return fmt.Errorf("config: peers require a manifest")
+	}
+	if c.Peers.Listen != "" && (c.Peers.URL == "" || c.Peers.Interval <= 0) {
+		return fmt.Errorf("config: peers listen address requires url and positive interval")
+	}
+	_, err := c.Log.newLogger(io.Discard)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	return c.Filters.validate()
+}
+
+/*
+NewMegaBrowserFromConfig creates a browser object for a Mega repository, wired with a Mega client and a downloader configured according to cfg. If cfg has a public link or a local directory, the browser works on that public folder or directory instead. Additional options are applied after the ones derived from cfg.
+
+The browser still has to be initialized with Initialize().
+
+Returns an error if cfg is invalid, its public link is malformed or any of the options fails.
+*/
+func NewMegaBrowserFromConfig(cfg *Config, opts ...Option) (*MegaBrowser, error) {
+	err := cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	logger, err := cfg.Log.newLogger(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	client := NewMegaSessionClient(mega.New())
+	downloader := NewMegaDownloader()
+	cfg.configureDownloader(downloader)
+	downloader.SetLogger(logger)
+
+	cfgOpts := []Option{
+		WithLogger(logger),
+		WithRootNode(cfg.RootNode),
+		WithDownloader(downloader),
+		WithFilters(cfg.Filters),
+		WithConcurrency(cfg.Concurrency),
+	}
+	if cfg.Releases {
+		cfgOpts = append(cfgOpts, WithReleases(), WithReleaseDir(cfg.TargetDir))
+	}
+	if cfg.Metrics.enabled() {
+		metrics := NewMetrics()
+		downloader.SetMetrics(metrics)
+		cfgOpts = append(cfgOpts, WithMetrics(metrics))
+	}
+	if cfg.Manifest != "" {
+		cfgOpts = append(cfgOpts, WithManifest(cfg.Manifest))
+	}
+	if cfg.ManifestKey != "" {
+		key, err := cfg.manifestKey()
+		if err != nil {
+			return nil, err
+		}
+		cfgOpts = append(cfgOpts, WithManifestKey(key))
+	}
+	for _, mirrorCfg := range cfg.Mirrors {
+		mirror, err := mirrorCfg.newMirror()
+		if err != nil {
+			return nil, err
+		}
+		cfgOpts = append(cfgOpts, WithMirrors(mirror))
+	}
+	if cfg.Peers.enabled() {
+		cfgOpts = append(cfgOpts, WithPeers(NewPeers(cfg.Peers.URLs, nil)))
+	}
+	if cfg.PublicLink != "" {
+		folder, err := NewPublicLink(cfg.PublicLink)
+		if err != nil {
+			return nil, err
+		}
+		cfgOpts = append(cfgOpts, WithPublicFolder(folder))
+		if cfg.Anonymous {
+			cfgOpts = append(cfgOpts, WithAnonymousSession())
+		}
+		return New(append(cfgOpts, opts...)...)
+	}
+	if cfg.LocalDir != "" {
+		cfgOpts = append(cfgOpts, WithBackend(NewLocalBackend(cfg.LocalDir)))
+		if cfg.Anonymous {
+			cfgOpts = append(cfgOpts, WithAnonymousSession())
+		}
+		return New(append(cfgOpts, opts...)...)
+	}
+	if cfg.Anonymous {
+		cfgOpts = append(cfgOpts, WithClient(client), WithFs(client.FS), WithAnonymousSession())
+		return New(append(cfgOpts, opts...)...)
+	}
+
+	cfgOpts = append(cfgOpts,
+		WithCredentialProvider(cfg.credentialProvider()),
+		WithMFACodeFunc(cfg.envMFACode),
+		WithClient(client),
+		WithFs(client.FS),
+	)
+	if cfg.Session.File != "" {
+		store := NewFileSessionStore(cfg.Session.File, cfg.envPassphrase(EnvSessionPassphrase))
+		cfgOpts = append(cfgOpts, WithSessionStore(store, time.Duration(cfg.Session.MaxAge)))
+	}
+	return New(append(cfgOpts, opts...)...)
+}
+
+// manifestKey decodes the manifest key. Returns an error, if it is not a base64 encoded ed25519 public key.
+func (c *Config) manifestKey() (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(c.ManifestKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("manifest key must be a base64 encoded ed25519 public key")
+	}
+	return key, nil
+}
+
+// newMirror creates the mirror described by the configuration. Returns an error, if its type is unknown or the setting required by the type is missing.
+func (mc MirrorConfig) newMirror() (Mirror, error) {
+	switch mc.Type {
+	case "http":
+		if mc.URL == "" {
+			return nil, fmt.Errorf("http mirror url must be set")
+		}
+		return NewHTTPMirror(mc.URL, nil), nil
+	case "s3":
+		return NewS3Mirror(mc.S3, nil)
+	case "local":
+		if mc.Dir == "" {
+			return nil, fmt.Errorf("local mirror dir must be set")
+		}
+		return NewLocalMirror(mc.Dir), nil
+	}
+	return nil, fmt.Errorf("unknown mirror type: %q", mc.Type)
+}
+
+// newLogger creates a logger writing records of the configured level and format to w, or discarding every record, if the level is empty. Returns an error, if the level or the format is unknown.
+func (lc LogConfig) newLogger(w io.Writer) (*slog.Logger, error) {
+	if lc.Level == "" {
+		return discardLogger(), nil
+	}
+	var level slog.Level
+	err := level.UnmarshalText([]byte(lc.Level))
+	if err != nil {
+		return nil, fmt.Errorf("unknown log level: %q", lc.Level)
+	}
+	options := &slog.HandlerOptions{Level: level}
+	switch lc.Format {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	}
+	return nil, fmt.Errorf("unknown log format: %q", lc.Format)
+}
+
+// enabled returns true, if metrics are exported anywhere.
+func (mc MetricsConfig) enabled() bool {
+	return mc.Listen != "" || mc.File != ""
+}
+
+// enabled returns true, if files are shared with any peers.
+func (pc PeersConfig) enabled() bool {
+	return len(pc.URLs) > 0 || pc.Discovery != "" || pc.Listen != ""
+}
+
+func (c *Config) credentialProvider() CredentialProvider {
+	if c.Account.CredentialsFile != "" {
+		return NewFileCredentialProvider(c.Account.CredentialsFile)
+	}
+	return staticCredentials{Login: c.Account.Login, Password: c.Account.Password}
+}
+
+// envPassphrase returns a function reading a passphrase from given environment variable, failing if it is not set.
+func (c *Config) envPassphrase(envVar string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		passphrase := c.env(envVar)
+		if passphrase == "" {
+			return nil, fmt.Errorf("environment variable %s must be set", envVar)
+		}
+		return []byte(passphrase), nil
+	}
+}
+
+// envMFACode returns the multi-factor authentication code from EnvMFACode, which is empty if the variable is not set.
+func (c *Config) envMFACode() (string, error) {
+	return c.env(EnvMFACode), nil
+}
+
+// env returns value of given environment variable, read with the function given to LoadConfig.
+func (c *Config) env(envVar string) string {
+	if c.getenv == nil {
+		return os.Getenv(envVar)
+	}
+	return c.getenv(envVar)
+}
+
+func (c *Config) configureDownloader(downloader *MegaDownloader) {
+	downloader.retryAttempts = c.Retry.Attempts
+	downloader.retryDelay = time.Duration(c.Retry.Delay)
+	if !c.Progress.Enabled {
+		downloader.progressOutput = nil
+	}
+	if c.Cache.Dir != "" {
+		downloader.SetCache(NewDownloadCache(c.Cache.Dir, c.Cache.MaxSize, !c.Cache.Copy))
+	}
+	downloader.SetQuotaWait(time.Duration(c.Quota.MaxWait))
+	downloader.SetRootDir(c.DownloadRoot)
+	if c.Bandwidth.limited() {
+		bandwidth := NewBandwidth(
+			BandwidthLimits{Total: c.Bandwidth.Limit, PerFile: c.Bandwidth.PerFile},
+			BandwidthLimits{Total: c.Bandwidth.BackgroundLimit, PerFile: c.Bandwidth.BackgroundPerFile},
+		)
+		bandwidth.SetBackground(c.Bandwidth.Background)
+		downloader.SetBandwidth(bandwidth)
+	}
+}
+
+// limited returns true, if any of the limits is set.
+func (bc BandwidthConfig) limited() bool {
+	return bc.Limit > 0 || bc.PerFile > 0 || bc.BackgroundLimit > 0 || bc.BackgroundPerFile > 0
+}
+
+func readConfigFile(configPath string, cfg *Config) error {
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(configPath)) {
+	case ".json":
+		err = json.Unmarshal(content, cfg)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, cfg)
+	case ".toml":
+		err = toml.Unmarshal(content, cfg)
+	default:
+		return fmt.Errorf("config: unsupported file format: %s", configPath)
+	}
+	if err != nil {
+		return fmt.Errorf("config: failed to parse %s: %w", configPath, err)
+	}
+	return nil
+}
+
+// applyEnv overrides configuration values with the non-empty environment variables.
+func (c *Config) applyEnv(getenv func(string) string) error {
+	overrideString(&c.PublicLink, getenv(EnvPublicLink))
+	overrideString(&c.LocalDir, getenv(EnvLocalDir))
+	overrideString(&c.Account.Login, getenv(EnvLogin))
+	overrideString(&c.Account.Password, getenv(EnvPassword))
+	overrideString(&c.Account.CredentialsFile, getenv(EnvCredentials))
+	overrideString(&c.RootNode, getenv(EnvRootNode))
+	overrideString(&c.TargetDir, getenv(EnvTargetDir))
+	overrideString(&c.DownloadRoot, getenv(EnvDownloadRoot))
+	overrideString(&c.Session.File, getenv(EnvSessionFile))
+	overrideString(&c.Manifest, getenv(EnvManifest))
+	overrideString(&c.ManifestKey, getenv(EnvManifestKey))
+	overrideString(&c.Cache.Dir, getenv(EnvCacheDir))
+
+	if value := getenv(EnvPeers); value != "" {
+		c.Peers.URLs = strings.Split(value, ",")
+	}
+	if value := getenv(EnvConcurrency); value != "" {
+		concurrency, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("config: invalid %s: %w", EnvConcurrency, err)
+		}
+		c.Concurrency = concurrency
+	}
+	if value := getenv(EnvRetryAttempts); value != "" {
+		attempts, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("config: invalid %s: %w", EnvRetryAttempts, err)
+		}
+		c.Retry.Attempts = attempts
+	}
+	if value := getenv(EnvRetryDelay); value != "" {
+		err := c.Retry.Delay.UnmarshalText([]byte(value))
+		if err != nil {
+			return fmt.Errorf("config: invalid %s: %w", EnvRetryDelay, err)
+		}
+	}
+	if value := getenv(EnvBandwidth); value != "" {
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("config: invalid %s: %w", EnvBandwidth, err)
+		}
+		c.Bandwidth.Limit = limit
+	}
+	if value := getenv(EnvBackground); value != "" {
+		background, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("config: invalid %s: %w", EnvBackground, err)
+		}
+		c.Bandwidth.Background = background
+	}
+	if value := getenv(EnvQuotaWait); value != "" {
+		err := c.Quota.MaxWait.UnmarshalText([]byte(value))
+		if err != nil {
+			return fmt.Errorf("config: invalid %s: %w", EnvQuotaWait, err)
+		}
+	}
+	if value := getenv(EnvMetricsListen); value != "" {
+		c.Metrics.Listen = value
+	}
+	if value := getenv(EnvMetricsFile); value != "" {
+		c.Metrics.File = value
+	}
+	if value := getenv(EnvReportFile); value != "" {
+		c.Report.File = value
+	}
+	if value := getenv(EnvLogLevel); value != "" {
+		c.Log.Level = value
+	}
+	if value := getenv(EnvLogFormat); value != "" {
+		c.Log.Format = value
+	}
+	if value := getenv(EnvAnonymous); value != "" {
+		anonymous, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("config: invalid %s: %w", EnvAnonymous, err)
+		}
+		c.Anonymous = anonymous
+	}
+	if value := getenv(EnvProgress); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("config: invalid %s: %w", EnvProgress, err)
+		}
+		c.Progress.Enabled = enabled
+	}
+	return nil
+}
+
+func overrideString(target *string, value string) {
+	if value != "" {
+		*target = value
+	}
+}
+
+func (f FiltersConfig) validate() error {
+	for _, pattern := range append(append([]string{}, f.Include...), f.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("config: invalid filter pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matches returns true, if a file of given path, relative to the project root, passes the filters.
+func (f FiltersConfig) matches(remotePath string) bool {
+	included := len(f.Include) == 0
+	for _, pattern := range f.Include {
+		if ok, _ := path.Match(pattern, remotePath); ok {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+	for _, pattern := range f.Exclude {
+		if ok, _ := path.Match(pattern, remotePath); ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,459 @@
+package megabrowser
+
+import (
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigFromFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		content  string
+	}{
+		{
+			name:     "should load JSON config",
+			fileName: "config.json",
+			content:  `{"account": {"login": "login", "password": "password"}, "rootNode": "root", "targetDir": "target", "filters": {"include": ["*.dat"]}, "concurrency": 4, "retry": {"attempts": 3, "delay": "2s"}, "progress": {"enabled": false}}`,
+		},
+		{
+			name:     "should load YAML config",
+			fileName: "config.yaml",
+			content:  "account:\n  login: login\n  password: password\nrootNode: root\ntargetDir: target\nfilters:\n  include: ['*.dat']\nconcurrency: 4\nretry:\n  attempts: 3\n  delay: 2s\nprogress:\n  enabled: false\n",
+		},
+		{
+			name:     "should load TOML config",
+			fileName: "config.toml",
+			content:  "rootNode = \"root\"\ntargetDir = \"target\"\nconcurrency = 4\n[account]\nlogin = \"login\"\npassword = \"password\"\n[filters]\ninclude = [\"*.dat\"]\n[retry]\nattempts = 3\ndelay = \"2s\"\n[progress]\nenabled = false\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configPath := writeConfigFile(t, test.fileName, test.content)
+
+			cfg, err := LoadConfig(configPath, emptyGetenv)
+
+			require.Nil(t, err)
+			assert.NotNil(t, cfg.getenv)
+			cfg.getenv = nil
+			assert.Equal(t, &Config{
+				Account:     AccountConfig{Login: "login", Password: "password"},
+				RootNode:    "root",
+				TargetDir:   "target",
+				Filters:     FiltersConfig{Include: []string{"*.dat"}},
+				Concurrency: 4,
+				Retry:       RetryConfig{Attempts: 3, Delay: Duration(2 * time.Second)},
+				Progress:    ProgressConfig{Enabled: false},
+				Peers:       PeersConfig{Interval: Duration(30 * time.Second)},
+			}, cfg)
+		})
+	}
+}
+
+func TestLoadConfigAppliesEnvironmentOverrides(t *testing.T) {
+	configPath := writeConfigFile(t, "config.json", `{"account": {"login": "login", "password": "password"}, "rootNode": "root"}`)
+	env := map[string]string{
+		EnvPassword:      "env-password",
+		EnvTargetDir:     "env-target",
+		EnvConcurrency:   "2",
+		EnvRetryAttempts: "5",
+		EnvRetryDelay:    "1m",
+		EnvProgress:      "false",
+	}
+
+	cfg, err := LoadConfig(configPath, func(key string) string { return env[key] })
+
+	require.Nil(t, err)
+	assert.Equal(t, "login", cfg.Account.Login)
+	assert.Equal(t, "env-password", cfg.Account.Password)
+	assert.Equal(t, "env-target", cfg.TargetDir)
+	assert.Equal(t, 2, cfg.Concurrency)
+	assert.Equal(t, RetryConfig{Attempts: 5, Delay: Duration(time.Minute)}, cfg.Retry)
+	assert.False(t, cfg.Progress.Enabled)
+}
+
+func TestLoadConfigFailCase(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		content  string
+		env      map[string]string
+		expErr   string
+	}{
+		{
+			name:     "should fail, if file format is not supported",
+			fileName: "config.ini",
+			content:  "",
+			expErr:   "unsupported file format",
+		},
+		{
+			name:     "should fail, if file is malformed",
+			fileName: "config.json",
+			content:  "{",
+			expErr:   "failed to parse",
+		},
+		{
+			name:     "should fail, if credentials are missing",
+			fileName: "config.json",
+			content:  `{"rootNode": "root"}`,
+			expErr:   "login and password, credentials file or session file must be set",
+		},
+		{
+			name:     "should fail, if root node is missing",
+			fileName: "config.json",
+			content:  `{"account": {"login": "login", "password": "password"}}`,
+			expErr:   "root node must be set",
+		},
+		{
+			name:     "should fail, if both public link and local directory are set",
+			fileName: "config.json",
+			content:  `{"publicLink": "https://mega.nz/folder/ID#KEY", "localDir": "mirror"}`,
+			expErr:   "public link and local directory must not be set together",
+		},
+		{
+			name:     "should fail, if concurrency is invalid",
+			fileName: "config.json",
+			content:  `{"account": {"login": "login", "password": "password"}, "rootNode": "root", "concurrency": -1}`,
+			expErr:   "concurrency must be at least 1",
+		},
+		{
+			name:     "should fail, if filter pattern is malformed",
+			fileName: "config.json",
+			content:  `{"account": {"login": "login", "password": "password"}, "rootNode": "root", "filters": {"exclude": ["["]}}`,
+			expErr:   "invalid filter pattern",
+		},
+		{
+			name:     "should fail, if cache max size is negative",
+			fileName: "config.json",
+			content:  `{"localDir": "repository", "cache": {"dir": "cache", "maxSize": -1}}`,
+			expErr:   "cache max size must not be negative",
+		},
+		{
+			name:     "should fail, if mirrors are set without a manifest",
+			fileName: "config.json",
+			content:  `{"localDir": "repository", "mirrors": [{"type": "local", "dir": "mirror"}]}`,
+			expErr:   "mirrors require a manifest",
+		},
+		{
+			name:     "should fail, if mirrors are set without a manifest key",
+			fileName: "config.json",
+			content:  `{"localDir": "repository", "manifest": "manifest.json", "mirrors": [{"type": "local", "dir": "mirror"}]}`,
+			expErr:   "mirrors require a manifest key",
+		},
+		{
+			name:     "should fail, if manifest key is not an ed25519 public key",
+			fileName: "config.json",
+			content:  `{"localDir": "repository", "manifest": "manifest.json", "manifestKey": "c2hvcnQ="}`,
+			expErr:   "manifest key must be a base64 encoded ed25519 public key",
+		},
+		{
+			name:     "should fail, if mirror type is unknown",
+			fileName: "config.json",
+			content:  `{"localDir": "repository", "manifest": "manifest.json", "manifestKey": "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", "mirrors": [{"type": "ftp"}]}`,
+			expErr:   `mirror 1: unknown mirror type: "ftp"`,
+		},
+		{
+			name:     "should fail, if s3 mirror has no bucket",
+			fileName: "config.json",
+			content:  `{"localDir": "repository", "manifest": "manifest.json", "manifestKey": "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", "mirrors": [{"type": "s3", "s3": {"endpoint": "https://s3.example.com"}}]}`,
+			expErr:   "s3 mirror endpoint and bucket must be set",
+		},
+		{
+			name:     "should fail, if peers are set without a manifest",
+			fileName: "config.json",
+			content:  `{"localDir": "repository", "peers": {"urls": ["http://peer:7077"]}}`,
+			expErr:   "peers require a manifest",
+		},
+		{
+			name:     "should fail, if peers listen address is set without url",
+			fileName: "config.json",
+			content:  `{"localDir": "repository", "manifest": "manifest.json", "peers": {"listen": ":7077"}}`,
+			expErr:   "peers listen address requires url and positive interval",
+		},
+		{
+			name:     "should fail, if bandwidth limit is negative",
+			fileName: "config.json",
+			content:  `{"localDir": "repository", "bandwidth": {"perFile": -1}}`,
+			expErr:   "bandwidth limits must not be negative",
+		},
+		{
+			name:     "should fail, if quota max wait is negative",
+			fileName: "config.json",
+			content:  `{"localDir": "repository", "quota": {"maxWait": "-1m"}}`,
+			expErr:   "quota max wait must not be negative",
+		},
+		{
+			name:     "should fail, if log level is unknown",
+			fileName: "config.json",
+			content:  `{"localDir": "repository", "log": {"level": "verbose"}}`,
+			expErr:   `unknown log level: "verbose"`,
+		},
+		{
+			name:     "should fail, if log format is unknown",
+			fileName: "config.json",
+			content:  `{"localDir": "repository", "log": {"level": "info", "format": "xml"}}`,
+			expErr:   `unknown log format: "xml"`,
+		},
+		{
+			name:     "should fail, if environment variable is malformed",
+			fileName: "config.json",
+			content:  `{"account": {"login": "login", "password": "password"}, "rootNode": "root"}`,
+			env:      map[string]string{EnvRetryDelay: "soon"},
+			expErr:   EnvRetryDelay,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configPath := writeConfigFile(t, test.fileName, test.content)
+
+			cfg, err := LoadConfig(configPath, func(key string) string { return test.env[key] })
+
+			assert.Nil(t, cfg)
+			require.NotNil(t, err)
+			assert.Contains(t, err.Error(), test.expErr)
+		})
+	}
+}
+
+func TestFiltersMatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		filters    FiltersConfig
+		remotePath string
+		expMatch   bool
+	}{
+		{
+			name:       "should match any file, if there are no filters",
+			filters:    FiltersConfig{},
+			remotePath: "dir/file.dat",
+			expMatch:   true,
+		},
+		{
+			name:       "should match file, if it matches an include pattern",
+			filters:    FiltersConfig{Include: []string{"*.txt", "dir/*.dat"}},
+			remotePath: "dir/file.dat",
+			expMatch:   true,
+		},
+		{
+			name:       "should not match file, if it matches no include pattern",
+			filters:    FiltersConfig{Include: []string{"*.dat"}},
+			remotePath: "dir/file.dat",
+			expMatch:   false,
+		},
+		{
+			name:       "should not match file, if it matches an exclude pattern",
+			filters:    FiltersConfig{Exclude: []string{"dir/*"}},
+			remotePath: "dir/file.dat",
+			expMatch:   false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expMatch, test.filters.matches(test.remotePath))
+		})
+	}
+}
+
+func TestNewMegaBrowserFromConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Account = AccountConfig{Login: login, Password: pass}
+	cfg.RootNode = rootNodeName
+	cfg.Concurrency = 3
+	cfg.Retry = RetryConfig{Attempts: 2, Delay: Duration(time.Second)}
+	cfg.Progress.Enabled = false
+	cfg.Releases = true
+	cfg.DownloadRoot = "install"
+
+	browser, err := NewMegaBrowserFromConfig(cfg)
+
+	require.Nil(t, err)
+	assert.Equal(t, rootNodeName, browser.rootNodeName)
+	assert.True(t, browser.releases)
+	assert.Equal(t, ".", browser.releaseDir)
+	assert.Equal(t, 3, browser.concurrency)
+	assert.Implements(t, (*MFAClient)(nil), browser.megaClient)
+	assert.NotNil(t, browser.mfaCode)
+	require.IsType(t, &MegaDownloader{}, browser.downloader)
+	downloader := browser.downloader.(*MegaDownloader)
+	assert.Equal(t, 2, downloader.retryAttempts)
+	assert.Equal(t, time.Second, downloader.retryDelay)
+	assert.Nil(t, downloader.progressOutput)
+	assert.Equal(t, "install", downloader.rootDir)
+}
+
+func TestNewMegaBrowserFromConfigResumesSession(t *testing.T) {
+	server := newFakeAccountServer(t)
+	sessionFile := filepath.Join(t.TempDir(), "session")
+	passphrase := func() ([]byte, error) { return []byte("passphrase"), nil }
+	token := sessionToken(accountSessionID)
+	err := NewFileSessionStore(sessionFile, passphrase).Save(Session{Token: token, SavedAt: time.Now()})
+	require.Nil(t, err)
+	cfg, err := LoadConfig("", func(key string) string {
+		return map[string]string{
+			EnvRootNode:          rootNodeName,
+			EnvSessionFile:       sessionFile,
+			EnvSessionPassphrase: "passphrase",
+		}[key]
+	})
+	require.Nil(t, err)
+
+	browser, err := NewMegaBrowserFromConfig(cfg)
+	require.Nil(t, err)
+	require.IsType(t, &MegaSessionClient{}, browser.megaClient)
+	browser.megaClient.(*MegaSessionClient).SetAPIUrl(server.URL)
+	browser.megaClient.(*MegaSessionClient).SetLogger(nil)
+	err = browser.Initialize()
+
+	require.Nil(t, err)
+	assert.Equal(t, accountDirHash, browser.rootNodeHash)
+	assert.Equal(t, token, browser.SessionToken())
+	assert.Equal(t, []string{"f"}, server.receivedCommands())
+}
+
+func TestNewMegaBrowserFromConfigWithPublicLink(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.PublicLink = "https://mega.nz/folder/" + publicFolderHandle + "#" + encodeKey(publicFolderKey)
+
+	browser, err := NewMegaBrowserFromConfig(cfg)
+
+	require.Nil(t, err)
+	require.NotNil(t, browser.publicFolder)
+	assert.Equal(t, publicFolderHandle, browser.publicFolder.handle)
+	assert.Nil(t, browser.credentials)
+	assert.True(t, browser.Anonymous())
+}
+
+func TestNewMegaBrowserFromConfigWithPublicFileLink(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.PublicLink = "https://mega.nz/file/" + publicFileHandle + "#" + encodeKey(make([]byte, fileKeySize))
+
+	browser, err := NewMegaBrowserFromConfig(cfg)
+
+	require.Nil(t, err)
+	require.NotNil(t, browser.publicFolder)
+	assert.Equal(t, publicFileHandle, browser.publicFolder.handle)
+	assert.True(t, browser.publicFolder.file)
+}
+
+func TestNewMegaBrowserFromConfigWithAnonymousSession(t *testing.T) {
+	cfg, err := LoadConfig("", func(key string) string {
+		return map[string]string{
+			EnvPublicLink: "https://mega.nz/folder/" + publicFolderHandle + "#" + encodeKey(publicFolderKey),
+			EnvAnonymous:  "true",
+			EnvLogin:      "login",
+			EnvPassword:   "password",
+		}[key]
+	})
+	require.Nil(t, err)
+
+	browser, err := NewMegaBrowserFromConfig(cfg)
+
+	require.Nil(t, err)
+	assert.True(t, browser.anonymous)
+	assert.Nil(t, browser.credentials)
+}
+
+func TestNewMegaBrowserFromConfigWithAnonymousSessionOnly(t *testing.T) {
+	cfg, err := LoadConfig("", func(key string) string {
+		return map[string]string{
+			EnvAnonymous: "true",
+			EnvLogin:     "login",
+			EnvPassword:  "password",
+		}[key]
+	})
+	require.Nil(t, err)
+
+	browser, err := NewMegaBrowserFromConfig(cfg)
+
+	require.Nil(t, err)
+	assert.True(t, browser.Anonymous())
+	assert.Nil(t, browser.credentials)
+	assert.ErrorIs(t, browser.Initialize(), ErrAccountRequired)
+}
+
+func TestNewMegaBrowserFromConfigWithLocalDir(t *testing.T) {
+	localDir := t.TempDir()
+	cfg, err := LoadConfig("", func(key string) string {
+		return map[string]string{EnvLocalDir: localDir}[key]
+	})
+	require.Nil(t, err)
+
+	browser, err := NewMegaBrowserFromConfig(cfg)
+
+	require.Nil(t, err)
+	assert.Equal(t, NewLocalBackend(localDir), browser.backend)
+	assert.Nil(t, browser.credentials)
+	assert.True(t, browser.Anonymous())
+}
+
+func TestNewMegaBrowserFromConfigWithMirrors(t *testing.T) {
+	configPath := writeConfigFile(t, "config.yaml", `
+localDir: repository
+manifest: manifest.json
+manifestKey: AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=
+mirrors:
+  - type: http
+    url: https://mirror.example.com/project
+  - type: s3
+    s3:
+      endpoint: https://s3.example.com
+      bucket: bucket
+      prefix: project
+  - type: local
+    dir: /mnt/mirror
+`)
+	cfg, err := LoadConfig(configPath, emptyGetenv)
+	require.Nil(t, err)
+
+	browser, err := NewMegaBrowserFromConfig(cfg)
+
+	require.Nil(t, err)
+	assert.Equal(t, "manifest.json", browser.manifestFile)
+	assert.Equal(t, ed25519.PublicKey(make([]byte, ed25519.PublicKeySize)), browser.manifestKey)
+	var names []string
+	for _, mirror := range browser.mirrors {
+		names = append(names, mirror.Name())
+	}
+	assert.Equal(t, []string{"https://mirror.example.com/project", "s3://bucket/project", "/mnt/mirror"}, names)
+}
+
+func TestNewMegaBrowserFromConfigWithPeers(t *testing.T) {
+	cfg, err := LoadConfig("", func(key string) string {
+		return map[string]string{
+			EnvLocalDir: t.TempDir(),
+			EnvManifest: "manifest.json",
+			EnvPeers:    "http://a:7077,http://b:7077/",
+		}[key]
+	})
+	require.Nil(t, err)
+
+	browser, err := NewMegaBrowserFromConfig(cfg)
+
+	require.Nil(t, err)
+	require.NotNil(t, browser.Peers())
+	assert.Equal(t, []string{"http://a:7077", "http://b:7077"}, browser.Peers().URLs())
+}
+
+func TestNewMegaBrowserFromConfigFailsIfConfigIsInvalid(t *testing.T) {
+	browser, err := NewMegaBrowserFromConfig(DefaultConfig())
+
+	assert.Nil(t, browser)
+	assert.NotNil(t, err)
+}
+
+func writeConfigFile(t *testing.T, fileName string, content string) string {
+	configPath := filepath.Join(t.TempDir(), fileName)
+	err := os.WriteFile(configPath, []byte(content), 0600)
+	require.Nil(t, err)
+	return configPath
+}
+
+func emptyGetenv(string) string {
+	return ""
+}
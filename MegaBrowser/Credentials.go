@@ -0,0 +1,261 @@
+package megabrowser
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ErrInsecureCredentialsFile is returned, if a credentials file can be accessed by users other than its owner.
+var ErrInsecureCredentialsFile = errors.New("credentials file must not be accessible by group or others")
+
+// ErrWrongPassphrase is returned, if a keyfile could not be decrypted with the given passphrase.
+var ErrWrongPassphrase = errors.New("could not decrypt keyfile, wrong passphrase or corrupted file")
+
+const (
+	// keyfileSaltSize is the size of the random salt used to derive a keyfile encryption key.
+	keyfileSaltSize = 16
+	// keyfileKeySize is the size of the AES-256 key protecting a keyfile.
+	keyfileKeySize = 32
+)
+
+// Credentials hold login and password for the Mega repository.
+type Credentials struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+// CredentialProvider supplies credentials for the Mega repository. The browser consults it only when it needs to log in, and does not keep the returned credentials.
+type CredentialProvider interface {
+	Credentials() (Credentials, error)
+}
+
+// CredentialProviderFunc is a CredentialProvider calling the function, e.g. prompting the user for login and password.
+type CredentialProviderFunc func() (Credentials, error)
+
+func (f CredentialProviderFunc) Credentials() (Credentials, error) {
+	return f()
+}
+
+// staticCredentials is a CredentialProvider returning fixed credentials, used by WithCredentials and NewMegaBrowser.
+type staticCredentials Credentials
+
+func (sc staticCredentials) Credentials() (Credentials, error) {
+	return Credentials(sc), nil
+}
+
+// EnvCredentialProvider reads credentials from environment variables.
+type EnvCredentialProvider struct {
+	loginVar    string
+	passwordVar string
+	getenv      func(string) string
+}
+
+// NewEnvCredentialProvider creates a provider reading login and password from given environment variables, e.g. EnvLogin and EnvPassword.
+func NewEnvCredentialProvider(loginVar string, passwordVar string) *EnvCredentialProvider {
+	return &EnvCredentialProvider{
+		loginVar:    loginVar,
+		passwordVar: passwordVar,
+		getenv:      os.Getenv,
+	}
+}
+
+/*
+Credentials returns login and password read from the environment variables.
+
+Returns an error if any of the variables is empty.
+*/
+func (ep *EnvCredentialProvider) Credentials() (Credentials, error) {
+	credentials := Credentials{
+		Login:    ep.getenv(ep.loginVar),
+		Password: ep.getenv(ep.passwordVar),
+	}
+	if credentials.Login == "" || credentials.Password == "" {
+		return Credentials{}, fmt.Errorf("environment variables %s and %s must be set", ep.loginVar, ep.passwordVar)
+	}
+	return credentials, nil
+}
+
+// FileCredentialProvider reads credentials from a JSON file with "login" and "password" fields.
+type FileCredentialProvider struct {
+	path string
+}
+
+// NewFileCredentialProvider creates a provider reading credentials from the JSON file at given path.
+func NewFileCredentialProvider(path string) *FileCredentialProvider {
+	return &FileCredentialProvider{
+		path: path,
+	}
+}
+
+/*
+Credentials returns login and password read from the file.
+
+Returns an error if:
+
+	failed to stat or read the file
+	the file can be accessed by group or others, which is not checked on Windows
+	the file is malformed or does not contain login and password
+*/
+func (fp *FileCredentialProvider) Credentials() (Credentials, error) {
+	err := checkCredentialsFilePermissions(fp.path)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	content, err := os.ReadFile(fp.path)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	var credentials Credentials
+	err = json.Unmarshal(content, &credentials)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to parse credentials file %s: %w", fp.path, err)
+	}
+	if credentials.Login == "" || credentials.Password == "" {
+		return Credentials{}, fmt.Errorf("credentials file %s must contain login and password", fp.path)
+	}
+	return credentials, nil
+}
+
+// KeyfileCredentialProvider reads credentials from a file encrypted with a passphrase, see WriteKeyfile.
+type KeyfileCredentialProvider struct {
+	path       string
+	passphrase func() ([]byte, error)
+}
+
+// NewKeyfileCredentialProvider creates a provider decrypting the keyfile at given path with the passphrase returned by the passphrase function, which is called only at login time.
+func NewKeyfileCredentialProvider(path string, passphrase func() ([]byte, error)) *KeyfileCredentialProvider {
+	return &KeyfileCredentialProvider{
+		path:       path,
+		passphrase: passphrase,
+	}
+}
+
+/*
+Credentials returns login and password decrypted from the keyfile.
+
+Returns an error if:
+
+	failed to read the file or get the passphrase
+	the file could not be decrypted, see ErrWrongPassphrase
+*/
+func (kp *KeyfileCredentialProvider) Credentials() (Credentials, error) {
+	content, err := os.ReadFile(kp.path)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	passphrase, err := kp.passphrase()
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	plaintext, err := decryptWithPassphrase(content, passphrase)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	var credentials Credentials
+	err = json.Unmarshal(plaintext, &credentials)
+	if err != nil {
+		return Credentials{}, ErrWrongPassphrase
+	}
+	return credentials, nil
+}
+
+/*
+WriteKeyfile encrypts credentials with a key derived from passphrase and writes them to a file at given path, readable only by its owner.
+
+The file is portable between operating systems, it consists of a random salt, AES-GCM nonce and the encrypted credentials.
+*/
+func WriteKeyfile(path string, credentials Credentials, passphrase []byte) error {
+	plaintext, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+
+	content, err := encryptWithPassphrase(plaintext, passphrase)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0600)
+}
+
+// checkCredentialsFilePermissions returns ErrInsecureCredentialsFile, if group or others have any permissions to the file. Windows permissions are not represented in the file mode, so they are not checked.
+func checkCredentialsFilePermissions(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%s: %w", path, ErrInsecureCredentialsFile)
+	}
+	return nil
+}
+
+// encryptWithPassphrase encrypts plaintext with AES-GCM, using a key derived from passphrase with scrypt. Returns salt, nonce and ciphertext concatenated.
+func encryptWithPassphrase(plaintext []byte, passphrase []byte) ([]byte, error) {
+	salt := make([]byte, keyfileSaltSize)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newPassphraseCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	result := append(salt, nonce...)
+	return gcm.Seal(result, nonce, plaintext, nil), nil
+}
+
+// decryptWithPassphrase reverses encryptWithPassphrase. Returns ErrWrongPassphrase, if the content could not be authenticated.
+func decryptWithPassphrase(content []byte, passphrase []byte) ([]byte, error) {
+	if len(content) < keyfileSaltSize {
+		return nil, ErrWrongPassphrase
+	}
+
+	gcm, err := newPassphraseCipher(passphrase, content[:keyfileSaltSize])
+	if err != nil {
+		return nil, err
+	}
+
+	content = content[keyfileSaltSize:]
+	if len(content) < gcm.NonceSize() {
+		return nil, ErrWrongPassphrase
+	}
+	plaintext, err := gcm.Open(nil, content[:gcm.NonceSize()], content[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return plaintext, nil
+}
+
+func newPassphraseCipher(passphrase []byte, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, 1<<15, 8, 1, keyfileKeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
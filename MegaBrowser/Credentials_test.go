@@ -0,0 +1,167 @@
+package megabrowser
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvCredentialProvider(t *testing.T) {
+	tests := []struct {
+		name           string
+		env            map[string]string
+		expCredentials Credentials
+		expErr         bool
+	}{
+		{
+			name:           "should return credentials, if both variables are set",
+			env:            map[string]string{EnvLogin: login, EnvPassword: pass},
+			expCredentials: Credentials{Login: login, Password: pass},
+			expErr:         false,
+		},
+		{
+			name:           "should fail, if password variable is not set",
+			env:            map[string]string{EnvLogin: login},
+			expCredentials: Credentials{},
+			expErr:         true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			provider := NewEnvCredentialProvider(EnvLogin, EnvPassword)
+			provider.getenv = func(key string) string { return test.env[key] }
+
+			credentials, err := provider.Credentials()
+
+			assert.Equal(t, test.expCredentials, credentials)
+			assert.Equal(t, test.expErr, err != nil)
+		})
+	}
+}
+
+func TestFileCredentialProvider(t *testing.T) {
+	tests := []struct {
+		name           string
+		content        string
+		perm           os.FileMode
+		expCredentials Credentials
+		expErr         string
+	}{
+		{
+			name:           "should return credentials, if file is valid",
+			content:        `{"login": "login", "password": "password"}`,
+			perm:           0600,
+			expCredentials: Credentials{Login: login, Password: pass},
+			expErr:         "",
+		},
+		{
+			name:           "should fail, if file is malformed",
+			content:        `{`,
+			perm:           0600,
+			expCredentials: Credentials{},
+			expErr:         "failed to parse credentials file",
+		},
+		{
+			name:           "should fail, if file does not contain password",
+			content:        `{"login": "login"}`,
+			perm:           0600,
+			expCredentials: Credentials{},
+			expErr:         "must contain login and password",
+		},
+	}
+	if runtime.GOOS != "windows" {
+		tests = append(tests, struct {
+			name           string
+			content        string
+			perm           os.FileMode
+			expCredentials Credentials
+			expErr         string
+		}{
+			name:           "should fail, if file is readable by others",
+			content:        `{"login": "login", "password": "password"}`,
+			perm:           0644,
+			expCredentials: Credentials{},
+			expErr:         ErrInsecureCredentialsFile.Error(),
+		})
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "credentials.json")
+			require.Nil(t, os.WriteFile(path, []byte(test.content), test.perm))
+			require.Nil(t, os.Chmod(path, test.perm))
+
+			credentials, err := NewFileCredentialProvider(path).Credentials()
+
+			assert.Equal(t, test.expCredentials, credentials)
+			if test.expErr == "" {
+				assert.Nil(t, err)
+			} else {
+				require.NotNil(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+			}
+		})
+	}
+}
+
+func TestKeyfileCredentialProvider(t *testing.T) {
+	tests := []struct {
+		name           string
+		passphrase     string
+		expCredentials Credentials
+		expErr         error
+	}{
+		{
+			name:           "should decrypt credentials, if passphrase is correct",
+			passphrase:     "passphrase",
+			expCredentials: Credentials{Login: login, Password: pass},
+			expErr:         nil,
+		},
+		{
+			name:           "should fail, if passphrase is wrong",
+			passphrase:     "wrong",
+			expCredentials: Credentials{},
+			expErr:         ErrWrongPassphrase,
+		},
+	}
+	path := filepath.Join(t.TempDir(), "keyfile")
+	err := WriteKeyfile(path, Credentials{Login: login, Password: pass}, []byte("passphrase"))
+	require.Nil(t, err)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			provider := NewKeyfileCredentialProvider(path, func() ([]byte, error) {
+				return []byte(test.passphrase), nil
+			})
+
+			credentials, err := provider.Credentials()
+
+			assert.Equal(t, test.expCredentials, credentials)
+			assert.Equal(t, test.expErr, err)
+		})
+	}
+}
+
+func TestKeyfileDoesNotContainPlaintextPassword(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyfile")
+
+	err := WriteKeyfile(path, Credentials{Login: login, Password: pass}, []byte("passphrase"))
+
+	require.Nil(t, err)
+	content, err := os.ReadFile(path)
+	require.Nil(t, err)
+	assert.NotContains(t, string(content), pass)
+}
+
+func TestCredentialProviderFunc(t *testing.T) {
+	provider := CredentialProviderFunc(func() (Credentials, error) {
+		return Credentials{Login: login, Password: pass}, nil
+	})
+
+	credentials, err := provider.Credentials()
+
+	assert.Nil(t, err)
+	assert.Equal(t, Credentials{Login: login, Password: pass}, credentials)
+}
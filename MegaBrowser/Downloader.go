@@ -1,66 +1,210 @@
 package megabrowser
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"io/fs"
+	"log/slog"
 	"math"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
-	"github.com/t3rm1n4l/go-mega"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Downloader downloads files of the backend a browser works on, which is an adapter of the Mega account, unless the browser was created with another one, see Backend.
 type Downloader interface {
-	DownloadFile(node *mega.Node, localDownloadPath string) error
+	DownloadFromBackend(backend Backend, node Node, localDownloadPath string) error
+}
+
+// PublicDownloader is a Downloader able to download files of public links. MegaBrowser requires its downloader to implement it, when browsing a public folder.
+type PublicDownloader interface {
+	DownloadPublicFile(file *PublicFile, localDownloadPath string) error
 }
 
 type MegaDownloader struct {
-	client      StorageClient
-	removeFile  removeFileFunc
-	mkDir       mkdirFunc
-	getWd       getWdFunc
-	getNodeSize getNodeSizeFunc
+	removeFile     removeFileFunc
+	mkDir          mkdirFunc
+	getWd          getWdFunc
+	getNodeSize    getNodeSizeFunc
+	getNodeHash    getNodeHashFunc
+	sleep          waitFunc
+	retryAttempts  int
+	retryDelay     time.Duration
+	progressOutput io.Writer
+	cache          *DownloadCache
+	bandwidth      *Bandwidth
+	quotaMaxWait   time.Duration
+	logger         *slog.Logger
+	metrics        *Metrics
+	rootDir        string
+	tracer         trace.Tracer
+	ctx            context.Context
 }
 
 type removeFileFunc func(path string) error
 type mkdirFunc func(path string, perm fs.FileMode) error
 type getWdFunc func() (string, error)
+type sleepFunc func(d time.Duration)
+
+// waitFunc pauses for d, or until ctx is done, in which case it returns the error of ctx.
+type waitFunc func(ctx context.Context, d time.Duration) error
+
+// transferFunc downloads a file to dstPath, sending the number of downloaded bytes to progress and closing it when finished.
+type transferFunc func(dstPath string, progress *chan int) error
 
-func NewMegaDownloader(client StorageClient) *MegaDownloader {
+func NewMegaDownloader() *MegaDownloader {
 	return &MegaDownloader{
-		client:      client,
-		removeFile:  os.Remove,
-		mkDir:       os.MkdirAll,
-		getWd:       os.Getwd,
-		getNodeSize: getNodeSize,
+		removeFile:     os.Remove,
+		mkDir:          os.MkdirAll,
+		getWd:          os.Getwd,
+		getNodeSize:    getNodeSize,
+		getNodeHash:    getNodeHash,
+		sleep:          sleepContext,
+		retryAttempts:  1,
+		progressOutput: os.Stdout,
+		logger:         discardLogger(),
+		tracer:         defaultTracer(),
+		ctx:            context.Background(),
+	}
+}
+
+// SetCache makes the downloader look for files of Mega accounts and public links in given cache before downloading them, and add downloaded files to it. A nil cache disables caching.
+func (md *MegaDownloader) SetCache(cache *DownloadCache) {
+	md.cache = cache
+}
+
+// SetLogger sets logger, which records every download step with the hash and the local path of the file. A nil logger discards every record.
+func (md *MegaDownloader) SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		logger = discardLogger()
+	}
+	md.logger = logger
+}
+
+// SetMetrics makes the downloader count downloaded bytes, retries and transfer durations in given metrics, which may be shared with a browser, see WithMetrics. Nil metrics disable counting.
+func (md *MegaDownloader) SetMetrics(metrics *Metrics) {
+	md.metrics = metrics
+}
+
+// SetBandwidth limits the download rate with given limits, which may be shared by several downloaders. A nil bandwidth disables limiting.
+func (md *MegaDownloader) SetBandwidth(bandwidth *Bandwidth) {
+	md.bandwidth = bandwidth
+}
+
+// DownloadPublicFile downloads a file of a public link, replacing the file at localDownloadPath the same way as DownloadFromBackend does. The transfer is cancelled with the downloader context, see WithContext.
+func (md *MegaDownloader) DownloadPublicFile(file *PublicFile, localDownloadPath string) error {
+	return md.download(file, localDownloadPath, md.cachedTransfer(file, md.limitedTransfer(func(dstPath string, progress *chan int) error {
+		return file.DownloadContext(md.ctx, dstPath, progress)
+	})))
+}
+
+// DownloadFromBackend downloads a file node of a backend, replacing the file at localDownloadPath. Files of Mega accounts, see NewMegaBackend, are cached, files of other backends are not, because their hashes do not identify the content, see DownloadCache.
+func (md *MegaDownloader) DownloadFromBackend(backend Backend, node Node, localDownloadPath string) error {
+	transfer := md.limitedTransfer(func(dstPath string, progress *chan int) error {
+		return backend.Download(node.GetHash(), dstPath, progress)
+	})
+	if _, ok := backend.(*megaBackend); ok {
+		transfer = md.cachedTransfer(node, transfer)
 	}
+	return md.download(node, localDownloadPath, transfer)
 }
 
-func (md *MegaDownloader) DownloadFile(node *mega.Node, localDownloadPath string) error {
-	err := md.removeOutdatedFile(localDownloadPath)
+// download removes the outdated file, creates its directory and transfers the new file of given node to localDownloadPath, which is relative to the working directory unless it is absolute. If the downloader is confined to a root directory, see SetRootDir, nothing is touched unless the path is safe. The download is traced with a span, which is a child of the span in the downloader context, see WithContext, with a child span for every step.
+func (md *MegaDownloader) download(node Node, localDownloadPath string, transfer transferFunc) (err error) {
+	ctx, span := md.tracer.Start(md.ctx, "download", trace.WithAttributes(attrPath.String(localDownloadPath)))
+	defer func() { endSpan(span, err) }()
+	if span.IsRecording() {
+		span.SetAttributes(attrHash.String(md.getNodeHash(node)), attrBytes.Int64(md.getNodeSize(node)))
+	}
+
+	err = md.confine(localDownloadPath)
 	if err != nil {
+		md.logger.Warn("unsafe download path", "path", localDownloadPath, "error", err)
 		return err
 	}
 
-	err = md.createFileDirectoryIfNotExist(localDownloadPath)
+	_, stepSpan := md.tracer.Start(ctx, "remove")
+	err = md.removeOutdatedFile(localDownloadPath)
+	endSpan(stepSpan, err)
 	if err != nil {
 		return err
 	}
 
-	currentDir, err := md.getWd()
+	_, stepSpan = md.tracer.Start(ctx, "mkdir")
+	err = md.createFileDirectoryIfNotExist(localDownloadPath)
+	endSpan(stepSpan, err)
 	if err != nil {
 		return err
 	}
 
-	err = md.downloadFile(node, filepath.Join(currentDir, localDownloadPath))
+	dstPath := localDownloadPath
+	if !filepath.IsAbs(dstPath) {
+		currentDir, err := md.getWd()
+		if err != nil {
+			return err
+		}
+		dstPath = filepath.Join(currentDir, localDownloadPath)
+	}
+
+	start := time.Now()
+	err = md.downloadFileWithRetries(ctx, node, dstPath, transfer)
 	if err != nil {
+		md.logger.Error("download failed", "hash", md.getNodeHash(node), "path", dstPath, "duration", time.Since(start), "error", err)
 		return err
 	}
-
+	md.logger.Info("file downloaded", "hash", md.getNodeHash(node), "path", dstPath, "bytes", md.getNodeSize(node), "duration", time.Since(start))
+	md.metrics.countDownload(md.getNodeSize(node))
 	return nil
 }
 
+// cachedTransfer wraps transfer, so that the file of given node is taken from the cache, if it is there, and added to the cache after it is downloaded. Failure to add the file does not fail the download, it is only not cached.
+func (md *MegaDownloader) cachedTransfer(node Node, transfer transferFunc) transferFunc {
+	if md.cache == nil {
+		return transfer
+	}
+	key := cacheKey(node)
+	return func(dstPath string, progress *chan int) error {
+		if md.cache.get(key, node.GetSize(), dstPath, progress) {
+			md.logger.Debug("file taken from cache", "hash", node.GetHash(), "path", dstPath, "bytes", node.GetSize())
+			close(*progress)
+			return nil
+		}
+		err := transfer(dstPath, progress)
+		if err != nil {
+			return err
+		}
+		_ = md.cache.put(key, dstPath)
+		return nil
+	}
+}
+
+// limitedTransfer wraps transfer, so that it is paused after every downloaded chunk, until the chunk fits the bandwidth limits. Transfers block on sending progress, so delaying its reception delays the download.
+func (md *MegaDownloader) limitedTransfer(transfer transferFunc) transferFunc {
+	if md.bandwidth == nil {
+		return transfer
+	}
+	return func(dstPath string, progress *chan int) error {
+		limiter := md.bandwidth.newTransfer()
+		limited := make(chan int)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			defer close(*progress)
+			for n := range limited {
+				limiter.wait(n)
+				*progress <- n
+			}
+		}()
+		err := transfer(dstPath, &limited)
+		<-done
+		return err
+	}
+}
+
 // removeOutdatedFile removes a file that is supposed to be updated.
 func (md *MegaDownloader) removeOutdatedFile(localDownloadPath string) error {
 	if _, err := os.Stat(localDownloadPath); err != nil {
@@ -72,6 +216,7 @@ func (md *MegaDownloader) removeOutdatedFile(localDownloadPath string) error {
 		if err != nil {
 			return err
 		}
+		md.logger.Debug("outdated file removed", "path", localDownloadPath)
 	}
 	return nil
 }
@@ -91,6 +236,7 @@ func (md *MegaDownloader) createFileDirectoryIfNotExist(downloadPathWithFile str
 	if err != nil {
 		return err
 	}
+	md.logger.Debug("directory created", "path", fileDirectory)
 	return nil
 }
 
@@ -98,23 +244,84 @@ func extractDirectoryFromFullPath(fullPath string) string {
 	return filepath.Dir(fullPath)
 }
 
-func (md *MegaDownloader) downloadFile(node *mega.Node, dstPath string) error {
+// downloadFileWithRetries attempts to download a file up to retryAttempts times, waiting retryDelay between attempts, and traces every attempt with a span, which is a child of the span in ctx. A download over the transfer quota is not attempted again at once, it waits for the quota to reset without counting the attempt, if quota waiting is enabled, see SetQuotaWait, or fails with QuotaError otherwise. Returns the error of the last attempt.
+func (md *MegaDownloader) downloadFileWithRetries(ctx context.Context, node Node, dstPath string, transfer transferFunc) error {
+	var err error
+	var waited time.Duration
+	for attempt := 1; attempt <= md.retryAttempts; {
+		// the directory may have been replaced with a link since the download started
+		err = md.confine(dstPath)
+		if err != nil {
+			md.logger.Warn("unsafe download path", "path", dstPath, "error", err)
+			return err
+		}
+		_, span := md.tracer.Start(ctx, "transfer", trace.WithAttributes(attrAttempt.Int(attempt)))
+		err = md.downloadFile(node, dstPath, transfer)
+		endSpan(span, err)
+		if err == nil {
+			return nil
+		}
+		if quotaErr, ok := asQuotaError(err); ok {
+			delay, ok := md.quotaDelay(quotaErr, waited)
+			if !ok {
+				return quotaErr
+			}
+			md.logger.Warn("transfer quota exceeded, waiting", "hash", md.getNodeHash(node), "path", dstPath, "duration", delay, "error", err)
+			md.metrics.countQuotaWait()
+			err = md.sleep(ctx, delay)
+			if err != nil {
+				return err
+			}
+			waited += delay
+			continue
+		}
+		attempt++
+		if attempt <= md.retryAttempts {
+			md.logger.Warn("download attempt failed, retrying", "hash", md.getNodeHash(node), "path", dstPath, "attempt", attempt-1, "error", err)
+			md.metrics.countRetry()
+			err = md.sleep(ctx, md.retryDelay)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return err
+}
+
+// sleepContext pauses for d, or until ctx is done, in which case it returns the error of ctx.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (md *MegaDownloader) downloadFile(node Node, dstPath string, transfer transferFunc) error {
 	var ch *chan int
 	var wg sync.WaitGroup
 	ch = new(chan int)
 	*ch = make(chan int)
 	wg.Add(1)
 
-	go handleDownloadProgress(*ch, &wg, md.getNodeSize(node))
-	err := md.client.DownloadFile(node, dstPath, ch)
+	size := md.getNodeSize(node)
+	md.logger.Debug("transfer started", "hash", md.getNodeHash(node), "path", dstPath, "bytes", size)
+	go handleDownloadProgress(*ch, &wg, size, md.progressOutput)
+	start := time.Now()
+	err := transfer(dstPath, ch)
 	wg.Wait()
+	md.metrics.observeTransfer(time.Since(start))
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func handleDownloadProgress(ch chan int, wg *sync.WaitGroup, size int64) {
+// handleDownloadProgress reads the number of downloaded bytes from ch and prints the progress percentage to output. Progress is not printed, if output is nil.
+func handleDownloadProgress(ch chan int, wg *sync.WaitGroup, size int64, output io.Writer) {
 	defer func() {
 		wg.Done()
 	}()
@@ -131,6 +338,8 @@ func handleDownloadProgress(ch chan int, wg *sync.WaitGroup, size int64) {
 		bytesread += b
 		percent = 100 * float32(bytesread) / float32(size)
 
-		fmt.Printf("<progress>%d</progress>\n", int(math.Round(float64(percent))))
+		if output != nil {
+			fmt.Fprintf(output, "<progress>%d</progress>\n", int(math.Round(float64(percent))))
+		}
 	}
 }
@@ -1,15 +1,21 @@
 package megabrowser
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/t3rm1n4l/go-mega"
 )
 
 var (
@@ -40,15 +46,15 @@ func TestDownloadFileSuccessCase(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			downloader := NewMegaDownloader(
-				&mockClient{},
-			)
+			client := &mockClient{}
+			downloader := NewMegaDownloader()
 			downloader.getNodeSize = mockGetNodeSize
+			downloader.getNodeHash = mockGetNodeHash
 			if test.removeFileFunction != nil {
 				downloader.removeFile = test.removeFileFunction
 			}
 
-			err := downloader.DownloadFile(nil, test.path)
+			err := downloadFile(downloader, client, test.path)
 			if test.needCleanup {
 				defer cleanupTestDir(t)
 			}
@@ -119,10 +125,9 @@ func TestDownloadFileFailCase(t *testing.T) {
 			client := &mockClient{
 				errDownload: test.downloadErr,
 			}
-			downloader := NewMegaDownloader(
-				client,
-			)
+			downloader := NewMegaDownloader()
 			downloader.getNodeSize = mockGetNodeSize
+			downloader.getNodeHash = mockGetNodeHash
 			if test.removeFileFunction != nil {
 				downloader.removeFile = test.removeFileFunction
 			}
@@ -133,7 +138,7 @@ func TestDownloadFileFailCase(t *testing.T) {
 				downloader.getWd = test.getWdFunction
 			}
 
-			err := downloader.DownloadFile(nil, test.path)
+			err := downloadFile(downloader, client, test.path)
 
 			require.NotNil(t, err)
 			require.NotEmpty(t, test.expErr)
@@ -142,6 +147,93 @@ func TestDownloadFileFailCase(t *testing.T) {
 	}
 }
 
+func TestDownloadFileToAbsolutePath(t *testing.T) {
+	client := &mockClient{}
+	downloader := NewMegaDownloader()
+	downloader.getNodeSize = mockGetNodeSize
+	downloader.getNodeHash = mockGetNodeHash
+	downloader.getWd = mockGetWdFail
+
+	err := downloadFile(downloader, client, filepath.Join(t.TempDir(), "dir", "file.txt"))
+
+	assert.Nil(t, err)
+}
+
+func TestDownloadFileRetries(t *testing.T) {
+	tests := []struct {
+		name          string
+		retryAttempts int
+		failures      int
+		expErr        error
+		expSleeps     int
+	}{
+		{
+			name:          "should succeed, if download succeeds before running out of attempts",
+			retryAttempts: 3,
+			failures:      2,
+			expErr:        nil,
+			expSleeps:     2,
+		},
+		{
+			name:          "should fail, if every attempt fails",
+			retryAttempts: 2,
+			failures:      2,
+			expErr:        errDownload,
+			expSleeps:     1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &flakyClient{failures: test.failures}
+			downloader := NewMegaDownloader()
+			downloader.getNodeSize = mockGetNodeSize
+			downloader.getNodeHash = mockGetNodeHash
+			downloader.retryAttempts = test.retryAttempts
+			downloader.retryDelay = time.Second
+			sleeps := 0
+			downloader.sleep = func(ctx context.Context, d time.Duration) error {
+				assert.Equal(t, time.Second, d)
+				sleeps++
+				return nil
+			}
+
+			err := downloadFile(downloader, client, filepath.Join(t.TempDir(), "file.txt"))
+
+			assert.Equal(t, test.expErr, err)
+			assert.Equal(t, test.expSleeps, sleeps)
+		})
+	}
+}
+
+func TestDownloadFileLogsSteps(t *testing.T) {
+	logger, output := newTestLogger()
+	client := &flakyClient{failures: 1}
+	downloader := NewMegaDownloader()
+	downloader.getNodeSize = mockGetNodeSize
+	downloader.getNodeHash = mockGetNodeHash
+	downloader.progressOutput = nil
+	downloader.retryAttempts = 2
+	downloader.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+	downloader.SetLogger(logger)
+	dstPath := filepath.Join(t.TempDir(), "dir", "file.txt")
+
+	err := downloadFile(downloader, client, dstPath)
+
+	require.Nil(t, err)
+	records := decodeLogRecords(t, output)
+	var messages []string
+	for _, record := range records {
+		messages = append(messages, record["msg"].(string))
+	}
+	assert.Equal(t, []string{"directory created", "transfer started", "download attempt failed, retrying", "transfer started", "file downloaded"}, messages)
+	downloaded := records[len(records)-1]
+	assert.Equal(t, "hash", downloaded["hash"])
+	assert.Equal(t, dstPath, downloaded["path"])
+	assert.Equal(t, float64(1), downloaded["bytes"])
+	assert.Contains(t, downloaded, "duration")
+	assert.Equal(t, errDownload.Error(), records[2]["error"])
+}
+
 func TestCreateDirectoryIfItNotExistsSuccessCase(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -161,9 +253,7 @@ func TestCreateDirectoryIfItNotExistsSuccessCase(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			downloader := NewMegaDownloader(
-				&mockClient{},
-			)
+			downloader := NewMegaDownloader()
 			if test.needCleanup {
 				defer cleanupTestDir(t)
 			}
@@ -197,9 +287,7 @@ func TestCreateDirectoryIfItNotExistsFailCase(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			downloader := NewMegaDownloader(
-				&mockClient{},
-			)
+			downloader := NewMegaDownloader()
 			if test.mkdirFunction != nil {
 				downloader.mkDir = test.mkdirFunction
 			}
@@ -213,6 +301,26 @@ func TestCreateDirectoryIfItNotExistsFailCase(t *testing.T) {
 	}
 }
 
+// downloadFile downloads a file of a Mega account with given client, whose filesystem finds every node, the way the browser does, see NewMegaBackend.
+func downloadFile(downloader Downloader, client StorageClient, localDownloadPath string) error {
+	return downloader.DownloadFromBackend(NewMegaBackend(client, &mockFs{}), &mockNode{nodeType: fileType}, localDownloadPath)
+}
+
+// flakyClient fails the first failures downloads.
+type flakyClient struct {
+	mockClient
+	failures int
+}
+
+func (f *flakyClient) DownloadFile(src *mega.Node, dstpath string, progress *chan int) error {
+	close(*progress)
+	if f.failures > 0 {
+		f.failures--
+		return errDownload
+	}
+	return nil
+}
+
 func mockRemoveFileSuccess(path string) error {
 	return nil
 }
@@ -233,6 +341,28 @@ func mockGetNodeSize(Node) int64 {
 	return int64(1)
 }
 
+func mockGetNodeHash(Node) string {
+	return "hash"
+}
+
+// newTestLogger creates a logger writing records of every level as JSON lines to the returned buffer.
+func newTestLogger() (*slog.Logger, *bytes.Buffer) {
+	var output bytes.Buffer
+	return slog.New(slog.NewJSONHandler(&output, &slog.HandlerOptions{Level: slog.LevelDebug})), &output
+}
+
+// decodeLogRecords decodes JSON lines written by a logger created with newTestLogger.
+func decodeLogRecords(t *testing.T, output *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+	decoder := json.NewDecoder(output)
+	for decoder.More() {
+		var record map[string]interface{}
+		require.Nil(t, decoder.Decode(&record))
+		records = append(records, record)
+	}
+	return records
+}
+
 func cleanupTestDir(t *testing.T) {
 	dir, err := os.Getwd()
 	require.Nil(t, err)
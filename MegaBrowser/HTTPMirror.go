@@ -0,0 +1,176 @@
+package megabrowser
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTPMirror is a Mirror serving files of a plain HTTP(S) server, under a base URL.
+type HTTPMirror struct {
+	baseURL string
+	client  *http.Client
+}
+
+// S3Mirror is a Mirror serving objects of an S3-compatible bucket, addressed in path style, e.g. https://endpoint/bucket/prefix/file. Requests are signed with AWS Signature Version 4, if an access key is set, otherwise the bucket has to be publicly readable.
+type S3Mirror struct {
+	endpoint        string
+	bucket          string
+	prefix          string
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	client          *http.Client
+	now             func() time.Time
+}
+
+// S3MirrorConfig describes an S3-compatible bucket, see NewS3Mirror. Region defaults to "us-east-1", which most S3-compatible servers accept.
+type S3MirrorConfig struct {
+	Endpoint        string `json:"endpoint" yaml:"endpoint" toml:"endpoint"`
+	Bucket          string `json:"bucket" yaml:"bucket" toml:"bucket"`
+	Prefix          string `json:"prefix" yaml:"prefix" toml:"prefix"`
+	Region          string `json:"region" yaml:"region" toml:"region"`
+	AccessKeyID     string `json:"accessKeyId" yaml:"accessKeyId" toml:"accessKeyId"`
+	SecretAccessKey string `json:"secretAccessKey" yaml:"secretAccessKey" toml:"secretAccessKey"`
+}
+
+// NewHTTPMirror creates a Mirror serving files under given base URL with given HTTP client, or a client with connection and response timeouts if it is nil, so that an unreachable mirror fails instead of blocking.
+func NewHTTPMirror(baseURL string, client *http.Client) *HTTPMirror {
+	if client == nil {
+		client = newHTTPClient()
+	}
+	return &HTTPMirror{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  client,
+	}
+}
+
+func (hm *HTTPMirror) Name() string {
+	return hm.baseURL
+}
+
+// Open requests a file of given path under the base URL. Returns an error, if the request failed or the server responded with a status other than 200 OK.
+func (hm *HTTPMirror) Open(filePath string) (io.ReadCloser, error) {
+	request, err := http.NewRequest(http.MethodGet, hm.baseURL+"/"+awsURIEncode(filePath, false), nil)
+	if err != nil {
+		return nil, err
+	}
+	return openHTTP(hm.client, request)
+}
+
+// NewS3Mirror creates a Mirror serving objects of an S3-compatible bucket with given HTTP client, or a client with connection and response timeouts if it is nil. Returns an error, if the endpoint or the bucket is not set.
+func NewS3Mirror(cfg S3MirrorConfig, client *http.Client) (*S3Mirror, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("s3 mirror endpoint and bucket must be set")
+	}
+	if client == nil {
+		client = newHTTPClient()
+	}
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3Mirror{
+		endpoint:        strings.TrimSuffix(cfg.Endpoint, "/"),
+		bucket:          cfg.Bucket,
+		prefix:          strings.Trim(cfg.Prefix, "/"),
+		region:          region,
+		accessKeyID:     cfg.AccessKeyID,
+		secretAccessKey: cfg.SecretAccessKey,
+		client:          client,
+		now:             time.Now,
+	}, nil
+}
+
+func (sm *S3Mirror) Name() string {
+	return "s3://" + strings.TrimSuffix(sm.bucket+"/"+sm.prefix, "/")
+}
+
+// Open requests an object of given path under the bucket prefix. Returns an error, if the request failed or the server responded with a status other than 200 OK.
+func (sm *S3Mirror) Open(filePath string) (io.ReadCloser, error) {
+	key := filePath
+	if sm.prefix != "" {
+		key = sm.prefix + "/" + filePath
+	}
+	uri := "/" + awsURIEncode(sm.bucket, true) + "/" + awsURIEncode(key, false)
+	request, err := http.NewRequest(http.MethodGet, sm.endpoint+uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	if sm.accessKeyID != "" {
+		sm.sign(request, uri)
+	}
+	return openHTTP(sm.client, request)
+}
+
+// sign adds AWS Signature Version 4 headers to a GET request of given canonical URI, without signing the payload.
+func (sm *S3Mirror) sign(request *http.Request, uri string) {
+	now := sm.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	request.Header.Set("x-amz-date", amzDate)
+	request.Header.Set("x-amz-content-sha256", "UNSIGNED-PAYLOAD")
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		uri,
+		"",
+		"host:" + request.URL.Host,
+		"x-amz-content-sha256:UNSIGNED-PAYLOAD",
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	scope := date + "/" + sm.region + "/s3/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := []byte("AWS4" + sm.secretAccessKey)
+	for _, part := range []string{date, sm.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", sm.accessKeyID, scope, signedHeaders, signature))
+}
+
+// openHTTP sends the request and returns the response body. Returns an error, if the request failed or the server responded with a status other than 200 OK.
+func openHTTP(client *http.Client, request *http.Request) (io.ReadCloser, error) {
+	resp, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s responded with %s", request.URL.Redacted(), resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// awsURIEncode percent-encodes every byte of s, except unreserved characters and, unless encodeSlash is true, slashes, as AWS Signature Version 4 requires.
+func awsURIEncode(s string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '_', b == '.', b == '~':
+			encoded.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			encoded.WriteByte(b)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return encoded.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
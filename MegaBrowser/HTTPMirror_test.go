@@ -0,0 +1,187 @@
+package megabrowser
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPMirrorOpen(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir(writeMirror(t, "1.0.0", map[string]string{
+		"data/my config.json": "{}",
+	}))))
+	defer server.Close()
+	mirror := NewHTTPMirror(server.URL+"/", nil)
+
+	tests := []struct {
+		name       string
+		filePath   string
+		expContent string
+		expErr     string
+	}{
+		{
+			name:       "should open file, if it exists",
+			filePath:   "data/my config.json",
+			expContent: "{}",
+		},
+		{
+			name:     "should fail, if server does not respond with OK",
+			filePath: "missing.json",
+			expErr:   server.URL + "/missing.json responded with 404 Not Found",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file, err := mirror.Open(test.filePath)
+
+			if test.expErr != "" {
+				require.NotNil(t, err)
+				assert.Equal(t, test.expErr, err.Error())
+				return
+			}
+			require.Nil(t, err)
+			defer file.Close()
+			content, err := io.ReadAll(file)
+			require.Nil(t, err)
+			assert.Equal(t, test.expContent, string(content))
+		})
+	}
+}
+
+func TestSyncWithHTTPMirror(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir(writeMirror(t, "1.0.0", map[string]string{
+		"app.bin": "binary",
+	}))))
+	defer server.Close()
+	browser := newMirrorBrowser(t, WithManifest(mirrorManifest), WithMirrors(NewHTTPMirror(server.URL, nil)))
+	require.Nil(t, browser.Initialize())
+	localDir := t.TempDir()
+
+	_, err := browser.Sync(localDir)
+
+	require.Nil(t, err)
+	content, err := os.ReadFile(filepath.Join(localDir, "app.bin"))
+	require.Nil(t, err)
+	assert.Equal(t, "binary", string(content))
+}
+
+func TestS3MirrorOpen(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         S3MirrorConfig
+		expName     string
+		expPath     string
+		expUnsigned bool
+	}{
+		{
+			name:        "should request object without signature, if access key is not set",
+			cfg:         S3MirrorConfig{Bucket: "bucket"},
+			expName:     "s3://bucket",
+			expPath:     "/bucket/app.bin",
+			expUnsigned: true,
+		},
+		{
+			name: "should request object of prefix with signature, if access key is set",
+			cfg: S3MirrorConfig{
+				Bucket:          "bucket",
+				Prefix:          "/project/",
+				Region:          "eu-central-1",
+				AccessKeyID:     "key",
+				SecretAccessKey: "secret",
+			},
+			expName: "s3://bucket/project",
+			expPath: "/bucket/project/app.bin",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var request *http.Request
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				request = r
+				_, _ = w.Write([]byte("binary"))
+			}))
+			defer server.Close()
+			test.cfg.Endpoint = server.URL
+			mirror, err := NewS3Mirror(test.cfg, nil)
+			require.Nil(t, err)
+			mirror.now = func() time.Time {
+				return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+			}
+
+			file, err := mirror.Open("app.bin")
+
+			require.Nil(t, err)
+			defer file.Close()
+			content, err := io.ReadAll(file)
+			require.Nil(t, err)
+			assert.Equal(t, "binary", string(content))
+			assert.Equal(t, test.expName, mirror.Name())
+			assert.Equal(t, test.expPath, request.URL.Path)
+			if test.expUnsigned {
+				assert.Equal(t, "", request.Header.Get("Authorization"))
+				return
+			}
+			assert.Equal(t, "20240501T120000Z", request.Header.Get("x-amz-date"))
+			assert.Equal(t, "UNSIGNED-PAYLOAD", request.Header.Get("x-amz-content-sha256"))
+			assert.Regexp(t, regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=key/20240501/eu-central-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=[0-9a-f]{64}$`), request.Header.Get("Authorization"))
+		})
+	}
+}
+
+func TestNewMirrorClientsTimeOut(t *testing.T) {
+	s3Mirror, err := NewS3Mirror(S3MirrorConfig{Endpoint: "http://s3", Bucket: "bucket"}, nil)
+	require.Nil(t, err)
+
+	for _, client := range []*http.Client{NewHTTPMirror("http://mirror", nil).client, s3Mirror.client} {
+		require.NotEqual(t, http.DefaultClient, client)
+		transport, ok := client.Transport.(*http.Transport)
+		require.True(t, ok)
+		assert.Equal(t, responseHeaderTimeout, transport.ResponseHeaderTimeout)
+	}
+}
+
+func TestNewS3MirrorFailsIfBucketIsNotSet(t *testing.T) {
+	_, err := NewS3Mirror(S3MirrorConfig{Endpoint: "https://s3.example.com"}, nil)
+
+	require.NotNil(t, err)
+	assert.Equal(t, "s3 mirror endpoint and bucket must be set", err.Error())
+}
+
+func TestAWSURIEncode(t *testing.T) {
+	tests := []struct {
+		name        string
+		s           string
+		encodeSlash bool
+		expEncoded  string
+	}{
+		{
+			name:       "should keep unreserved characters and slashes",
+			s:          "dir/file-1_2.~txt",
+			expEncoded: "dir/file-1_2.~txt",
+		},
+		{
+			name:       "should encode spaces and non-ASCII characters",
+			s:          "my file ż+",
+			expEncoded: "my%20file%20%C5%BC%2B",
+		},
+		{
+			name:        "should encode slashes, if requested",
+			s:           "a/b",
+			encodeSlash: true,
+			expEncoded:  "a%2Fb",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expEncoded, awsURIEncode(test.s, test.encodeSlash))
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package megabrowser
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// localRootHash is the hash of the root node of a LocalBackend.
+const localRootHash = "."
+
+// localCopyChunkSize is the size of the chunks, in which LocalBackend copies files, reporting progress after every one.
+const localCopyChunkSize = 1048576
+
+// LocalBackend is a Backend working on a directory of the local filesystem, e.g. a copy of the project for offline testing or a mirror shared in a local network. Hash of a node is its slash separated path relative to that directory, "." for the directory itself. Only directories and regular files are listed, symbolic links and special files are skipped.
+type LocalBackend struct {
+	dir string
+}
+
+// localNode is a file or directory of a LocalBackend.
+type localNode struct {
+	hash      string
+	parent    string
+	name      string
+	nodeType  int
+	size      int64
+	timestamp time.Time
+}
+
+// NewLocalBackend creates a Backend for given local directory. The directory is not accessed until the backend is used.
+func NewLocalBackend(dir string) *LocalBackend {
+	return &LocalBackend{dir: dir}
+}
+
+// Root returns the node of the directory itself, named after its base name.
+func (lb *LocalBackend) Root() Node {
+	root := &localNode{
+		hash:     localRootHash,
+		name:     filepath.Base(lb.dir),
+		nodeType: directoryType,
+	}
+	if absDir, err := filepath.Abs(lb.dir); err == nil {
+		root.name = filepath.Base(absDir)
+	}
+	if info, err := os.Stat(lb.dir); err == nil {
+		root.timestamp = info.ModTime()
+	}
+	return root
+}
+
+/*
+Children returns child nodes of a directory of given hash, sorted by name. A file has no children, because the local filesystem keeps no versions.
+
+Returns an error if hash is not a path inside the directory or the node could not be read.
+*/
+func (lb *LocalBackend) Children(hash string) ([]Node, error) {
+	node, err := lb.stat(hash)
+	if err != nil {
+		return nil, err
+	}
+	if node.nodeType != directoryType {
+		return nil, nil
+	}
+
+	dirPath, _ := lb.localPath(hash)
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	var children []Node
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		child, ok := newLocalNode(path.Join(hash, entry.Name()), info)
+		if ok {
+			children = append(children, child)
+		}
+	}
+	return children, nil
+}
+
+/*
+Stat returns the node of given hash.
+
+Returns an error if hash is not a path inside the directory, the node does not exist or it is neither a directory nor a regular file.
+*/
+func (lb *LocalBackend) Stat(hash string) (Node, error) {
+	return lb.stat(hash)
+}
+
+/*
+Download copies a file of given hash to dstPath, sending the number of bytes written to progress, if it is not nil, and closing it when finished.
+
+Returns an error if the node is not a regular file or failed to copy it, in which case the partially written dstPath is removed.
+*/
+func (lb *LocalBackend) Download(hash string, dstPath string, progress *chan int) error {
+	if progress != nil {
+		defer close(*progress)
+	}
+
+	node, err := lb.stat(hash)
+	if err != nil {
+		return err
+	}
+	if node.nodeType != fileType {
+		return fmt.Errorf("could not find file node: %s", hash)
+	}
+	srcPath, _ := lb.localPath(hash)
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return err
+	}
+	err = copyWithProgress(dst, src, progress)
+	closeErr := dst.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(dstPath)
+		return err
+	}
+	return nil
+}
+
+// stat returns the node of given hash, see Stat.
+func (lb *LocalBackend) stat(hash string) (*localNode, error) {
+	localPath, err := lb.localPath(hash)
+	if err != nil {
+		return nil, err
+	}
+	// The directory itself may be a symbolic link, nodes inside it may not.
+	lstat := os.Lstat
+	if hash == localRootHash {
+		lstat = os.Stat
+	}
+	info, err := lstat(localPath)
+	if err != nil {
+		return nil, err
+	}
+	node, ok := newLocalNode(hash, info)
+	if !ok {
+		return nil, fmt.Errorf("not a regular file or directory: %s", hash)
+	}
+	if hash == localRootHash {
+		node.parent = ""
+		node.name = lb.Root().GetName()
+	}
+	return node, nil
+}
+
+// localPath returns path of the node of given hash in the local filesystem. Returns an error, if the hash is not a path inside the directory.
+func (lb *LocalBackend) localPath(hash string) (string, error) {
+	localHash := filepath.FromSlash(hash)
+	if !filepath.IsLocal(localHash) {
+		return "", fmt.Errorf("invalid local node hash: %q", hash)
+	}
+	return filepath.Join(lb.dir, localHash), nil
+}
+
+// newLocalNode creates a node of given hash from its file info. Returns false, if the file is neither a directory nor a regular file.
+func newLocalNode(hash string, info os.FileInfo) (*localNode, bool) {
+	node := &localNode{
+		hash:      hash,
+		parent:    path.Dir(hash),
+		name:      info.Name(),
+		timestamp: info.ModTime(),
+	}
+	switch {
+	case info.IsDir():
+		node.nodeType = directoryType
+	case info.Mode().IsRegular():
+		node.nodeType = fileType
+		node.size = info.Size()
+	default:
+		return nil, false
+	}
+	return node, true
+}
+
+// copyWithProgress copies src to dst in chunks, sending size of every chunk to progress, if it is not nil.
+func copyWithProgress(dst io.Writer, src io.Reader, progress *chan int) error {
+	buf := make([]byte, localCopyChunkSize)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			_, writeErr := dst.Write(buf[:n])
+			if writeErr != nil {
+				return writeErr
+			}
+			if progress != nil {
+				*progress <- n
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (ln *localNode) GetName() string {
+	return ln.name
+}
+
+func (ln *localNode) GetType() int {
+	return ln.nodeType
+}
+
+func (ln *localNode) GetHash() string {
+	return ln.hash
+}
+
+func (ln *localNode) GetSize() int64 {
+	return ln.size
+}
+
+func (ln *localNode) GetTimeStamp() time.Time {
+	return ln.timestamp
+}
+
+func (ln *localNode) GetParentHash() string {
+	return ln.parent
+}
+
+// GetPath returns an empty path, the browser wraps nodes it resolves with their paths, see pathNode.
+func (ln *localNode) GetPath() string {
+	return ""
+}
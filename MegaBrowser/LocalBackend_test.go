@@ -0,0 +1,173 @@
+package megabrowser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalBackendChildren(t *testing.T) {
+	tests := []struct {
+		name      string
+		hash      string
+		expHashes []string
+		expParent string
+		expErr    string
+	}{
+		{
+			name:      "should list files and directories, skipping symbolic links, if hash is the root",
+			hash:      ".",
+			expHashes: []string{expDirName, "readme.txt"},
+			expParent: ".",
+		},
+		{
+			name:      "should list nested nodes with slash separated hashes, if hash is a directory",
+			hash:      expDirName,
+			expHashes: []string{expDirName + "/" + expFileName},
+			expParent: expDirName,
+		},
+		{
+			name:      "should list no children, if hash is a file",
+			hash:      "readme.txt",
+			expHashes: nil,
+		},
+		{
+			name:   "should fail, if hash points outside of the directory",
+			hash:   "../outside",
+			expErr: "invalid local node hash",
+		},
+		{
+			name:   "should fail, if node does not exist",
+			hash:   "missing",
+			expErr: "no such file or directory",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			backend := NewLocalBackend(writeLocalRepository(t))
+
+			children, err := backend.Children(test.hash)
+
+			if test.expErr != "" {
+				require.NotNil(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+				return
+			}
+			require.Nil(t, err)
+			var hashes []string
+			for _, child := range children {
+				hashes = append(hashes, child.GetHash())
+				assert.Equal(t, test.expParent, child.GetParentHash())
+				assert.False(t, child.GetTimeStamp().IsZero())
+			}
+			assert.Equal(t, test.expHashes, hashes)
+		})
+	}
+}
+
+func TestLocalBackendStat(t *testing.T) {
+	dir := writeLocalRepository(t)
+	backend := NewLocalBackend(dir)
+
+	root, err := backend.Stat(".")
+	require.Nil(t, err)
+	assert.Equal(t, filepath.Base(dir), root.GetName())
+	assert.Equal(t, backend.Root().GetHash(), root.GetHash())
+	assert.Equal(t, "", root.GetParentHash())
+
+	file, err := backend.Stat(expDirName + "/" + expFileName)
+	require.Nil(t, err)
+	assert.Equal(t, expFileName, file.GetName())
+	assert.Equal(t, fileType, file.GetType())
+	assert.Equal(t, int64(len("content")), file.GetSize())
+
+	_, err = backend.Stat("link.txt")
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "not a regular file or directory")
+}
+
+func TestLocalBackendDownload(t *testing.T) {
+	tests := []struct {
+		name   string
+		hash   string
+		expErr string
+	}{
+		{
+			name: "should copy file and report progress, if hash is a file",
+			hash: expDirName + "/" + expFileName,
+		},
+		{
+			name:   "should fail, if hash is a directory",
+			hash:   expDirName,
+			expErr: "could not find file node: " + expDirName,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			backend := NewLocalBackend(writeLocalRepository(t))
+			dstPath := filepath.Join(t.TempDir(), "downloaded")
+			progress := make(chan int)
+			written := make(chan int)
+			go func() {
+				total := 0
+				for n := range progress {
+					total += n
+				}
+				written <- total
+			}()
+
+			err := backend.Download(test.hash, dstPath, &progress)
+
+			total := <-written
+			if test.expErr != "" {
+				require.NotNil(t, err)
+				assert.Equal(t, test.expErr, err.Error())
+				assert.NoFileExists(t, dstPath)
+				return
+			}
+			require.Nil(t, err)
+			content, err := os.ReadFile(dstPath)
+			require.Nil(t, err)
+			assert.Equal(t, "content", string(content))
+			assert.Equal(t, len("content"), total)
+		})
+	}
+}
+
+func TestSyncWithLocalBackend(t *testing.T) {
+	repository := writeLocalRepository(t)
+	localDir := t.TempDir()
+	downloader := NewMegaDownloader()
+	downloader.progressOutput = nil
+	browser, err := New(
+		WithRootNode(filepath.Base(repository)),
+		WithBackend(NewLocalBackend(repository)),
+		WithDownloader(downloader),
+	)
+	require.Nil(t, err)
+	require.Nil(t, browser.Initialize())
+
+	entries, err := browser.Sync(localDir)
+
+	require.Nil(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, expDirName+"/"+expFileName, entries[0].Path)
+	assert.Equal(t, "readme.txt", entries[1].Path)
+	content, err := os.ReadFile(filepath.Join(localDir, expDirName, expFileName))
+	require.Nil(t, err)
+	assert.Equal(t, "content", string(content))
+	assert.True(t, browser.Anonymous())
+}
+
+// writeLocalRepository creates a directory containing readme.txt, expDir/expFile and a symbolic link to readme.txt.
+func writeLocalRepository(t *testing.T) string {
+	dir := t.TempDir()
+	require.Nil(t, os.Mkdir(filepath.Join(dir, expDirName), 0777))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, expDirName, expFileName), []byte("content"), 0666))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "readme.txt"), []byte("readme"), 0666))
+	require.Nil(t, os.Symlink("readme.txt", filepath.Join(dir, "link.txt")))
+	return dir
+}
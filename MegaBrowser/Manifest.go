@@ -0,0 +1,184 @@
+package megabrowser
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrChecksumMismatch is returned, if a downloaded file does not match its size or checksum listed in the manifest, or is not listed in it at all, see WithManifest.
+var ErrChecksumMismatch = errors.New("downloaded file does not match the manifest")
+
+// ErrInvalidSignature is returned by Initialize, if the manifest signature is missing or was not made with the private key of the manifest key, see WithManifestKey.
+var ErrInvalidSignature = errors.New("invalid manifest signature")
+
+// signatureSuffix is appended to the manifest name to get the name of its signature file, see WithSigningKey.
+const signatureSuffix = ".sig"
+
+// manifestIndex is a Manifest with its files indexed by their paths. Generated are the manifest itself and its signature, which are not listed in the manifest, but are part of the project root.
+type manifestIndex struct {
+	version   string
+	files     map[string]ManifestFile
+	generated map[string]ManifestFile
+}
+
+// parseManifest parses a Manifest in JSON format, as generated by Publisher, and indexes its files. Returns an error, if it is malformed or any file path is not a clean path relative to the project root.
+func parseManifest(content []byte) (*manifestIndex, error) {
+	var manifest Manifest
+	err := json.Unmarshal(content, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	index := &manifestIndex{
+		version:   manifest.Version,
+		files:     map[string]ManifestFile{},
+		generated: map[string]ManifestFile{},
+	}
+	for _, file := range manifest.Files {
+		if !validManifestPath(file.Path) {
+			return nil, fmt.Errorf("invalid manifest file path: %q", file.Path)
+		}
+		index.files[file.Path] = file
+	}
+	return index, nil
+}
+
+// loadManifest reads the manifest from the project root node, if the browser was created with WithManifest.
+func (mb *MegaBrowser) loadManifest() error {
+	if mb.manifestFile == "" {
+		return nil
+	}
+	nodes, err := mb.listChildren(mb.rootNodeHash)
+	if err != nil {
+		return err
+	}
+	mb.manifest, err = mb.readManifest(func(name string) ([]byte, error) {
+		hash, err := getNodeHashOfNewestFile(name, &nodes)
+		if err != nil {
+			return nil, err
+		}
+		return mb.readRemoteFile(hash)
+	})
+	return err
+}
+
+/*
+readManifest reads the manifest with given function, which returns the content of a file of given name in the project root, and verifies its signature, if the browser was created with WithManifestKey. Without a manifest key the signature is read, if there is one, only so that it can be downloaded like the manifest.
+
+Returns an error if:
+
+	failed to read the manifest or its signature
+	the signature does not match, see ErrInvalidSignature
+	the manifest is malformed, see parseManifest
+*/
+func (mb *MegaBrowser) readManifest(read func(name string) ([]byte, error)) (*manifestIndex, error) {
+	content, err := read(mb.manifestFile)
+	if err != nil {
+		return nil, err
+	}
+	signature, signatureErr := read(mb.manifestFile + signatureSuffix)
+	if mb.manifestKey != nil {
+		if signatureErr != nil {
+			return nil, fmt.Errorf("failed to read manifest signature: %w", signatureErr)
+		}
+		err = verifySignature(mb.manifestKey, content, signature)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	manifest, err := parseManifest(content)
+	if err != nil {
+		return nil, err
+	}
+	manifest.addGenerated(mb.manifestFile, content)
+	if signatureErr == nil {
+		manifest.addGenerated(mb.manifestFile+signatureSuffix, signature)
+	}
+	return manifest, nil
+}
+
+// addGenerated lists a file of given path and content, which is not listed in the manifest, e.g. the manifest itself, so that it is verified like the listed files.
+func (mi *manifestIndex) addGenerated(filePath string, content []byte) {
+	checksum := sha256.Sum256(content)
+	mi.generated[filePath] = ManifestFile{Path: filePath, Size: int64(len(content)), SHA256: hex.EncodeToString(checksum[:])}
+}
+
+// lookup returns the entry of a file of given path, listed in the manifest or generated with it.
+func (mi *manifestIndex) lookup(filePath string) (ManifestFile, bool) {
+	if file, ok := mi.files[filePath]; ok {
+		return file, true
+	}
+	file, ok := mi.generated[filePath]
+	return file, ok
+}
+
+// signManifest returns the signature of the manifest content made with given key, as written by Publisher next to the manifest.
+func signManifest(key ed25519.PrivateKey, content []byte) []byte {
+	return []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key, content)) + "\n")
+}
+
+// verifySignature returns ErrInvalidSignature, if signature, written by signManifest, was not made of the manifest content with the private key of given public key.
+func verifySignature(key ed25519.PublicKey, content []byte, signature []byte) error {
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil || !ed25519.Verify(key, content, decoded) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+/*
+verifyFile compares a downloaded file at localPath with the manifest entry of given path, relative to the project root, and removes the local file, if it does not match. A file not listed in the manifest does not match either, except the manifest itself and its signature, which are compared with the ones the browser read.
+
+The verification is traced with a span, which is a child of the span in ctx.
+
+Returns an error if failed to read the local file or it does not match, see ErrChecksumMismatch.
+*/
+func (mb *MegaBrowser) verifyFile(ctx context.Context, remotePath string, localPath string) (err error) {
+	if mb.manifest == nil {
+		return nil
+	}
+	expected, ok := mb.manifest.lookup(remotePath)
+	if !ok {
+		mb.logger.Warn("file is not listed in manifest", "path", remotePath)
+		_ = os.Remove(localPath)
+		return fmt.Errorf("%w: %s is not listed", ErrChecksumMismatch, remotePath)
+	}
+	_, span := mb.tracer.Start(ctx, "verify", trace.WithAttributes(attrPath.String(remotePath), attrBytes.Int64(expected.Size)))
+	defer func() { endSpan(span, err) }()
+
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+	if info.Size() == expected.Size {
+		checksum, err := fileChecksum(localPath)
+		if err != nil {
+			return err
+		}
+		if checksum == expected.SHA256 {
+			mb.logger.Debug("file verified", "path", remotePath, "bytes", info.Size())
+			return nil
+		}
+	}
+	mb.logger.Warn("file does not match manifest", "path", remotePath, "bytes", info.Size())
+	_ = os.Remove(localPath)
+	return fmt.Errorf("%w: %s", ErrChecksumMismatch, remotePath)
+}
+
+// validManifestPath returns true, if given path is a clean, slash separated path inside the project root.
+func validManifestPath(filePath string) bool {
+	return filePath != "" && filePath != "." && path.Clean(filePath) == filePath && !path.IsAbs(filePath) &&
+		filePath != ".." && !strings.HasPrefix(filePath, "../") && !strings.Contains(filePath, "\\")
+}
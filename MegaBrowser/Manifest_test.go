@@ -0,0 +1,111 @@
+package megabrowser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expFiles int
+		expErr   string
+	}{
+		{
+			name:     "should parse manifest, if it is valid",
+			content:  `{"version":"1.0.0","files":[{"path":"dir/app.bin","size":6,"sha256":"abc"}]}`,
+			expFiles: 1,
+		},
+		{
+			name:    "should fail, if manifest is malformed",
+			content: `{`,
+			expErr:  "failed to parse manifest",
+		},
+		{
+			name:    "should fail, if file path is outside project root",
+			content: `{"version":"1.0.0","files":[{"path":"../app.bin"}]}`,
+			expErr:  `invalid manifest file path: "../app.bin"`,
+		},
+		{
+			name:    "should fail, if file path is not clean",
+			content: `{"version":"1.0.0","files":[{"path":"dir//app.bin"}]}`,
+			expErr:  `invalid manifest file path: "dir//app.bin"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			manifest, err := parseManifest([]byte(test.content))
+
+			if test.expErr != "" {
+				require.NotNil(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+				return
+			}
+			require.Nil(t, err)
+			assert.Equal(t, "1.0.0", manifest.version)
+			assert.Len(t, manifest.files, test.expFiles)
+		})
+	}
+}
+
+func TestSyncWithManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		expErr  error
+	}{
+		{
+			name:    "should sync, if files match the manifest",
+			content: "binary",
+		},
+		{
+			name:    "should fail and remove the file, if it does not match the manifest",
+			content: "tamper",
+			expErr:  ErrChecksumMismatch,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository := writeMirror(t, "1.0.0", map[string]string{"app.bin": "binary"})
+			require.Nil(t, os.WriteFile(filepath.Join(repository, "app.bin"), []byte(test.content), 0666))
+			downloader := NewMegaDownloader()
+			downloader.progressOutput = nil
+			browser, err := New(
+				WithBackend(NewLocalBackend(repository)),
+				WithDownloader(downloader),
+				WithManifest(mirrorManifest),
+			)
+			require.Nil(t, err)
+			require.Nil(t, browser.Initialize())
+			localDir := t.TempDir()
+
+			_, err = browser.Sync(localDir)
+
+			if test.expErr != nil {
+				assert.ErrorIs(t, err, test.expErr)
+				assert.NoFileExists(t, filepath.Join(localDir, "app.bin"))
+				return
+			}
+			require.Nil(t, err)
+			assert.FileExists(t, filepath.Join(localDir, "app.bin"))
+		})
+	}
+}
+
+func TestInitializeFailsIfManifestIsMissing(t *testing.T) {
+	browser, err := New(
+		WithBackend(NewLocalBackend(writeLocalRepository(t))),
+		WithManifest(mirrorManifest),
+	)
+	require.Nil(t, err)
+
+	err = browser.Initialize()
+
+	require.NotNil(t, err)
+	assert.Equal(t, "", browser.Mirror())
+}
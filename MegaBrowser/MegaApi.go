@@ -0,0 +1,139 @@
+package megabrowser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/t3rm1n4l/go-mega"
+)
+
+// defaultAPIURL is the address of the Mega API server.
+const defaultAPIURL = "https://g.api.mega.co.nz"
+
+// Timeouts of the HTTP client created with newHTTPClient and of a single API call. Transfers of file content are not limited, they stop when the context of the request is cancelled.
+const (
+	dialTimeout           = 30 * time.Second
+	tlsHandshakeTimeout   = 10 * time.Second
+	responseHeaderTimeout = time.Minute
+	apiCallTimeout        = 2 * time.Minute
+)
+
+// megaAPI is a minimal client of the Mega API, used for requests not supported by t3rm1n4l/go-mega package.
+type megaAPI struct {
+	url    string
+	client *http.Client
+	seq    uint64
+}
+
+func newMegaAPI() *megaAPI {
+	return &megaAPI{
+		url:    defaultAPIURL,
+		client: newHTTPClient(),
+	}
+}
+
+// newHTTPClient creates an HTTP client, which fails, if a server does not accept the connection or does not respond in time, unlike http.DefaultClient, which waits forever.
+func newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: dialTimeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = tlsHandshakeTimeout
+	transport.ResponseHeaderTimeout = responseHeaderTimeout
+	return &http.Client{Transport: transport}
+}
+
+/*
+call sends a single command msg to the API, with additional query parameters, and decodes its result into res. The call is cancelled with ctx or after apiCallTimeout.
+
+Returns an error if:
+
+	the request failed, timed out or the server responded with a status other than 200 OK
+	the server responded with an error code, mapped to the errors of t3rm1n4l/go-mega package
+	the response could not be decoded
+*/
+func (api *megaAPI) call(ctx context.Context, query url.Values, msg interface{}, res interface{}) error {
+	request, err := json.Marshal([]interface{}{msg})
+	if err != nil {
+		return err
+	}
+
+	params := url.Values{}
+	for key, values := range query {
+		params[key] = values
+	}
+	params.Set("id", strconv.FormatUint(atomic.AddUint64(&api.seq, 1), 10))
+
+	ctx, cancel := context.WithTimeout(ctx, apiCallTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, api.url+"/cs?"+params.Encode(), bytes.NewReader(request))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := api.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("mega api responded with %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var errno int
+	if json.Unmarshal(body, &errno) == nil {
+		return megaError(errno)
+	}
+	var results []json.RawMessage
+	err = json.Unmarshal(body, &results)
+	if err != nil || len(results) == 0 {
+		return mega.EBADRESP
+	}
+	if json.Unmarshal(results[0], &errno) == nil {
+		return megaError(errno)
+	}
+	return json.Unmarshal(results[0], res)
+}
+
+// megaError maps a Mega API error code to the matching error of t3rm1n4l/go-mega package.
+func megaError(errno int) error {
+	switch errno {
+	case 0:
+		return nil
+	case -2:
+		return mega.EARGS
+	case -3:
+		return mega.EAGAIN
+	case -4:
+		return mega.ERATELIMIT
+	case -9:
+		return mega.ENOENT
+	case -11:
+		return mega.EACCESS
+	case -14:
+		return mega.EKEY
+	case -15:
+		return mega.ESID
+	case -16:
+		return mega.EBLOCKED
+	case -17:
+		return mega.EOVERQUOTA
+	case -18:
+		return mega.ETEMPUNAVAIL
+	case -26:
+		return mega.EMFAREQUIRED
+	}
+	return fmt.Errorf("mega api error %d", errno)
+}
@@ -6,3 +6,26 @@ type StorageClient interface {
 	Login(login string, pass string) error
 	DownloadFile(src *mega.Node, dstpath string, progress *chan int) error
 }
+
+// SessionClient is a StorageClient able to resume an authenticated session with a token, so that the password is needed only for the first login. MegaBrowser uses it, if its client implements it.
+//
+// The client from t3rm1n4l/go-mega package resumes sessions from their parts, wrap it with NewMegaSessionClient to take advantage of sessions.
+type SessionClient interface {
+	StorageClient
+	SessionToken() (string, error)
+	ResumeSession(token string) error
+}
+
+// MFAClient is a StorageClient able to log in to an account with multi-factor authentication enabled, like the client from t3rm1n4l/go-mega package. MegaBrowser uses it, if the account requires a multi-factor code.
+type MFAClient interface {
+	StorageClient
+	MultiFactorLogin(login string, pass string, code string) error
+}
+
+// UploadClient is a StorageClient able to modify the Mega repository, like the client from t3rm1n4l/go-mega package. Publisher requires it.
+type UploadClient interface {
+	StorageClient
+	UploadFile(srcpath string, parent *mega.Node, name string, progress *chan int) (*mega.Node, error)
+	CreateDir(name string, parent *mega.Node) (*mega.Node, error)
+	Delete(node *mega.Node, destroy bool) error
+}
@@ -7,3 +7,9 @@ type Fs interface {
 	GetRoot() *mega.Node
 	HashLookup(string) *mega.Node
 }
+
+// NodeFs is a Fs describing its nodes itself, instead of relying on attributes of mega.Node structures, which cannot be created outside of t3rm1n4l/go-mega package, e.g. the in-memory fake of megatest package. The browser uses it, if its filesystem implements it, see NewMegaBackend.
+type NodeFs interface {
+	Fs
+	Describe(node *mega.Node) Node
+}
@@ -2,15 +2,22 @@ package megabrowser
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/t3rm1n4l/go-mega"
 )
 
 const (
 	// fileType is the integer value, which specifies that a node is a file, not a directory.
-	fileType = 0
+	fileType = mega.FILE
 	// directoryType is the integer value, which specifies that a node is a directory, not a file.
-	directoryType = 1
+	directoryType = mega.FOLDER
+	// rootType is the integer value, which specifies that a node is the root of the account cloud drive.
+	rootType = mega.ROOT
+	// inboxType is the integer value, which specifies that a node is the account inbox, holding files sent by other users.
+	inboxType = mega.INBOX
+	// trashType is the integer value, which specifies that a node is the account rubbish bin.
+	trashType = mega.TRASH
 )
 
 type Node interface {
@@ -18,15 +25,94 @@ type Node interface {
 	GetType() int
 	GetHash() string
 	GetSize() int64
+	// GetTimeStamp returns time of the last modification of the node, which is zero if it is not known.
+	GetTimeStamp() time.Time
+	// GetParentHash returns hash of the parent node, which is empty for a node without a parent or if it is not known.
+	GetParentHash() string
+	// GetPath returns path of the node relative to the project root, which is empty if the node was not resolved by the browser.
+	GetPath() string
+}
+
+// megaNode adapts mega.Node from t3rm1n4l/go-mega package to the Node interface, which requires the parent node, not exposed by mega.Node.
+type megaNode struct {
+	*mega.Node
+	parent *mega.Node
+}
+
+// GetParentHash returns hash of the parent node, which is empty if the node was not obtained as a child of another node.
+func (mn megaNode) GetParentHash() string {
+	if mn.parent == nil {
+		return ""
+	}
+	return mn.parent.GetHash()
+}
+
+// GetPath returns an empty path, the browser wraps nodes it resolves with their paths, see pathNode.
+func (mn megaNode) GetPath() string {
+	return ""
+}
+
+// pathNode is a Node resolved by the browser, knowing its path relative to the project root.
+type pathNode struct {
+	Node
+	path string
+}
+
+func (pn pathNode) GetPath() string {
+	return pn.path
+}
+
+// NodeTypeName returns a short name of given node type: "file", "dir", "root", "inbox", "trash" or "unknown".
+func NodeTypeName(nodeType int) string {
+	switch nodeType {
+	case fileType:
+		return "file"
+	case directoryType:
+		return "dir"
+	case rootType:
+		return "root"
+	case inboxType:
+		return "inbox"
+	case trashType:
+		return "trash"
+	}
+	return "unknown"
+}
+
+// isContainerType returns true, if nodes of given type can have child nodes other than file versions, i.e. they are directories, the root, the inbox or the trash.
+func isContainerType(nodeType int) bool {
+	return nodeType == directoryType || nodeType == rootType || nodeType == inboxType || nodeType == trashType
 }
 
 type getNodeSizeFunc func(Node) int64
+type getNodeHashFunc func(Node) string
 
-// nodeStructArrToInterfaceArr converts array of mega.Node structures to an array of Node interface instances, to make it more generic and allow testing.
-func nodeStructArrToInterfaceArr(nodes []*mega.Node) []Node {
+// describeNodes converts array of mega.Node structures, which are children of given parent node, to an array of Node interface instances, described by the filesystem, if it implements NodeFs, see nodeStructArrToInterfaceArr.
+func describeNodes(fs Fs, nodes []*mega.Node, parent *mega.Node) []Node {
+	nodeFs, ok := fs.(NodeFs)
+	if !ok {
+		return nodeStructArrToInterfaceArr(nodes, parent)
+	}
 	convertedNodes := make([]Node, len(nodes))
 	for i, node := range nodes {
-		convertedNodes[i] = node
+		convertedNodes[i] = nodeFs.Describe(node)
+	}
+	return convertedNodes
+}
+
+// describeNode converts a mega.Node structure to a Node interface instance, described by the filesystem, if it implements NodeFs. Parent of the node is not known.
+func describeNode(fs Fs, node *mega.Node) Node {
+	if nodeFs, ok := fs.(NodeFs); ok {
+		return nodeFs.Describe(node)
+	}
+	return megaNode{Node: node}
+}
+
+// nodeStructArrToInterfaceArr converts array of mega.Node structures, which are children of given parent node, to an array of Node interface instances, to make it more generic and allow testing.
+func nodeStructArrToInterfaceArr(nodes []*mega.Node, parent *mega.Node) []Node {
+	convertedNodes := make([]Node, len(nodes))
+	for i, node := range nodes {
+		convertedNodes[i] = megaNode{Node: node, parent: parent}
 	}
 	return convertedNodes
 }
@@ -40,6 +126,24 @@ func getNodeHashOfExpectedFile(expectedFile string, currDirChildNodes *[]Node) (
 	return hash, nil
 }
 
+// getNodeHashOfNewestFile is like getNodeHashOfExpectedFile, but if there are several files of the name, e.g. because a replaced file could not be moved to the trash, it returns the hash of the newest one, and of the greatest hash among equally old ones, so that every reader picks the same file.
+func getNodeHashOfNewestFile(expectedFile string, currDirChildNodes *[]Node) (string, error) {
+	var newest Node
+	for _, child := range *currDirChildNodes {
+		if child.GetName() != expectedFile || child.GetType() != fileType {
+			continue
+		}
+		if newest == nil || child.GetTimeStamp().After(newest.GetTimeStamp()) ||
+			(child.GetTimeStamp().Equal(newest.GetTimeStamp()) && child.GetHash() > newest.GetHash()) {
+			newest = child
+		}
+	}
+	if newest == nil {
+		return "", fmt.Errorf("could not find file: %s", expectedFile)
+	}
+	return newest.GetHash(), nil
+}
+
 // getNodeHashOfExpectedDirectory expects that given list of nodes contains a directory of specific name. Returns that directory's hash, otherwise, if that file is not found, returns an error
 func getNodeHashOfExpectedDirectory(expectedDirectory string, currDirChildNodes *[]Node) (string, error) {
 	hash := getNodeHashOfExpectedItem(expectedDirectory, directoryType, currDirChildNodes)
@@ -62,3 +166,8 @@ func getNodeHashOfExpectedItem(expectedItem string, expectedItemType int, currDi
 func getNodeSize(node Node) int64 {
 	return node.GetSize()
 }
+
+// getNodeHash is a wrapper function that calls the node's GetHash() function, like getNodeSize.
+func getNodeHash(node Node) string {
+	return node.GetHash()
+}
@@ -3,16 +3,19 @@ package megabrowser
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/t3rm1n4l/go-mega"
 )
 
 type mockNode struct {
-	name     string
-	nodeType int
-	hash     string
-	size     int64
+	name      string
+	nodeType  int
+	hash      string
+	size      int64
+	timestamp time.Time
+	parent    string
 }
 
 func TestShouldConvertStructArrayToInterfaceArray(t *testing.T) {
@@ -20,9 +23,38 @@ func TestShouldConvertStructArrayToInterfaceArray(t *testing.T) {
 		{}, {},
 	}
 
-	convertedNodes := nodeStructArrToInterfaceArr(nodes)
+	convertedNodes := nodeStructArrToInterfaceArr(nodes, nil)
 
 	assert.Equal(t, len(nodes), len(convertedNodes))
+	assert.Empty(t, convertedNodes[0].GetParentHash())
+	assert.Empty(t, convertedNodes[0].GetPath())
+}
+
+func TestNodeTypeName(t *testing.T) {
+	tests := []struct {
+		nodeType int
+		expName  string
+	}{
+		{nodeType: mega.FILE, expName: "file"},
+		{nodeType: mega.FOLDER, expName: "dir"},
+		{nodeType: mega.ROOT, expName: "root"},
+		{nodeType: mega.INBOX, expName: "inbox"},
+		{nodeType: mega.TRASH, expName: "trash"},
+		{nodeType: 42, expName: "unknown"},
+	}
+	for _, test := range tests {
+		t.Run(test.expName, func(t *testing.T) {
+			assert.Equal(t, test.expName, NodeTypeName(test.nodeType))
+		})
+	}
+}
+
+func TestPathNodeOverridesPath(t *testing.T) {
+	node := pathNode{Node: &mockNode{name: "file", parent: "parenthash"}, path: "dir/file"}
+
+	assert.Equal(t, "dir/file", node.GetPath())
+	assert.Equal(t, "file", node.GetName())
+	assert.Equal(t, "parenthash", node.GetParentHash())
 }
 
 func TestShouldReturnNodeHashIfFileExistsOnTheList(t *testing.T) {
@@ -127,3 +159,15 @@ func (m *mockNode) GetHash() string {
 func (m *mockNode) GetSize() int64 {
 	return m.size
 }
+
+func (m *mockNode) GetTimeStamp() time.Time {
+	return m.timestamp
+}
+
+func (m *mockNode) GetParentHash() string {
+	return m.parent
+}
+
+func (m *mockNode) GetPath() string {
+	return ""
+}
@@ -0,0 +1,61 @@
+package megabrowser
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/t3rm1n4l/go-mega"
+)
+
+// sessionTokenSeparator separates the session id, the master key and the user handle in a session token.
+const sessionTokenSeparator = "."
+
+// errNotLoggedIn is returned by MegaSessionClient.SessionToken, if the client has no session yet.
+var errNotLoggedIn = errors.New("client is not logged in")
+
+/*
+MegaSessionClient wraps the client from t3rm1n4l/go-mega package, so that it implements SessionClient with the Session and ResumeSession methods of the fork in third_party/go-mega. NewMegaBrowserFromConfig uses it.
+
+A session token grants full access to the account until the session expires, so it should be kept encrypted, e.g. in a FileSessionStore.
+*/
+type MegaSessionClient struct {
+	*mega.Mega
+}
+
+// NewMegaSessionClient wraps given client, e.g. created with mega.New().
+func NewMegaSessionClient(client *mega.Mega) *MegaSessionClient {
+	return &MegaSessionClient{Mega: client}
+}
+
+// SessionToken returns a token of the session established by Login or MultiFactorLogin, made of the session id, the master key and the user handle, which ResumeSession restores. Returns an error, if the client is not logged in.
+func (c *MegaSessionClient) SessionToken() (string, error) {
+	sid, key, uh := c.Session()
+	if sid == "" || len(key) == 0 {
+		return "", errNotLoggedIn
+	}
+	return strings.Join([]string{sid, base64.RawURLEncoding.EncodeToString(key), base64.RawURLEncoding.EncodeToString(uh)}, sessionTokenSeparator), nil
+}
+
+/*
+ResumeSession restores the session of given token, returned by SessionToken, and loads the nodes of the account with it, so that no password is needed. The client is initialized the same way as by a password login, and left logged out, if the session cannot be resumed.
+
+Returns an error if:
+
+	the token is malformed
+	the session expired or was closed, see mega.ESID
+	failed to load the nodes
+*/
+func (c *MegaSessionClient) ResumeSession(token string) error {
+	parts := strings.Split(token, sessionTokenSeparator)
+	if len(parts) != 3 || parts[0] == "" {
+		return fmt.Errorf("malformed session token")
+	}
+	masterKey, keyErr := base64.RawURLEncoding.DecodeString(parts[1])
+	userHandle, handleErr := base64.RawURLEncoding.DecodeString(parts[2])
+	if keyErr != nil || handleErr != nil || len(masterKey) != 16 {
+		return fmt.Errorf("malformed session token")
+	}
+	return c.Mega.ResumeSession(parts[0], masterKey, userHandle)
+}
@@ -0,0 +1,161 @@
+package megabrowser
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/t3rm1n4l/go-mega"
+)
+
+const (
+	accountSessionID = "session-id"
+	accountRootHash  = "rOOT0001"
+	accountDirHash   = "pROJECT1"
+)
+
+var (
+	accountMasterKey  = []byte("accountmasterkey")
+	accountUserHandle = []byte("userhandle")
+)
+
+// fakeAccountServer emulates the Mega API for an account with a single session, which has the project root directory in its cloud drive.
+type fakeAccountServer struct {
+	*httptest.Server
+	nodes    []mega.FSNode
+	mutex    sync.Mutex
+	commands []string
+	closed   chan struct{}
+}
+
+func TestMegaSessionClientResumeSession(t *testing.T) {
+	server := newFakeAccountServer(t)
+	client := NewMegaSessionClient(mega.New())
+	client.SetAPIUrl(server.URL)
+	client.SetLogger(nil)
+	token := sessionToken(accountSessionID)
+
+	err := client.ResumeSession(token)
+
+	require.Nil(t, err)
+	children, err := client.FS.GetChildren(client.FS.GetRoot())
+	require.Nil(t, err)
+	require.Len(t, children, 1)
+	assert.Equal(t, rootNodeName, children[0].GetName())
+	resumedToken, err := client.SessionToken()
+	assert.Nil(t, err)
+	assert.Equal(t, token, resumedToken)
+	assert.Equal(t, []string{"f"}, server.receivedCommands())
+}
+
+func TestMegaSessionClientResumeSessionFailCase(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		expErr error
+	}{
+		{
+			name:   "should fail, if session expired",
+			token:  sessionToken("expired-session"),
+			expErr: mega.ESID,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newFakeAccountServer(t)
+			client := NewMegaSessionClient(mega.New())
+			client.SetAPIUrl(server.URL)
+			client.SetLogger(nil)
+
+			err := client.ResumeSession(test.token)
+
+			assert.Equal(t, test.expErr, err)
+			_, err = client.SessionToken()
+			assert.Equal(t, errNotLoggedIn, err)
+		})
+	}
+}
+
+func TestMegaSessionClientRejectsMalformedToken(t *testing.T) {
+	tokens := []string{"", "session-id", "session-id.", ".key.handle", "session-id.c2hvcnQ.aGFuZGxl", "session-id.!!!.aGFuZGxl", "session-id." + encodeKey(accountMasterKey), "session-id." + encodeKey(accountMasterKey) + ".!!!"}
+	for _, token := range tokens {
+		client := NewMegaSessionClient(mega.New())
+
+		err := client.ResumeSession(token)
+
+		assert.EqualError(t, err, "malformed session token", token)
+	}
+}
+
+func TestMegaSessionClientSessionTokenFailsIfNotLoggedIn(t *testing.T) {
+	client := NewMegaSessionClient(mega.New())
+
+	token, err := client.SessionToken()
+
+	assert.Equal(t, errNotLoggedIn, err)
+	assert.Empty(t, token)
+}
+
+// sessionToken returns a token of the session of given id in the account of fakeAccountServer.
+func sessionToken(sid string) string {
+	return sid + sessionTokenSeparator + encodeKey(accountMasterKey) + sessionTokenSeparator + base64.RawURLEncoding.EncodeToString(accountUserHandle)
+}
+
+func newFakeAccountServer(t *testing.T) *fakeAccountServer {
+	dirKey := []byte("projectdirectory")
+	server := &fakeAccountServer{
+		nodes: []mega.FSNode{
+			{Hash: accountRootHash, T: mega.ROOT},
+			{Hash: accountDirHash, Parent: accountRootHash, User: "owner", T: mega.FOLDER, Key: "owner:" + encryptECB(t, accountMasterKey, dirKey), Attr: encryptAttr(t, dirKey, rootNodeName)},
+		},
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.handle))
+	server.closed = make(chan struct{})
+	t.Cleanup(server.Close)
+	t.Cleanup(func() {
+		close(server.closed)
+	})
+	return server
+}
+
+func (s *fakeAccountServer) handle(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/sc":
+		// there are no pending events, the client waits for new ones at the wait URL
+		_ = json.NewEncoder(w).Encode(mega.Events{W: s.URL + "/wait"})
+		return
+	case "/wait":
+		select {
+		case <-r.Context().Done():
+		case <-s.closed:
+		}
+		return
+	}
+	var msgs []struct {
+		Cmd string `json:"a"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&msgs)
+	s.mutex.Lock()
+	s.commands = append(s.commands, msgs[0].Cmd)
+	s.mutex.Unlock()
+
+	switch {
+	case r.URL.Query().Get("sid") != accountSessionID:
+		_ = json.NewEncoder(w).Encode(-15)
+	case msgs[0].Cmd == "f":
+		_ = json.NewEncoder(w).Encode([]interface{}{mega.FilesResp{F: s.nodes, Sn: "sn"}})
+	default:
+		_ = json.NewEncoder(w).Encode(-2)
+	}
+}
+
+func (s *fakeAccountServer) receivedCommands() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, s.commands...)
+}
@@ -0,0 +1,210 @@
+package megabrowser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// metricsContentType is the content type of the Prometheus text exposition format.
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// transferDurationBuckets are upper bounds of the transfer duration histogram buckets in seconds.
+var transferDurationBuckets = []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 900}
+
+/*
+Metrics counts operations of browsers and downloaders, see WithMetrics and MegaDownloader.SetMetrics. A single Metrics is usually shared by a browser and its downloader, and it is safe for concurrent use.
+
+Metrics is an http.Handler serving the counters in the Prometheus text format, so that updaters running as services can be scraped, and can write them with WriteTo, e.g. into a file read by a node exporter textfile collector.
+*/
+type Metrics struct {
+	mutex            sync.Mutex
+	lookups          uint64
+	childrenRequests uint64
+	downloadedBytes  uint64
+	filesUpdated     uint64
+	filesSkipped     uint64
+	filesFailed      uint64
+	retries          uint64
+	quotaWaits       uint64
+	transferBuckets  []uint64
+	transfers        uint64
+	transferSeconds  float64
+}
+
+// MetricsSnapshot holds values of Metrics at a single moment.
+type MetricsSnapshot struct {
+	// Lookups is the number of paths looked up in the project.
+	Lookups uint64
+	// ChildrenRequests is the number of requests for children of a node, i.e. Fs.GetChildren calls for a Mega account.
+	ChildrenRequests uint64
+	// DownloadedBytes is the size of downloaded files, including files taken from the cache.
+	DownloadedBytes uint64
+	// FilesUpdated, FilesSkipped and FilesFailed count files synchronized, found up to date and failed to synchronize.
+	FilesUpdated uint64
+	FilesSkipped uint64
+	FilesFailed  uint64
+	// Retries is the number of repeated download attempts, QuotaWaits the number of pauses for the transfer quota to reset.
+	Retries    uint64
+	QuotaWaits uint64
+	// Transfers is the number of download attempts, which took TransferSeconds together.
+	Transfers       uint64
+	TransferSeconds float64
+}
+
+// NewMetrics creates metrics with every counter at zero.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		transferBuckets: make([]uint64, len(transferDurationBuckets)),
+	}
+}
+
+// Snapshot returns the current values.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return MetricsSnapshot{
+		Lookups:          m.lookups,
+		ChildrenRequests: m.childrenRequests,
+		DownloadedBytes:  m.downloadedBytes,
+		FilesUpdated:     m.filesUpdated,
+		FilesSkipped:     m.filesSkipped,
+		FilesFailed:      m.filesFailed,
+		Retries:          m.retries,
+		QuotaWaits:       m.quotaWaits,
+		Transfers:        m.transfers,
+		TransferSeconds:  m.transferSeconds,
+	}
+}
+
+// ServeHTTP responds with the metrics in the Prometheus text format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", metricsContentType)
+	_, _ = m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format to w. Returns the number of bytes written and the error of writing, if any.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	writeCounter(cw, "megabrowser_lookups_total", "Paths looked up in the project.", m.lookups)
+	writeCounter(cw, "megabrowser_children_requests_total", "Requests for children of a node.", m.childrenRequests)
+	writeCounter(cw, "megabrowser_downloaded_bytes_total", "Size of downloaded files in bytes.", m.downloadedBytes)
+	writeHeader(cw, "megabrowser_files_total", "Files by synchronization result.", "counter")
+	fmt.Fprintf(cw, "megabrowser_files_total{result=\"updated\"} %d\n", m.filesUpdated)
+	fmt.Fprintf(cw, "megabrowser_files_total{result=\"skipped\"} %d\n", m.filesSkipped)
+	fmt.Fprintf(cw, "megabrowser_files_total{result=\"failed\"} %d\n", m.filesFailed)
+	writeCounter(cw, "megabrowser_download_retries_total", "Repeated download attempts.", m.retries)
+	writeCounter(cw, "megabrowser_quota_waits_total", "Pauses for the transfer quota to reset.", m.quotaWaits)
+	writeHeader(cw, "megabrowser_transfer_duration_seconds", "Duration of download attempts.", "histogram")
+	for i, bound := range transferDurationBuckets {
+		fmt.Fprintf(cw, "megabrowser_transfer_duration_seconds_bucket{le=\"%s\"} %d\n", formatFloat(bound), m.transferBuckets[i])
+	}
+	fmt.Fprintf(cw, "megabrowser_transfer_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.transfers)
+	fmt.Fprintf(cw, "megabrowser_transfer_duration_seconds_sum %s\n", formatFloat(m.transferSeconds))
+	fmt.Fprintf(cw, "megabrowser_transfer_duration_seconds_count %d\n", m.transfers)
+
+	err := cw.w.Flush()
+	if cw.err == nil {
+		cw.err = err
+	}
+	return cw.n, cw.err
+}
+
+// countLookup counts a path lookup. Metrics methods counting operations do nothing, if m is nil, so that callers do not have to check whether metrics are enabled.
+func (m *Metrics) countLookup() {
+	m.update(func() { m.lookups++ })
+}
+
+// countFiles counts files synchronized, found up to date and failed to synchronize.
+func (m *Metrics) countFiles(updated uint64, skipped uint64, failed uint64) {
+	m.update(func() {
+		m.filesUpdated += updated
+		m.filesSkipped += skipped
+		m.filesFailed += failed
+	})
+}
+
+// countDownload counts a downloaded file of given size.
+func (m *Metrics) countDownload(size int64) {
+	m.update(func() { m.downloadedBytes += uint64(max(size, 0)) })
+}
+
+// countRetry counts a repeated download attempt.
+func (m *Metrics) countRetry() {
+	m.update(func() { m.retries++ })
+}
+
+// countQuotaWait counts a pause for the transfer quota to reset.
+func (m *Metrics) countQuotaWait() {
+	m.update(func() { m.quotaWaits++ })
+}
+
+// observeTransfer records a download attempt, which took given time.
+func (m *Metrics) observeTransfer(d time.Duration) {
+	m.update(func() {
+		seconds := d.Seconds()
+		for i, bound := range transferDurationBuckets {
+			if seconds <= bound {
+				m.transferBuckets[i]++
+			}
+		}
+		m.transfers++
+		m.transferSeconds += seconds
+	})
+}
+
+// countChildren counts a request for children of a node.
+func (m *Metrics) countChildren() {
+	m.update(func() { m.childrenRequests++ })
+}
+
+// update changes the counters with fn, holding the lock.
+func (m *Metrics) update(fn func()) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	fn()
+}
+
+// countingWriter counts bytes written to w and keeps the first error, so that a sequence of writes can be checked once.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
+
+func writeHeader(w io.Writer, name string, help string, metricType string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func writeCounter(w io.Writer, name string, help string, value uint64) {
+	writeHeader(w, name, help, "counter")
+	fmt.Fprintf(w, "%s %d\n", name, value)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
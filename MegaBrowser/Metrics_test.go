@@ -0,0 +1,140 @@
+package megabrowser
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsCountSync(t *testing.T) {
+	metrics := NewMetrics()
+	downloader := NewMegaDownloader()
+	downloader.progressOutput = nil
+	downloader.SetMetrics(metrics)
+	browser, err := New(
+		WithBackend(NewLocalBackend(writeLocalRepository(t))),
+		WithDownloader(downloader),
+		WithMetrics(metrics),
+	)
+	require.Nil(t, err)
+	require.Nil(t, browser.Initialize())
+	localDir := t.TempDir()
+	_, err = browser.Sync(localDir)
+	require.Nil(t, err)
+	require.Nil(t, os.WriteFile(filepath.Join(localDir, "readme.txt"), []byte("changed"), 0666))
+
+	_, err = browser.Sync(localDir)
+
+	require.Nil(t, err)
+	snapshot := metrics.Snapshot()
+	// Both synchronizations list the root and the directory, the second one downloads only the changed file.
+	assert.Equal(t, uint64(4), snapshot.ChildrenRequests)
+	assert.Equal(t, uint64(3), snapshot.FilesUpdated)
+	assert.Equal(t, uint64(1), snapshot.FilesSkipped)
+	assert.Equal(t, uint64(0), snapshot.FilesFailed)
+	assert.Equal(t, uint64(len("content")+2*len("readme")), snapshot.DownloadedBytes)
+	assert.Equal(t, uint64(3), snapshot.Transfers)
+}
+
+func TestMetricsCountRetries(t *testing.T) {
+	metrics := NewMetrics()
+	client := &flakyClient{failures: 2}
+	downloader := NewMegaDownloader()
+	downloader.getNodeSize = mockGetNodeSize
+	downloader.getNodeHash = mockGetNodeHash
+	downloader.progressOutput = nil
+	downloader.retryAttempts = 3
+	downloader.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+	downloader.SetMetrics(metrics)
+
+	err := downloadFile(downloader, client, filepath.Join(t.TempDir(), "file.txt"))
+
+	require.Nil(t, err)
+	snapshot := metrics.Snapshot()
+	assert.Equal(t, uint64(2), snapshot.Retries)
+	assert.Equal(t, uint64(3), snapshot.Transfers)
+	assert.Equal(t, uint64(1), snapshot.DownloadedBytes)
+}
+
+func TestMetricsCountLookups(t *testing.T) {
+	metrics := NewMetrics()
+	browser, err := New(WithBackend(NewLocalBackend(writeLocalRepository(t))), WithMetrics(metrics))
+	require.Nil(t, err)
+	require.Nil(t, browser.Initialize())
+
+	_, err = browser.Stat(expDirName + "/" + expFileName)
+	require.Nil(t, err)
+	_, err = browser.ListDirectory(expDirName)
+	require.Nil(t, err)
+	_, err = browser.GetObjectNode("missing.txt")
+	require.NotNil(t, err)
+
+	assert.Equal(t, uint64(3), metrics.Snapshot().Lookups)
+}
+
+func TestMetricsServeHTTP(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.countLookup()
+	metrics.countFiles(2, 1, 0)
+	metrics.observeTransfer(750 * time.Millisecond)
+	metrics.observeTransfer(2 * time.Minute)
+
+	tests := []struct {
+		name      string
+		method    string
+		expStatus int
+	}{
+		{
+			name:      "should serve metrics in Prometheus text format",
+			method:    http.MethodGet,
+			expStatus: http.StatusOK,
+		},
+		{
+			name:      "should reject other methods",
+			method:    http.MethodPost,
+			expStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			metrics.ServeHTTP(recorder, httptest.NewRequest(test.method, "/metrics", nil))
+
+			assert.Equal(t, test.expStatus, recorder.Code)
+			if test.expStatus != http.StatusOK {
+				return
+			}
+			assert.Equal(t, metricsContentType, recorder.Header().Get("Content-Type"))
+			body := recorder.Body.String()
+			assert.Contains(t, body, "# TYPE megabrowser_lookups_total counter\nmegabrowser_lookups_total 1\n")
+			assert.Contains(t, body, "megabrowser_files_total{result=\"updated\"} 2\nmegabrowser_files_total{result=\"skipped\"} 1\n")
+			assert.Contains(t, body, "megabrowser_transfer_duration_seconds_bucket{le=\"0.5\"} 0\nmegabrowser_transfer_duration_seconds_bucket{le=\"1\"} 1\n")
+			assert.Contains(t, body, "megabrowser_transfer_duration_seconds_bucket{le=\"300\"} 2\n")
+			assert.Contains(t, body, "megabrowser_transfer_duration_seconds_sum 120.75\nmegabrowser_transfer_duration_seconds_count 2\n")
+		})
+	}
+}
+
+func TestNewMegaBrowserFromConfigWithMetrics(t *testing.T) {
+	cfg, err := LoadConfig("", func(key string) string {
+		return map[string]string{
+			EnvLocalDir:    t.TempDir(),
+			EnvMetricsFile: filepath.Join(t.TempDir(), "megabrowser.prom"),
+		}[key]
+	})
+	require.Nil(t, err)
+
+	browser, err := NewMegaBrowserFromConfig(cfg)
+
+	require.Nil(t, err)
+	require.NotNil(t, browser.Metrics())
+	assert.Same(t, browser.Metrics(), browser.downloader.(*MegaDownloader).metrics)
+}
@@ -0,0 +1,281 @@
+package megabrowser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+var errNoManifest = fmt.Errorf("mirrors require a manifest, see WithManifest")
+
+var errNoManifestKey = fmt.Errorf("mirrors require a manifest key, see WithManifestKey")
+
+// maxMirrorManifestSize is the size of the biggest manifest or signature read from a mirror, so that a broken mirror cannot exhaust the memory.
+const maxMirrorManifestSize = 16 << 20
+
+/*
+Mirror is an alternative source of the project tree, used when the Mega repository is unavailable, see WithMirrors. A mirror serves a copy of the project root directory of a single release, including the manifest generated by Publisher, which describes the files served by the mirror, and its signature, which proves that the manifest comes from the publisher and not from the mirror, see WithManifestKey.
+
+Open opens a file of given slash separated path, relative to the mirror root. Name identifies the mirror in errors and logs.
+*/
+type Mirror interface {
+	Name() string
+	Open(filePath string) (io.ReadCloser, error)
+}
+
+// LocalMirror is a Mirror serving files of a local directory, e.g. a network share or a USB drive.
+type LocalMirror struct {
+	dir string
+}
+
+// NewLocalMirror creates a Mirror serving files of given local directory.
+func NewLocalMirror(dir string) *LocalMirror {
+	return &LocalMirror{dir: dir}
+}
+
+func (lm *LocalMirror) Name() string {
+	return lm.dir
+}
+
+// Open opens a file of given path inside the mirror directory. Returns an error, if the path is not inside the directory.
+func (lm *LocalMirror) Open(filePath string) (io.ReadCloser, error) {
+	localPath := filepath.FromSlash(filePath)
+	if !filepath.IsLocal(localPath) {
+		return nil, fmt.Errorf("invalid mirror file path: %q", filePath)
+	}
+	return os.Open(filepath.Join(lm.dir, localPath))
+}
+
+// mirrorBackend is a Backend serving the files listed in the manifest of a mirror. Hash of a node is its path relative to the mirror root, "." for the root itself.
+type mirrorBackend struct {
+	mirror   Mirror
+	manifest *manifestIndex
+	nodes    map[string]*mirrorNode
+	children map[string][]Node
+}
+
+// fallbackState holds the browser state replaced by a mirror, see restorePrimary.
+type fallbackState struct {
+	backend Backend
+}
+
+// mirrorNode is a file or directory of a mirror.
+type mirrorNode struct {
+	hash     string
+	parent   string
+	name     string
+	nodeType int
+	size     int64
+}
+
+// newMirrorBackend creates a Backend serving files of given mirror, which are listed in its manifest, with directories inferred from the file paths. The manifest itself and its signature are served as well.
+func newMirrorBackend(mirror Mirror, manifest *manifestIndex) *mirrorBackend {
+	mb := &mirrorBackend{
+		mirror:   mirror,
+		manifest: manifest,
+		nodes:    map[string]*mirrorNode{},
+		children: map[string][]Node{},
+	}
+	mb.nodes[localRootHash] = &mirrorNode{hash: localRootHash, name: mirror.Name(), nodeType: directoryType}
+
+	files := map[string]int64{}
+	for _, listed := range []map[string]ManifestFile{manifest.files, manifest.generated} {
+		for filePath, file := range listed {
+			files[filePath] = file.Size
+		}
+	}
+	paths := make([]string, 0, len(files))
+	for filePath := range files {
+		paths = append(paths, filePath)
+	}
+	sort.Strings(paths)
+	for _, filePath := range paths {
+		mb.add(filePath, fileType, files[filePath])
+	}
+	return mb
+}
+
+// add adds a node of given path and its missing parent directories.
+func (mb *mirrorBackend) add(nodePath string, nodeType int, size int64) {
+	if _, ok := mb.nodes[nodePath]; ok {
+		return
+	}
+	parent := path.Dir(nodePath)
+	if parent != localRootHash {
+		mb.add(parent, directoryType, 0)
+	}
+	node := &mirrorNode{
+		hash:     nodePath,
+		parent:   parent,
+		name:     path.Base(nodePath),
+		nodeType: nodeType,
+		size:     size,
+	}
+	mb.nodes[nodePath] = node
+	mb.children[parent] = append(mb.children[parent], node)
+}
+
+// Root returns the node of the mirror root, named after the mirror.
+func (mb *mirrorBackend) Root() Node {
+	return mb.nodes[localRootHash]
+}
+
+func (mb *mirrorBackend) Children(hash string) ([]Node, error) {
+	node, ok := mb.nodes[hash]
+	if !ok {
+		return nil, fmt.Errorf("could not find node: %s", hash)
+	}
+	if node.nodeType != directoryType {
+		return nil, nil
+	}
+	return mb.children[hash], nil
+}
+
+func (mb *mirrorBackend) Stat(hash string) (Node, error) {
+	node, ok := mb.nodes[hash]
+	if !ok {
+		return nil, fmt.Errorf("could not find node: %s", hash)
+	}
+	return node, nil
+}
+
+// Download copies a file of the mirror to dstPath. Reads at most one byte more than the size listed in the manifest and fails, if the size does not match. Its content is verified by the browser afterwards, see WithManifest.
+func (mb *mirrorBackend) Download(hash string, dstPath string, progress *chan int) error {
+	if progress != nil {
+		defer close(*progress)
+	}
+	node, ok := mb.nodes[hash]
+	if !ok || node.nodeType != fileType {
+		return fmt.Errorf("could not find file node: %s", hash)
+	}
+	src, err := mb.mirror.Open(hash)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return err
+	}
+	err = copyWithProgress(dst, io.LimitReader(src, node.size+1), progress)
+	if err == nil {
+		var info os.FileInfo
+		info, err = dst.Stat()
+		if err == nil && info.Size() != node.size {
+			err = fmt.Errorf("mirror sent %d bytes, expected %d", info.Size(), node.size)
+		}
+	}
+	closeErr := dst.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(dstPath)
+		return err
+	}
+	return nil
+}
+
+/*
+initializeMirrors makes the browser work on the first of its mirrors, whose manifest could be read and has a valid signature, after the primary source failed with primaryErr. If the browser works on releases, the manifest version becomes the current release, but other releases are not available.
+
+Returns an error joining primaryErr with errors of every mirror, if none of them could be used, or with errNoManifest or errNoManifestKey, if the browser cannot verify files of the mirrors.
+*/
+func (mb *MegaBrowser) initializeMirrors(primaryErr error) error {
+	if mb.manifestFile == "" {
+		return errors.Join(primaryErr, errNoManifest)
+	}
+	if mb.manifestKey == nil {
+		return errors.Join(primaryErr, errNoManifestKey)
+	}
+
+	errs := []error{primaryErr}
+	for _, mirror := range mb.mirrors {
+		backend, err := mb.openMirror(mirror)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("mirror %s: %w", mirror.Name(), err))
+			continue
+		}
+
+		mb.logger.Warn("using mirror", "mirror", mirror.Name(), "error", primaryErr)
+		mb.fallback = &fallbackState{backend: mb.backend}
+		mb.mirror = mirror.Name()
+		mb.backend = backend
+		mb.rootNodeHash = localRootHash
+		mb.releasesHash = ""
+		mb.manifest = backend.manifest
+		if mb.releases {
+			mb.release = backend.manifest.version
+		}
+		return nil
+	}
+	return errors.Join(errs...)
+}
+
+// openMirror reads the manifest of given mirror, verifies its signature and creates a backend serving its files. Returns an error, if the manifest or the signature is bigger than maxMirrorManifestSize.
+func (mb *MegaBrowser) openMirror(mirror Mirror) (*mirrorBackend, error) {
+	manifest, err := mb.readManifest(func(name string) ([]byte, error) {
+		file, err := mirror.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		content, err := io.ReadAll(io.LimitReader(file, maxMirrorManifestSize+1))
+		if err == nil && len(content) > maxMirrorManifestSize {
+			err = fmt.Errorf("%s is bigger than %d bytes", name, maxMirrorManifestSize)
+		}
+		return content, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newMirrorBackend(mirror, manifest), nil
+}
+
+// restorePrimary restores the browser state replaced by a mirror, so that the primary source is tried again.
+func (mb *MegaBrowser) restorePrimary() {
+	if mb.fallback == nil {
+		return
+	}
+	mb.backend = mb.fallback.backend
+	mb.fallback = nil
+	mb.mirror = ""
+	mb.manifest = nil
+	mb.release = ""
+}
+
+func (mn *mirrorNode) GetName() string {
+	return mn.name
+}
+
+func (mn *mirrorNode) GetType() int {
+	return mn.nodeType
+}
+
+func (mn *mirrorNode) GetHash() string {
+	return mn.hash
+}
+
+func (mn *mirrorNode) GetSize() int64 {
+	return mn.size
+}
+
+// GetTimeStamp returns zero time, because manifests do not list modification times.
+func (mn *mirrorNode) GetTimeStamp() time.Time {
+	return time.Time{}
+}
+
+func (mn *mirrorNode) GetParentHash() string {
+	return mn.parent
+}
+
+// GetPath returns an empty path, the browser wraps nodes it resolves with their paths, see pathNode.
+func (mn *mirrorNode) GetPath() string {
+	return ""
+}
@@ -0,0 +1,323 @@
+package megabrowser
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/t3rm1n4l/go-mega"
+)
+
+const mirrorManifest = "manifest.json"
+
+// mirrorKey signs manifests of the mirrors written by writeMirror.
+var mirrorKey = ed25519.NewKeyFromSeed([]byte("mirror signing key seed of 32 by"))
+
+func TestInitializeWithMirrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		mirrors    func(t *testing.T) []Mirror
+		noManifest bool
+		noKey      bool
+		expMirror  int
+		expErrs    []error
+	}{
+		{
+			name: "should use the mirror, if the primary source fails",
+			mirrors: func(t *testing.T) []Mirror {
+				return []Mirror{NewLocalMirror(writeMirror(t, "1.0.0", nil))}
+			},
+		},
+		{
+			name: "should use the next mirror, if manifest of the first one could not be read",
+			mirrors: func(t *testing.T) []Mirror {
+				return []Mirror{NewLocalMirror(t.TempDir()), NewLocalMirror(writeMirror(t, "1.0.0", nil))}
+			},
+			expMirror: 1,
+		},
+		{
+			name: "should fail with every error, if no mirror could be used",
+			mirrors: func(t *testing.T) []Mirror {
+				return []Mirror{NewLocalMirror(t.TempDir())}
+			},
+			expMirror: -1,
+			expErrs:   []error{errLogin, os.ErrNotExist},
+		},
+		{
+			name: "should fail, if manifest of the mirror is not signed with the manifest key",
+			mirrors: func(t *testing.T) []Mirror {
+				dir := writeMirror(t, "1.0.0", nil)
+				forged := ed25519.NewKeyFromSeed([]byte("forged signing key seed of 32 by"))
+				signManifestFile(t, dir, forged)
+				return []Mirror{NewLocalMirror(dir)}
+			},
+			expMirror: -1,
+			expErrs:   []error{errLogin, ErrInvalidSignature},
+		},
+		{
+			name: "should fail, if manifest of the mirror is not signed at all",
+			mirrors: func(t *testing.T) []Mirror {
+				dir := writeMirror(t, "1.0.0", nil)
+				require.Nil(t, os.Remove(filepath.Join(dir, mirrorManifest+signatureSuffix)))
+				return []Mirror{NewLocalMirror(dir)}
+			},
+			expMirror: -1,
+			expErrs:   []error{errLogin, os.ErrNotExist},
+		},
+		{
+			name: "should fail, if mirrors are used without a manifest key",
+			mirrors: func(t *testing.T) []Mirror {
+				return []Mirror{NewLocalMirror(writeMirror(t, "1.0.0", nil))}
+			},
+			noKey:     true,
+			expMirror: -1,
+			expErrs:   []error{errLogin, errNoManifestKey},
+		},
+		{
+			name: "should fail, if mirrors are used without a manifest",
+			mirrors: func(t *testing.T) []Mirror {
+				return []Mirror{NewLocalMirror(writeMirror(t, "1.0.0", nil))}
+			},
+			noManifest: true,
+			expMirror:  -1,
+			expErrs:    []error{errLogin, errNoManifest},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mirrors := test.mirrors(t)
+			opts := []Option{WithMirrors(mirrors...)}
+			if !test.noManifest {
+				opts = append(opts, WithManifest(mirrorManifest))
+			}
+			browser := newMirrorBrowser(t, opts...)
+			if test.noKey {
+				browser.manifestKey = nil
+			}
+
+			err := browser.Initialize()
+
+			if test.expMirror < 0 {
+				require.NotNil(t, err)
+				for _, expErr := range test.expErrs {
+					assert.ErrorIs(t, err, expErr)
+				}
+				assert.Equal(t, "", browser.Mirror())
+				return
+			}
+			require.Nil(t, err)
+			assert.Equal(t, mirrors[test.expMirror].Name(), browser.Mirror())
+			assert.Equal(t, "1.0.0", browser.manifest.version)
+		})
+	}
+}
+
+func TestSyncWithMirror(t *testing.T) {
+	dir := writeMirror(t, "1.0.0", map[string]string{
+		"app.bin":          "binary",
+		"data/config.json": "{}",
+	})
+	browser := newMirrorBrowser(t, WithManifest(mirrorManifest), WithMirrors(NewLocalMirror(dir)))
+	require.Nil(t, browser.Initialize())
+	localDir := t.TempDir()
+
+	entries, err := browser.Sync(localDir)
+
+	require.Nil(t, err)
+	var paths []string
+	for _, entry := range entries {
+		paths = append(paths, entry.Path)
+	}
+	assert.Equal(t, []string{"app.bin", "data/config.json", mirrorManifest, mirrorManifest + signatureSuffix}, paths)
+	content, err := os.ReadFile(filepath.Join(localDir, "data", "config.json"))
+	require.Nil(t, err)
+	assert.Equal(t, "{}", string(content))
+}
+
+func TestSyncWithMirrorFailsIfFileDoesNotMatchManifest(t *testing.T) {
+	dir := writeMirror(t, "1.0.0", map[string]string{"app.bin": "binary"})
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "app.bin"), []byte("tamper"), 0666))
+	browser := newMirrorBrowser(t, WithManifest(mirrorManifest), WithMirrors(NewLocalMirror(dir)))
+	require.Nil(t, browser.Initialize())
+	localDir := t.TempDir()
+
+	_, err := browser.Sync(localDir)
+
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.NoFileExists(t, filepath.Join(localDir, "app.bin"))
+}
+
+func TestSyncWithMirrorFailsIfFileIsNotListedInManifest(t *testing.T) {
+	dir := writeMirror(t, "1.0.0", map[string]string{"app.bin": "binary"})
+	browser := newMirrorBrowser(t, WithManifest(mirrorManifest), WithMirrors(NewLocalMirror(dir)))
+	require.Nil(t, browser.Initialize())
+	localPath := filepath.Join(t.TempDir(), "extra.bin")
+	require.Nil(t, os.WriteFile(localPath, []byte("extra"), 0666))
+
+	err := browser.verifyFile(context.Background(), "extra.bin", localPath)
+
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.NoFileExists(t, localPath)
+}
+
+func TestMirrorDownloadFailsIfSizeDoesNotMatch(t *testing.T) {
+	dir := writeMirror(t, "1.0.0", map[string]string{"app.bin": "binary"})
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "app.bin"), []byte("binary, but much longer than listed in the manifest"), 0666))
+	backend, err := newMirrorBrowser(t, WithManifest(mirrorManifest)).openMirror(NewLocalMirror(dir))
+	require.Nil(t, err)
+	dstPath := filepath.Join(t.TempDir(), "app.bin")
+
+	err = backend.Download("app.bin", dstPath, nil)
+
+	require.NotNil(t, err)
+	assert.Equal(t, "mirror sent 7 bytes, expected 6", err.Error())
+	assert.NoFileExists(t, dstPath)
+}
+
+func TestOpenMirrorFailsIfManifestIsTooBig(t *testing.T) {
+	browser := newMirrorBrowser(t, WithManifest(mirrorManifest))
+
+	_, err := browser.openMirror(endlessMirror{})
+
+	require.NotNil(t, err)
+	assert.Equal(t, fmt.Sprintf("%s is bigger than %d bytes", mirrorManifest, maxMirrorManifestSize), err.Error())
+}
+
+func TestInitializeDoesNotUseMirrorsIfAuthenticationFails(t *testing.T) {
+	tests := []struct {
+		name     string
+		errLogin error
+	}{
+		{
+			name:     "should not use mirrors, if account rejects credentials",
+			errLogin: mega.ENOENT,
+		},
+		{
+			name:     "should not use mirrors, if account is blocked",
+			errLogin: mega.EBLOCKED,
+		},
+		{
+			name:     "should not use mirrors, if account requires a multi-factor code",
+			errLogin: mega.EMFAREQUIRED,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			browser := newMirrorBrowser(t, WithManifest(mirrorManifest), WithMirrors(NewLocalMirror(writeMirror(t, "1.0.0", nil))))
+			browser.megaClient = &mockClient{errLogin: test.errLogin}
+
+			err := browser.Initialize()
+
+			require.NotNil(t, err)
+			assert.Equal(t, "", browser.Mirror())
+		})
+	}
+}
+
+func TestInitializeRestoresPrimaryAfterMirror(t *testing.T) {
+	primary := t.TempDir()
+	backend := NewLocalBackend(primary)
+	browser, err := New(
+		WithBackend(backend),
+		WithManifest(mirrorManifest),
+		WithManifestKey(mirrorKey.Public().(ed25519.PublicKey)),
+		WithMirrors(NewLocalMirror(writeMirror(t, "1.0.0", nil))),
+	)
+	require.Nil(t, err)
+	// The primary source fails, because the manifest is missing there.
+	require.Nil(t, browser.Initialize())
+	require.NotEqual(t, "", browser.Mirror())
+
+	require.Nil(t, os.WriteFile(filepath.Join(primary, mirrorManifest), []byte(`{"version":"2.0.0","files":[]}`), 0666))
+	signManifestFile(t, primary, mirrorKey)
+	err = browser.Initialize()
+
+	require.Nil(t, err)
+	assert.Equal(t, "", browser.Mirror())
+	assert.Equal(t, backend, browser.backend)
+	assert.Equal(t, "2.0.0", browser.manifest.version)
+}
+
+func TestLocalMirrorOpenFailsIfPathIsOutsideDirectory(t *testing.T) {
+	mirror := NewLocalMirror(t.TempDir())
+
+	_, err := mirror.Open("../secret")
+
+	require.NotNil(t, err)
+	assert.Equal(t, `invalid mirror file path: "../secret"`, err.Error())
+}
+
+// endlessMirror is a Mirror serving files, which never end.
+type endlessMirror struct{}
+
+func (endlessMirror) Name() string {
+	return "endless"
+}
+
+func (endlessMirror) Open(string) (io.ReadCloser, error) {
+	return io.NopCloser(endlessReader{}), nil
+}
+
+// endlessReader fills every buffer with spaces.
+type endlessReader struct{}
+
+func (endlessReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = ' '
+	}
+	return len(p), nil
+}
+
+// newMirrorBrowser creates a browser whose login always fails, so that its mirrors are used.
+func newMirrorBrowser(t *testing.T, opts ...Option) *MegaBrowser {
+	downloader := NewMegaDownloader()
+	downloader.progressOutput = nil
+	browser, err := New(append([]Option{
+		WithCredentials(login, pass),
+		WithRootNode(rootNodeName),
+		WithClient(&mockClient{errLogin: errLogin}),
+		WithFs(&mockFs{}),
+		WithDownloader(downloader),
+		WithManifestKey(mirrorKey.Public().(ed25519.PublicKey)),
+	}, opts...)...)
+	require.Nil(t, err)
+	return browser
+}
+
+// writeMirror creates a mirror directory containing given files and their manifest of given version, signed with mirrorKey.
+func writeMirror(t *testing.T, version string, files map[string]string) string {
+	dir := t.TempDir()
+	manifest := Manifest{Version: version, Files: []ManifestFile{}}
+	for filePath, content := range files {
+		localPath := filepath.Join(dir, filepath.FromSlash(filePath))
+		require.Nil(t, os.MkdirAll(filepath.Dir(localPath), 0777))
+		require.Nil(t, os.WriteFile(localPath, []byte(content), 0666))
+		checksum := sha256.Sum256([]byte(content))
+		manifest.Files = append(manifest.Files, ManifestFile{
+			Path:   filePath,
+			Size:   int64(len(content)),
+			SHA256: hex.EncodeToString(checksum[:]),
+		})
+	}
+	content, err := json.Marshal(manifest)
+	require.Nil(t, err)
+	require.Nil(t, os.WriteFile(filepath.Join(dir, mirrorManifest), content, 0666))
+	signManifestFile(t, dir, mirrorKey)
+	return dir
+}
+
+// signManifestFile writes the signature of the manifest in given directory, made with given key.
+func signManifestFile(t *testing.T, dir string, key ed25519.PrivateKey) {
+	content, err := os.ReadFile(filepath.Join(dir, mirrorManifest))
+	require.Nil(t, err)
+	require.Nil(t, os.WriteFile(filepath.Join(dir, mirrorManifest+signatureSuffix), signManifest(key, content), 0666))
+}
@@ -0,0 +1,313 @@
+package megabrowser
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// Option configures a MegaBrowser created with New.
+type Option func(mb *MegaBrowser) error
+
+/*
+New creates a browser object for a Mega repository, configured with given options.
+
+Options not given keep their defaults: "/" path separator, a logger discarding every record, tracing with the global tracer provider, time.Now clock, concurrency of 1 and no filters. Client, filesystem and downloader have no defaults and have to be given for the browser to work.
+
+Returns an error if any of the options fails.
+*/
+func New(opts ...Option) (*MegaBrowser, error) {
+	browser := &MegaBrowser{
+		getRootNodeHash: getRootNodeHash,
+		getChildren:     getChildren,
+		targetSeparator: "/",
+		concurrency:     1,
+		logger:          discardLogger(),
+		tracer:          defaultTracer(),
+		now:             time.Now,
+	}
+	for _, opt := range opts {
+		err := opt(browser)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return browser, nil
+}
+
+// WithCredentials sets fixed credentials for the Mega repository. They are kept in memory for the whole browser lifetime, use WithCredentialProvider to avoid it.
+func WithCredentials(login string, pass string) Option {
+	return WithCredentialProvider(staticCredentials{Login: login, Password: pass})
+}
+
+// WithCredentialProvider sets provider of credentials for the Mega repository, consulted only when the browser logs in.
+func WithCredentialProvider(provider CredentialProvider) Option {
+	return func(mb *MegaBrowser) error {
+		if provider == nil {
+			return fmt.Errorf("credential provider must not be nil")
+		}
+		mb.credentials = provider
+		return nil
+	}
+}
+
+// WithMFACode sets a precomputed multi-factor authentication code, e.g. a TOTP code, used if the account requires one. The code is valid for a short time only, so the browser should be initialized right away.
+func WithMFACode(code string) Option {
+	return WithMFACodeFunc(func() (string, error) {
+		return code, nil
+	})
+}
+
+// WithMFACodeFunc sets function supplying a multi-factor authentication code, e.g. prompting the user for a TOTP code. It is called only when the account requires a code at login time, an empty code results in ErrMFARequired.
+func WithMFACodeFunc(fn func() (string, error)) Option {
+	return func(mb *MegaBrowser) error {
+		if fn == nil {
+			return fmt.Errorf("multi-factor code function must not be nil")
+		}
+		mb.mfaCode = fn
+		return nil
+	}
+}
+
+// WithSessionToken sets token of a previous session, which Initialize tries to resume instead of logging in with credentials. Used only if the client implements SessionClient.
+func WithSessionToken(token string) Option {
+	return func(mb *MegaBrowser) error {
+		mb.sessionToken = token
+		return nil
+	}
+}
+
+// WithRootNode sets name of the directory, containing the project.
+func WithRootNode(rootNodeName string) Option {
+	return func(mb *MegaBrowser) error {
+		mb.rootNodeName = rootNodeName
+		return nil
+	}
+}
+
+// WithClient sets client for the Mega repository, e.g. created with mega.New() function from t3rm1n4l/go-mega package.
+func WithClient(client StorageClient) Option {
+	return func(mb *MegaBrowser) error {
+		mb.megaClient = client
+		return nil
+	}
+}
+
+// WithFs sets system of Mega nodes, e.g. FS parameter of the client.
+func WithFs(fs Fs) Option {
+	return func(mb *MegaBrowser) error {
+		mb.megaFs = fs
+		return nil
+	}
+}
+
+// WithDownloader sets object responsible for downloading and updating project files, e.g. created with NewMegaDownloader().
+func WithDownloader(downloader Downloader) Option {
+	return func(mb *MegaBrowser) error {
+		mb.downloader = downloader
+		return nil
+	}
+}
+
+// WithSessionStore sets store, from which Initialize loads a session to resume and to which it saves a new session. Sessions older than maxAge are not resumed, zero maxAge means no limit. Used only if the client implements SessionClient.
+func WithSessionStore(store SessionStore, maxAge time.Duration) Option {
+	return func(mb *MegaBrowser) error {
+		if store == nil {
+			return fmt.Errorf("session store must not be nil")
+		}
+		if maxAge < 0 {
+			return fmt.Errorf("session max age must not be negative")
+		}
+		mb.sessionStore = store
+		mb.sessionMaxAge = maxAge
+		return nil
+	}
+}
+
+// WithPublicFolder makes the browser work on a public folder, or a public file link, see NewPublicLink, instead of an account, so that no client, filesystem or credentials are needed. The downloader has to implement PublicDownloader, like MegaDownloader does.
+func WithPublicFolder(folder *PublicFolder) Option {
+	return func(mb *MegaBrowser) error {
+		if folder == nil {
+			return fmt.Errorf("public folder must not be nil")
+		}
+		mb.publicFolder = folder
+		return WithBackend(folder)(mb)
+	}
+}
+
+// WithBackend makes the browser work on given backend instead of an account, e.g. a LocalBackend, so that no client, filesystem or credentials are needed.
+func WithBackend(backend Backend) Option {
+	return func(mb *MegaBrowser) error {
+		if backend == nil {
+			return fmt.Errorf("backend must not be nil")
+		}
+		mb.backend = backend
+		return nil
+	}
+}
+
+// WithAnonymousSession makes the browser read-only and never log in, even if credentials or a session are set. Operations needing an account return ErrAccountRequired. Combine it with WithPublicFolder to browse and download shared content.
+func WithAnonymousSession() Option {
+	return func(mb *MegaBrowser) error {
+		mb.anonymous = true
+		return nil
+	}
+}
+
+// WithReleases makes the browser work on the current release of the project, published with Publisher.PublishRelease, instead of the project root directory itself.
+func WithReleases() Option {
+	return func(mb *MegaBrowser) error {
+		mb.releases = true
+		return nil
+	}
+}
+
+// WithReleaseDir makes Initialize honor the release pinned in localDir by Rollback, instead of the current release, see Unpin. Synchronizing a directory honors its pinned release also without this option, so it is meant for browsing, e.g. listing the files of the release installed in localDir.
+func WithReleaseDir(localDir string) Option {
+	return func(mb *MegaBrowser) error {
+		mb.releaseDir = localDir
+		return nil
+	}
+}
+
+// WithManifest makes the browser read the manifest of given name, generated by Publisher, from the project root and verify every downloaded file against it. Downloading a file not listed in the manifest fails, see ErrChecksumMismatch.
+func WithManifest(name string) Option {
+	return func(mb *MegaBrowser) error {
+		if name == "" {
+			return fmt.Errorf("manifest name must not be empty")
+		}
+		mb.manifestFile = name
+		return nil
+	}
+}
+
+// WithManifestKey makes the browser verify the signature of the manifest with given public key, before it trusts the manifest, see WithManifest. The signature is read from the file named after the manifest with ".sig" appended, generated by Publisher created with WithSigningKey. Mirrors require a manifest key, because a mirror could otherwise serve any files together with a manifest matching them.
+func WithManifestKey(key ed25519.PublicKey) Option {
+	return func(mb *MegaBrowser) error {
+		if len(key) != ed25519.PublicKeySize {
+			return fmt.Errorf("manifest key must be an ed25519 public key")
+		}
+		mb.manifestKey = key
+		return nil
+	}
+}
+
+// WithMirrors sets mirrors of the project, tried in given order, if the browser fails to initialize with its primary source. Mirrors require a manifest and a manifest key, see WithManifest and WithManifestKey.
+func WithMirrors(mirrors ...Mirror) Option {
+	return func(mb *MegaBrowser) error {
+		for _, mirror := range mirrors {
+			if mirror == nil {
+				return fmt.Errorf("mirror must not be nil")
+			}
+		}
+		mb.mirrors = append(mb.mirrors, mirrors...)
+		return nil
+	}
+}
+
+// WithPeers makes the browser fetch files from peers in a local network before downloading them, and share synchronized files with the peers, see Peers. Peers require a manifest, see WithManifest, without it files are only downloaded.
+func WithPeers(peers *Peers) Option {
+	return func(mb *MegaBrowser) error {
+		if peers == nil {
+			return fmt.Errorf("peers must not be nil")
+		}
+		mb.peers = peers
+		return nil
+	}
+}
+
+// WithPathSeparator sets separator of the paths given to the browser methods.
+func WithPathSeparator(separator string) Option {
+	return func(mb *MegaBrowser) error {
+		if separator == "" {
+			return fmt.Errorf("path separator must not be empty")
+		}
+		mb.targetSeparator = separator
+		return nil
+	}
+}
+
+// discardLogger returns a logger discarding every record, used by default.
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// WithLogger sets logger used by the browser. A MegaDownloader has its own logger, see MegaDownloader.SetLogger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(mb *MegaBrowser) error {
+		if logger == nil {
+			return fmt.Errorf("logger must not be nil")
+		}
+		mb.logger = logger
+		return nil
+	}
+}
+
+// WithMetrics makes the browser count lookups, requests for children and synchronized files in given metrics. A MegaDownloader counts downloads in its own metrics, usually the same ones, see MegaDownloader.SetMetrics.
+func WithMetrics(metrics *Metrics) Option {
+	return func(mb *MegaBrowser) error {
+		if metrics == nil {
+			return fmt.Errorf("metrics must not be nil")
+		}
+		mb.metrics = metrics
+		return nil
+	}
+}
+
+// WithClock sets function returning the current time, used instead of time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(mb *MegaBrowser) error {
+		if now == nil {
+			return fmt.Errorf("clock must not be nil")
+		}
+		mb.now = now
+		return nil
+	}
+}
+
+// WithFilters sets filters of the files, which are synchronized by Plan and Sync.
+func WithFilters(filters FiltersConfig) Option {
+	return func(mb *MegaBrowser) error {
+		err := filters.validate()
+		if err != nil {
+			return err
+		}
+		mb.filters = filters
+		return nil
+	}
+}
+
+// WithConcurrency sets how many files Sync downloads at once.
+func WithConcurrency(concurrency int) Option {
+	return func(mb *MegaBrowser) error {
+		if concurrency < 1 {
+			return fmt.Errorf("concurrency must be at least 1, got %d", concurrency)
+		}
+		mb.concurrency = concurrency
+		return nil
+	}
+}
+
+// WithRootNodeHashFunc replaces the function, which finds the project root node among children of the Mega repository root.
+func WithRootNodeHashFunc(fn func(nodes []Node, rootNodeName string) (string, error)) Option {
+	return func(mb *MegaBrowser) error {
+		if fn == nil {
+			return fmt.Errorf("root node hash function must not be nil")
+		}
+		mb.getRootNodeHash = fn
+		return nil
+	}
+}
+
+// WithChildrenFunc replaces the function, which returns children of a node of given hash in the Mega account, see NewMegaBackend.
+func WithChildrenFunc(fn func(fs Fs, nodeHash string) ([]Node, error)) Option {
+	return func(mb *MegaBrowser) error {
+		if fn == nil {
+			return fmt.Errorf("children function must not be nil")
+		}
+		mb.getChildren = fn
+		return nil
+	}
+}
@@ -0,0 +1,164 @@
+package megabrowser
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWithDefaults(t *testing.T) {
+	browser, err := New()
+
+	require.Nil(t, err)
+	assert.Equal(t, "/", browser.targetSeparator)
+	assert.Equal(t, 1, browser.concurrency)
+	assert.NotNil(t, browser.logger)
+	assert.NotNil(t, browser.now)
+	assert.NotNil(t, browser.getRootNodeHash)
+	assert.NotNil(t, browser.getChildren)
+}
+
+func TestNewWithOptions(t *testing.T) {
+	client := &mockClient{}
+	fs := &mockFs{}
+	downloader := &mockDownloader{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clockTime := time.Date(2024, 1, 24, 0, 0, 0, 0, time.UTC)
+	filters := FiltersConfig{Include: []string{"*.dat"}}
+
+	browser, err := New(
+		WithCredentials(login, pass),
+		WithRootNode(rootNodeName),
+		WithClient(client),
+		WithFs(fs),
+		WithDownloader(downloader),
+		WithPathSeparator("\\"),
+		WithLogger(logger),
+		WithClock(func() time.Time { return clockTime }),
+		WithFilters(filters),
+		WithConcurrency(4),
+		WithRootNodeHashFunc(mockGetRootNodeHash),
+		WithChildrenFunc(mockGetChildren),
+	)
+
+	require.Nil(t, err)
+	credentials, err := browser.credentials.Credentials()
+	require.Nil(t, err)
+	assert.Equal(t, Credentials{Login: login, Password: pass}, credentials)
+	assert.Equal(t, rootNodeName, browser.rootNodeName)
+	assert.Same(t, client, browser.megaClient)
+	assert.Same(t, fs, browser.megaFs)
+	assert.Same(t, downloader, browser.downloader)
+	assert.Equal(t, "\\", browser.targetSeparator)
+	assert.Same(t, logger, browser.logger)
+	assert.Equal(t, clockTime, browser.now())
+	assert.Equal(t, filters, browser.filters)
+	assert.Equal(t, 4, browser.concurrency)
+
+	err = browser.Initialize()
+
+	require.Nil(t, err)
+	assert.Equal(t, expRootNodeHash, browser.rootNodeHash)
+}
+
+func TestNewFailsIfOptionIsInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		option Option
+		expErr string
+	}{
+		{
+			name:   "should fail, if credential provider is nil",
+			option: WithCredentialProvider(nil),
+			expErr: "credential provider must not be nil",
+		},
+		{
+			name:   "should fail, if multi-factor code function is nil",
+			option: WithMFACodeFunc(nil),
+			expErr: "multi-factor code function must not be nil",
+		},
+		{
+			name:   "should fail, if session store is nil",
+			option: WithSessionStore(nil, 0),
+			expErr: "session store must not be nil",
+		},
+		{
+			name:   "should fail, if session max age is negative",
+			option: WithSessionStore(&mockSessionStore{}, -time.Second),
+			expErr: "session max age must not be negative",
+		},
+		{
+			name:   "should fail, if path separator is empty",
+			option: WithPathSeparator(""),
+			expErr: "path separator must not be empty",
+		},
+		{
+			name:   "should fail, if logger is nil",
+			option: WithLogger(nil),
+			expErr: "logger must not be nil",
+		},
+		{
+			name:   "should fail, if metrics are nil",
+			option: WithMetrics(nil),
+			expErr: "metrics must not be nil",
+		},
+		{
+			name:   "should fail, if tracer provider is nil",
+			option: WithTracerProvider(nil),
+			expErr: "tracer provider must not be nil",
+		},
+		{
+			name:   "should fail, if clock is nil",
+			option: WithClock(nil),
+			expErr: "clock must not be nil",
+		},
+		{
+			name:   "should fail, if filter pattern is malformed",
+			option: WithFilters(FiltersConfig{Include: []string{"["}}),
+			expErr: "invalid filter pattern",
+		},
+		{
+			name:   "should fail, if concurrency is lower than 1",
+			option: WithConcurrency(0),
+			expErr: "concurrency must be at least 1",
+		},
+		{
+			name:   "should fail, if manifest name is empty",
+			option: WithManifest(""),
+			expErr: "manifest name must not be empty",
+		},
+		{
+			name:   "should fail, if mirror is nil",
+			option: WithMirrors(nil),
+			expErr: "mirror must not be nil",
+		},
+		{
+			name:   "should fail, if peers are nil",
+			option: WithPeers(nil),
+			expErr: "peers must not be nil",
+		},
+		{
+			name:   "should fail, if root node hash function is nil",
+			option: WithRootNodeHashFunc(nil),
+			expErr: "root node hash function must not be nil",
+		},
+		{
+			name:   "should fail, if children function is nil",
+			option: WithChildrenFunc(nil),
+			expErr: "children function must not be nil",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			browser, err := New(test.option)
+
+			assert.Nil(t, browser)
+			require.NotNil(t, err)
+			assert.Contains(t, err.Error(), test.expErr)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package megabrowser
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Messages exchanged by PeerDiscovery. An announcement is followed by the base URL of the announcing peer.
+const (
+	peerAnnouncement = "megabrowser-peer "
+	peerQuery        = "megabrowser-query"
+)
+
+// peerMessageSize is the maximum size of a received discovery message.
+const peerMessageSize = 1024
+
+/*
+PeerDiscovery finds peers in a local network with UDP multicast. Every client listens on a multicast group address and adds peers announcing themselves there to its Peers. A client serving files announces its URL every interval and whenever another client queries the group, which every client does once it starts, so that it does not have to wait for the next announcements.
+
+A unicast address can be given instead of a multicast one, e.g. to try discovery on a single machine, in which case messages are only received by the client listening on that address.
+
+Only announced http and https URLs of the host sending the announcement, which has to be a private, loopback or link-local address, are accepted, see validPeerURL.
+*/
+type PeerDiscovery struct {
+	peers    *Peers
+	url      string
+	group    *net.UDPAddr
+	listener *net.UDPConn
+	sender   *net.UDPConn
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+/*
+NewPeerDiscovery starts discovery of peers on given UDP address, e.g. "239.255.77.77:7077", adding the discovered peers to peers. If url is not empty, it is announced every interval as the base URL, at which the client serves files, see Peers.ServeHTTP. Discovery runs until Close is called.
+
+Discovered peers are kept for at least three intervals, so that a single lost announcement does not remove them.
+
+Returns an error if:
+
+	the address is malformed
+	url is set, while interval is not positive
+	failed to listen on the address or to open a connection for sending messages
+	failed to send the initial query
+*/
+func NewPeerDiscovery(peers *Peers, address string, url string, interval time.Duration) (*PeerDiscovery, error) {
+	if url != "" && interval <= 0 {
+		return nil, fmt.Errorf("peer announcement interval must be positive")
+	}
+	group, err := net.ResolveUDPAddr("udp4", address)
+	if err != nil {
+		return nil, err
+	}
+	var listener *net.UDPConn
+	if group.IP.IsMulticast() {
+		listener, err = net.ListenMulticastUDP("udp4", nil, group)
+	} else {
+		listener, err = net.ListenUDP("udp4", group)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sender, err := net.DialUDP("udp4", nil, group)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	peers.mutex.Lock()
+	peers.ttl = max(peers.ttl, 3*interval)
+	peers.mutex.Unlock()
+
+	pd := &PeerDiscovery{
+		peers:    peers,
+		url:      strings.TrimSuffix(url, "/"),
+		group:    group,
+		listener: listener,
+		sender:   sender,
+		done:     make(chan struct{}),
+	}
+	pd.wg.Add(1)
+	go pd.listen()
+	if pd.url != "" {
+		pd.wg.Add(1)
+		go pd.announceEvery(interval)
+	}
+
+	_, err = sender.Write([]byte(peerQuery))
+	if err != nil {
+		pd.Close()
+		return nil, fmt.Errorf("failed to query peers: %w", err)
+	}
+	return pd, nil
+}
+
+// Close stops the discovery and waits until it finishes. Discovered peers stay in Peers until they expire.
+func (pd *PeerDiscovery) Close() error {
+	close(pd.done)
+	err := pd.listener.Close()
+	pd.sender.Close()
+	pd.wg.Wait()
+	return err
+}
+
+// listen adds announced peers and answers queries, until the listener is closed.
+func (pd *PeerDiscovery) listen() {
+	defer pd.wg.Done()
+	buf := make([]byte, peerMessageSize)
+	for {
+		n, sender, err := pd.listener.ReadFromUDP(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			continue
+		}
+		message := string(buf[:n])
+		if peerURL, ok := strings.CutPrefix(message, peerAnnouncement); ok {
+			if peerURL != pd.url && validPeerURL(peerURL, sender.IP) {
+				pd.peers.AddPeer(peerURL)
+			}
+		} else if message == peerQuery && pd.url != "" {
+			pd.announce()
+		}
+	}
+}
+
+// announceEvery announces the client URL every interval, until the discovery is closed.
+func (pd *PeerDiscovery) announceEvery(interval time.Duration) {
+	defer pd.wg.Done()
+	pd.announce()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			pd.announce()
+		case <-pd.done:
+			return
+		}
+	}
+}
+
+// announce sends the client URL to the group. Failures are ignored, because the announcement is repeated.
+func (pd *PeerDiscovery) announce() {
+	_, _ = pd.sender.Write([]byte(peerAnnouncement + pd.url))
+}
+
+// validPeerURL returns true, if an announced URL is an http or https URL, whose host is the IP address of the sender of the announcement, and the address is in a local network. Announcements are not authenticated, so this keeps peers from pointing others at any other host.
+func validPeerURL(peerURL string, sender net.IP) bool {
+	parsed, err := url.Parse(peerURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.User != nil || parsed.RawQuery != "" || parsed.Fragment != "" {
+		return false
+	}
+	ip := net.ParseIP(parsed.Hostname())
+	if ip == nil || !ip.Equal(sender) {
+		return false
+	}
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast()
+}
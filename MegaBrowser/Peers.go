@@ -0,0 +1,248 @@
+package megabrowser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// peerFilesPath is the path under which a peer serves files, followed by their hex encoded SHA256 checksums.
+const peerFilesPath = "/sha256/"
+
+// defaultPeerTTL is the time a discovered peer is kept for, unless it announces itself again.
+const defaultPeerTTL = time.Minute
+
+var errNoPeerFile = errors.New("no peer has the file")
+
+/*
+Peers shares files of a project with other clients in a local network, so that a release is downloaded from Mega only once, see WithPeers. Files are addressed by SHA256 checksums listed in the manifest, so peers serve only files listed there, and every file fetched from a peer is verified against the manifest, before it is used. A file no peer has, or whose copies do not match, is downloaded from Mega.
+
+Peers is an http.Handler serving files synchronized by the browser. Clients find each other through a list of peer URLs given to NewPeers or with PeerDiscovery.
+*/
+type Peers struct {
+	client     *http.Client
+	static     []string
+	ttl        time.Duration
+	now        func() time.Time
+	mutex      sync.RWMutex
+	discovered map[string]time.Time
+	dir        string
+	files      map[string]ManifestFile
+}
+
+// NewPeers creates Peers fetching files from peers of given base URLs, e.g. http://192.168.1.10:7077, with given HTTP client, or a client with connection and response timeouts if it is nil, so that a stalled peer does not block a synchronization.
+func NewPeers(urls []string, client *http.Client) *Peers {
+	if client == nil {
+		client = newHTTPClient()
+	}
+	static := make([]string, 0, len(urls))
+	for _, url := range urls {
+		static = append(static, strings.TrimSuffix(url, "/"))
+	}
+	return &Peers{
+		client:     client,
+		static:     static,
+		ttl:        defaultPeerTTL,
+		now:        time.Now,
+		discovered: map[string]time.Time{},
+		files:      map[string]ManifestFile{},
+	}
+}
+
+// URLs returns base URLs of the given peers followed by the discovered ones, which did not expire, sorted.
+func (p *Peers) URLs() []string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	urls := append([]string{}, p.static...)
+	var discovered []string
+	now := p.now()
+	for url, expires := range p.discovered {
+		if now.Before(expires) && !slices.Contains(p.static, url) {
+			discovered = append(discovered, url)
+		}
+	}
+	sort.Strings(discovered)
+	return append(urls, discovered...)
+}
+
+// AddPeer adds a discovered peer of given base URL, which is kept until it does not announce itself again for the peer time to live.
+func (p *Peers) AddPeer(url string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.discovered[strings.TrimSuffix(url, "/")] = p.now().Add(p.ttl)
+}
+
+/*
+ServeHTTP serves a shared file of the checksum given in the request path, e.g. /sha256/<checksum>. Only files listed in the manifest of the last synchronization are served, and only if their local copies have the listed size.
+
+Responds with 404 Not Found, if the file is not shared or its local copy changed.
+*/
+func (p *Peers) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	checksum, ok := strings.CutPrefix(r.URL.Path, peerFilesPath)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	p.mutex.RLock()
+	file, ok := p.files[checksum]
+	dir := p.dir
+	p.mutex.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	local, err := os.Open(filepath.Join(dir, filepath.FromSlash(file.Path)))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer local.Close()
+	info, err := local.Stat()
+	if err != nil || !info.Mode().IsRegular() || info.Size() != file.Size {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, "", info.ModTime(), local)
+}
+
+// share makes files of given manifest, synchronized into dir, available to other peers, replacing the files shared before.
+func (p *Peers) share(dir string, manifest *manifestIndex) {
+	files := map[string]ManifestFile{}
+	for _, file := range manifest.files {
+		files[file.SHA256] = file
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.dir = dir
+	p.files = files
+}
+
+/*
+fetch downloads the file from the first peer having it to dstPath, see fetchFrom. Peers are tried in the order of URLs, and requests are cancelled with ctx.
+
+Returns an error joining errors of every peer, or errNoPeerFile, if there are no peers.
+*/
+func (p *Peers) fetch(ctx context.Context, file ManifestFile, dstPath string, confine func() error, verify func(path string) error) error {
+	urls := p.URLs()
+	if len(urls) == 0 {
+		return errNoPeerFile
+	}
+	errs := []error{errNoPeerFile}
+	for _, url := range urls {
+		err := p.fetchFrom(ctx, url, file, dstPath, confine, verify)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("peer %s: %w", url, err))
+	}
+	return errors.Join(errs...)
+}
+
+/*
+fetchFrom downloads the file from the peer of given base URL to a temporary file next to dstPath, checks it with verify and renames it to dstPath, if confine accepts dstPath right before, see confinePath. Reads at most one byte more than the size listed in the manifest and fails, if the size does not match.
+
+The file at dstPath is replaced, not written in place, because it may be a hard link to a cached file, see DownloadCache. The temporary file is removed, if the download fails.
+*/
+func (p *Peers) fetchFrom(ctx context.Context, url string, file ManifestFile, dstPath string, confine func() error, verify func(path string) error) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url+peerFilesPath+file.SHA256, nil)
+	if err != nil {
+		return err
+	}
+	body, err := openHTTP(p.client, request)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	err = confine()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dstPath), ".peer-*")
+	if err != nil {
+		return err
+	}
+	written, err := io.Copy(tmp, io.LimitReader(body, file.Size+1))
+	if err == nil && written != file.Size {
+		err = fmt.Errorf("peer sent %d bytes, expected %d", written, file.Size)
+	}
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = verify(tmp.Name())
+	}
+	if err == nil {
+		err = confine()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), dstPath)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+/*
+fetchFromPeers downloads a file of given path, relative to the project root, from peers to localPath, if the browser has peers and the file is listed in the manifest. The directory of the file is created, if it does not exist. Requests are cancelled with ctx, and localPath is confined like downloads made with ctx are, before the directory is created and again before the file is written and replaced, see MegaBrowser.confine.
+
+Returns false if the file could not be fetched from any peer, in which case it has to be downloaded from Mega.
+*/
+func (mb *MegaBrowser) fetchFromPeers(ctx context.Context, remotePath string, localPath string) bool {
+	if mb.peers == nil || mb.manifest == nil {
+		return false
+	}
+	file, ok := mb.manifest.files[remotePath]
+	if !ok {
+		return false
+	}
+	confine := func() error {
+		return mb.confine(ctx, localPath)
+	}
+	err := confine()
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(localPath), 0777)
+	}
+	if err == nil {
+		err = mb.peers.fetch(ctx, file, localPath, confine, func(path string) error {
+			return mb.verifyFile(ctx, remotePath, path)
+		})
+	}
+	if err != nil {
+		if err != errNoPeerFile {
+			mb.logger.Debug("file not fetched from peers", "path", remotePath, "error", err)
+		}
+		return false
+	}
+	mb.logger.Debug("file fetched from peers", "path", remotePath, "bytes", file.Size)
+	return true
+}
+
+// sharePeerFiles makes files synchronized into localDir available to other peers, if the browser has peers and a manifest.
+func (mb *MegaBrowser) sharePeerFiles(localDir string) {
+	if mb.peers == nil || mb.manifest == nil {
+		return
+	}
+	mb.peers.share(localDir, mb.manifest)
+}
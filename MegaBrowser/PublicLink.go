@@ -0,0 +1,647 @@
+package megabrowser
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/t3rm1n4l/go-mega"
+)
+
+// ErrInvalidLink is returned, if a public link could not be parsed.
+var ErrInvalidLink = errors.New("invalid mega public link")
+
+const (
+	// folderKeySize is the size of the key in a public folder link.
+	folderKeySize = 16
+	// fileKeySize is the size of the key in a public file link and of every file node key.
+	fileKeySize = 32
+	// publicFileRootHash is the hash of the directory node, which contains the file of a public file link, see NewPublicLink.
+	publicFileRootHash = "/"
+)
+
+var (
+	folderLinkPattern       = regexp.MustCompile(`^/folder/([0-9A-Za-z_-]{8})$`)
+	fileLinkPattern         = regexp.MustCompile(`^/file/([0-9A-Za-z_-]{8})$`)
+	legacyFolderLinkPattern = regexp.MustCompile(`^F!([0-9A-Za-z_-]{8})!([0-9A-Za-z_-]+)`)
+	legacyFileLinkPattern   = regexp.MustCompile(`^!([0-9A-Za-z_-]{8})!([0-9A-Za-z_-]+)`)
+)
+
+// PublicLinkOption configures access to a public link.
+type PublicLinkOption func(api *megaAPI)
+
+// WithPublicLinkHTTPClient sets the HTTP client used for the API requests and file transfers, instead of the default one, which times out connecting and waiting for responses.
+func WithPublicLinkHTTPClient(client *http.Client) PublicLinkOption {
+	return func(api *megaAPI) {
+		api.client = client
+	}
+}
+
+// WithPublicLinkAPIURL sets the address of the Mega API server.
+func WithPublicLinkAPIURL(apiURL string) PublicLinkOption {
+	return func(api *megaAPI) {
+		api.url = strings.TrimSuffix(apiURL, "/")
+	}
+}
+
+// PublicFolder is a folder shared with a public link, e.g. https://mega.nz/folder/ID#KEY, browsed without an account. A loaded folder is a Backend.
+type PublicFolder struct {
+	api      *megaAPI
+	handle   string
+	key      []byte
+	file     bool
+	root     *publicNode
+	nodes    map[string]*publicNode
+	children map[string][]Node
+}
+
+// PublicFile is a file of a public folder or a file shared with a public link, e.g. https://mega.nz/file/ID#KEY.
+type PublicFile struct {
+	*publicNode
+	api          *megaAPI
+	folderHandle string
+}
+
+// publicNode is a node decrypted with the key of a public link.
+type publicNode struct {
+	hash      string
+	parent    string
+	name      string
+	nodeType  int
+	size      int64
+	timestamp time.Time
+	// key decrypts the node attributes and, for files, the content.
+	key []byte
+	// nonce is the first half of the counter used to decrypt the file content.
+	nonce []byte
+	// mac is the condensed MAC of the file content.
+	mac []byte
+}
+
+type publicFilesMsg struct {
+	Cmd string `json:"a"`
+	C   int    `json:"c"`
+	CA  int    `json:"ca"`
+	R   int    `json:"r"`
+}
+
+type publicFileMsg struct {
+	Cmd string `json:"a"`
+	G   int    `json:"g,omitempty"`
+	P   string `json:"p,omitempty"`
+	N   string `json:"n,omitempty"`
+	SSL int    `json:"ssl,omitempty"`
+}
+
+// publicDownloadResp is a response to a download request of a public file. TimeLeft is the number of seconds until the transfer quota resets, if it is exceeded.
+type publicDownloadResp struct {
+	mega.DownloadResp
+	TimeLeft int64 `json:"tl"`
+}
+
+type nodeAttr struct {
+	Name string `json:"n"`
+}
+
+/*
+NewPublicFolder creates an object for a public folder link in https://mega.nz/folder/ID#KEY or legacy https://mega.nz/#F!ID!KEY format. The folder tree has to be fetched with Load before browsing it.
+
+Returns an error if the link is not a valid folder link, see ErrInvalidLink.
+*/
+func NewPublicFolder(link string, opts ...PublicLinkOption) (*PublicFolder, error) {
+	folder, err := NewPublicLink(link, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if folder.file {
+		return nil, fmt.Errorf("%w: not a folder link: %s", ErrInvalidLink, link)
+	}
+	return folder, nil
+}
+
+/*
+NewPublicLink creates an object for a public folder link like NewPublicFolder does, or for a public file link in https://mega.nz/file/ID#KEY or legacy https://mega.nz/#!ID!KEY format. A file link is browsed as a folder without a name, containing only the shared file, so that it can be synchronized like a folder. The folder has to be loaded with Load before browsing it, which for a file link fetches the file attributes, see OpenPublicFile.
+
+Returns an error if the link is neither a valid folder link nor a valid file link, see ErrInvalidLink.
+*/
+func NewPublicLink(link string, opts ...PublicLinkOption) (*PublicFolder, error) {
+	isFolder, handle, key, err := parsePublicLink(link)
+	if err != nil {
+		return nil, err
+	}
+	if isFolder && len(key) != folderKeySize || !isFolder && len(key) != fileKeySize {
+		return nil, fmt.Errorf("%w: malformed key: %s", ErrInvalidLink, link)
+	}
+
+	api := newMegaAPI()
+	for _, opt := range opts {
+		opt(api)
+	}
+	return &PublicFolder{
+		api:    api,
+		handle: handle,
+		key:    key,
+		file:   !isFolder,
+	}, nil
+}
+
+/*
+Load fetches the folder tree and decrypts it with the link key. Nodes, which could not be decrypted, are skipped.
+
+Returns an error if:
+
+	the API request failed, e.g. the folder does not exist anymore
+	the folder root node could not be decrypted
+*/
+func (pf *PublicFolder) Load() error {
+	return pf.LoadContext(context.Background())
+}
+
+// LoadContext fetches the folder tree like Load does, cancelling the request with ctx.
+func (pf *PublicFolder) LoadContext(ctx context.Context) error {
+	if pf.file {
+		return pf.loadFile(ctx)
+	}
+	var res mega.FilesResp
+	err := pf.api.call(ctx, url.Values{"n": {pf.handle}}, publicFilesMsg{Cmd: "f", C: 1, CA: 1, R: 1}, &res)
+	if err != nil {
+		return err
+	}
+
+	nodes := map[string]*publicNode{}
+	for _, item := range res.F {
+		node, err := decryptPublicNode(item, pf.key)
+		if err != nil {
+			continue
+		}
+		nodes[node.hash] = node
+	}
+
+	root, ok := nodes[pf.handle]
+	if !ok {
+		return fmt.Errorf("could not decrypt root node of public folder %s", pf.handle)
+	}
+
+	children := map[string][]Node{}
+	for _, node := range nodes {
+		if node != root {
+			children[node.parent] = append(children[node.parent], node)
+		}
+	}
+	pf.root = root
+	pf.nodes = nodes
+	pf.children = children
+	return nil
+}
+
+// loadFile fetches attributes of the file of a public file link and makes it the only child of the folder root.
+func (pf *PublicFolder) loadFile(ctx context.Context) error {
+	file, err := fetchPublicFile(ctx, pf.api, pf.handle, pf.key)
+	if err != nil {
+		return err
+	}
+	root := &publicNode{hash: publicFileRootHash, nodeType: directoryType}
+	file.parent = root.hash
+	pf.root = root
+	pf.nodes = map[string]*publicNode{root.hash: root, file.hash: file}
+	pf.children = map[string][]Node{root.hash: {file}}
+	return nil
+}
+
+// Root returns the node of the shared folder itself. Returns nil, if the folder is not loaded.
+func (pf *PublicFolder) Root() Node {
+	if pf.root == nil {
+		return nil
+	}
+	return pf.root
+}
+
+/*
+Children returns child nodes of a directory of given hash.
+
+Returns an error if the folder is not loaded or it does not contain a directory of that hash.
+*/
+func (pf *PublicFolder) Children(hash string) ([]Node, error) {
+	node, ok := pf.nodes[hash]
+	if !ok || node.nodeType != directoryType {
+		return nil, fmt.Errorf("could not find directory node: %s", hash)
+	}
+	return pf.children[hash], nil
+}
+
+/*
+File returns a file of given hash, which can be downloaded.
+
+Returns an error if the folder is not loaded or it does not contain a file of that hash.
+*/
+func (pf *PublicFolder) File(hash string) (*PublicFile, error) {
+	node, ok := pf.nodes[hash]
+	if !ok || node.nodeType != fileType {
+		return nil, fmt.Errorf("could not find file node: %s", hash)
+	}
+	if pf.file {
+		return &PublicFile{publicNode: node, api: pf.api}, nil
+	}
+	return &PublicFile{
+		publicNode:   node,
+		api:          pf.api,
+		folderHandle: pf.handle,
+	}, nil
+}
+
+/*
+Stat returns a node of given hash, so that the folder can be used as a Backend.
+
+Returns an error if the folder is not loaded or it does not contain a node of that hash.
+*/
+func (pf *PublicFolder) Stat(hash string) (Node, error) {
+	node, ok := pf.nodes[hash]
+	if !ok {
+		return nil, fmt.Errorf("could not find node: %s", hash)
+	}
+	return node, nil
+}
+
+// Download downloads a file of given hash to dstPath, see File and PublicFile.Download.
+func (pf *PublicFolder) Download(hash string, dstPath string, progress *chan int) error {
+	file, err := pf.File(hash)
+	if err != nil {
+		if progress != nil {
+			close(*progress)
+		}
+		return err
+	}
+	return file.Download(dstPath, progress)
+}
+
+/*
+OpenPublicFile fetches attributes of a file shared with a public link in https://mega.nz/file/ID#KEY or legacy https://mega.nz/#!ID!KEY format.
+
+Returns an error if:
+
+	the link is not a valid file link, see ErrInvalidLink
+	the API request failed, e.g. the file does not exist anymore
+	the file attributes could not be decrypted with the link key
+*/
+func OpenPublicFile(link string, opts ...PublicLinkOption) (*PublicFile, error) {
+	return OpenPublicFileContext(context.Background(), link, opts...)
+}
+
+// OpenPublicFileContext fetches attributes of a file shared with a public link like OpenPublicFile does, cancelling the request with ctx.
+func OpenPublicFileContext(ctx context.Context, link string, opts ...PublicLinkOption) (*PublicFile, error) {
+	isFolder, handle, key, err := parsePublicLink(link)
+	if err != nil {
+		return nil, err
+	}
+	if isFolder || len(key) != fileKeySize {
+		return nil, fmt.Errorf("%w: not a file link: %s", ErrInvalidLink, link)
+	}
+
+	api := newMegaAPI()
+	for _, opt := range opts {
+		opt(api)
+	}
+	node, err := fetchPublicFile(ctx, api, handle, key)
+	if err != nil {
+		return nil, err
+	}
+	return &PublicFile{
+		publicNode: node,
+		api:        api,
+	}, nil
+}
+
+// fetchPublicFile fetches attributes of the file of given handle, shared with a public link, and decrypts them with given link key.
+func fetchPublicFile(ctx context.Context, api *megaAPI, handle string, key []byte) (*publicNode, error) {
+	var res mega.DownloadResp
+	err := api.call(ctx, nil, publicFileMsg{Cmd: "g", P: handle}, &res)
+	if err != nil {
+		return nil, err
+	}
+	if res.Err != 0 {
+		return nil, megaError(int(res.Err))
+	}
+
+	node := newPublicFileNode(handle, key)
+	node.size = int64(res.Size)
+	attr, err := decryptNodeAttr(node.key, res.Attr)
+	if err != nil {
+		return nil, err
+	}
+	node.name = attr.Name
+	return node, nil
+}
+
+/*
+Download downloads the file to dstPath, decrypting it and verifying its MAC. If progress is not nil, the number of bytes written is sent to it after every chunk, and it is closed when the download finishes.
+
+Returns an error if:
+
+	failed to get the download URL or to fetch the content
+	failed to create or write the destination file
+	the content does not match the file MAC, see mega.EMACMISMATCH
+*/
+func (file *PublicFile) Download(dstPath string, progress *chan int) error {
+	return file.DownloadContext(context.Background(), dstPath, progress)
+}
+
+// DownloadContext downloads the file like Download does, cancelling the requests and the transfer with ctx.
+func (file *PublicFile) DownloadContext(ctx context.Context, dstPath string, progress *chan int) error {
+	if progress != nil {
+		defer close(*progress)
+	}
+
+	msg := publicFileMsg{Cmd: "g", G: 1, SSL: 2}
+	var query url.Values
+	if file.folderHandle != "" {
+		msg.N = file.hash
+		query = url.Values{"n": {file.folderHandle}}
+	} else {
+		msg.P = file.hash
+	}
+	var res publicDownloadResp
+	err := file.api.call(ctx, query, msg, &res)
+	if err != nil {
+		return err
+	}
+	if res.Err != 0 {
+		err = megaError(int(res.Err))
+		if err == mega.EOVERQUOTA {
+			return &QuotaError{Wait: time.Duration(res.TimeLeft) * time.Second, Err: err}
+		}
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, res.G, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := file.api.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("download of %s responded with %s", file.name, resp.Status)
+		if resp.StatusCode == megaOverQuotaStatusCode {
+			return &QuotaError{Wait: quotaWait(resp), Err: err}
+		}
+		return err
+	}
+
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return err
+	}
+	err = file.decryptContent(resp.Body, dst, progress)
+	closeErr := dst.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(dstPath)
+		return err
+	}
+	return nil
+}
+
+// decryptContent decrypts the file content from src chunk by chunk, writing it to dst and computing the chunk MACs, and verifies the condensed MAC at the end.
+func (file *PublicFile) decryptContent(src io.Reader, dst io.Writer, progress *chan int) error {
+	block, err := aes.NewCipher(file.key)
+	if err != nil {
+		return err
+	}
+	ctr := cipher.NewCTR(block, append(append([]byte{}, file.nonce...), make([]byte, 8)...))
+	chunkIV := append(append([]byte{}, file.nonce...), file.nonce...)
+	fileMAC := cipher.NewCBCEncrypter(block, make([]byte, aes.BlockSize))
+	macData := make([]byte, aes.BlockSize)
+
+	for _, chunkSize := range chunkSizes(file.size) {
+		chunk := make([]byte, chunkSize)
+		_, err = io.ReadFull(src, chunk)
+		if err != nil {
+			return err
+		}
+		ctr.XORKeyStream(chunk, chunk)
+
+		chunkMAC := make([]byte, aes.BlockSize)
+		mac := cipher.NewCBCEncrypter(block, chunkIV)
+		padded := padNull(chunk, aes.BlockSize)
+		for i := 0; i < len(padded); i += aes.BlockSize {
+			mac.CryptBlocks(chunkMAC, padded[i:i+aes.BlockSize])
+		}
+		fileMAC.CryptBlocks(macData, chunkMAC)
+
+		_, err = dst.Write(chunk)
+		if err != nil {
+			return err
+		}
+		if progress != nil {
+			*progress <- len(chunk)
+		}
+	}
+
+	if file.size == 0 {
+		return nil
+	}
+	condensed := make([]byte, 8)
+	for i := 0; i < 4; i++ {
+		condensed[i] = macData[i] ^ macData[i+4]
+		condensed[i+4] = macData[i+8] ^ macData[i+12]
+	}
+	if !bytes.Equal(condensed, file.mac) {
+		return mega.EMACMISMATCH
+	}
+	return nil
+}
+
+func (pn *publicNode) GetName() string {
+	return pn.name
+}
+
+func (pn *publicNode) GetType() int {
+	return pn.nodeType
+}
+
+func (pn *publicNode) GetHash() string {
+	return pn.hash
+}
+
+func (pn *publicNode) GetSize() int64 {
+	return pn.size
+}
+
+func (pn *publicNode) GetTimeStamp() time.Time {
+	return pn.timestamp
+}
+
+func (pn *publicNode) GetParentHash() string {
+	return pn.parent
+}
+
+// GetPath returns an empty path, the browser wraps nodes it resolves with their paths, see pathNode.
+func (pn *publicNode) GetPath() string {
+	return ""
+}
+
+// parsePublicLink extracts the handle and key from a public link, reporting whether it is a folder or a file link.
+func parsePublicLink(link string) (bool, string, []byte, error) {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return false, "", nil, fmt.Errorf("%w: %s", ErrInvalidLink, link)
+	}
+
+	var isFolder bool
+	var handle, key string
+	if match := folderLinkPattern.FindStringSubmatch(parsed.Path); match != nil {
+		isFolder, handle, key = true, match[1], strings.SplitN(parsed.Fragment, "/", 2)[0]
+	} else if match := fileLinkPattern.FindStringSubmatch(parsed.Path); match != nil {
+		isFolder, handle, key = false, match[1], parsed.Fragment
+	} else if match := legacyFolderLinkPattern.FindStringSubmatch(parsed.Fragment); match != nil {
+		isFolder, handle, key = true, match[1], match[2]
+	} else if match := legacyFileLinkPattern.FindStringSubmatch(parsed.Fragment); match != nil {
+		isFolder, handle, key = false, match[1], match[2]
+	} else {
+		return false, "", nil, fmt.Errorf("%w: %s", ErrInvalidLink, link)
+	}
+
+	decodedKey, err := base64.RawURLEncoding.DecodeString(key)
+	if err != nil {
+		return false, "", nil, fmt.Errorf("%w: malformed key: %s", ErrInvalidLink, link)
+	}
+	return isFolder, handle, decodedKey, nil
+}
+
+// decryptPublicNode decrypts key and attributes of a node listed in a public folder, whose key is encrypted with the folder key.
+func decryptPublicNode(item mega.FSNode, folderKey []byte) (*publicNode, error) {
+	encryptedKey, err := nodeKeyFromList(item.Key)
+	if err != nil {
+		return nil, err
+	}
+	key, err := decryptECB(folderKey, encryptedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var node *publicNode
+	switch {
+	case item.T == fileType && len(key) == fileKeySize:
+		node = newPublicFileNode(item.Hash, key)
+	case item.T == directoryType && len(key) == folderKeySize:
+		node = &publicNode{hash: item.Hash, key: key}
+	default:
+		return nil, mega.EKEY
+	}
+	node.parent = item.Parent
+	node.nodeType = item.T
+	node.size = item.Sz
+	node.timestamp = time.Unix(item.Ts, 0)
+
+	attr, err := decryptNodeAttr(node.key, item.Attr)
+	if err != nil {
+		return nil, err
+	}
+	node.name = attr.Name
+	return node, nil
+}
+
+// newPublicFileNode creates a file node from its full 32 byte key, which consists of the AES key XORed with the nonce and the MAC.
+func newPublicFileNode(hash string, fullKey []byte) *publicNode {
+	key := make([]byte, 16)
+	for i := range key {
+		key[i] = fullKey[i] ^ fullKey[i+16]
+	}
+	return &publicNode{
+		hash:     hash,
+		nodeType: fileType,
+		key:      key,
+		nonce:    append([]byte{}, fullKey[16:24]...),
+		mac:      append([]byte{}, fullKey[24:32]...),
+	}
+}
+
+// nodeKeyFromList returns the first encrypted key of a node key list in "handle:key/handle:key" format.
+func nodeKeyFromList(keys string) ([]byte, error) {
+	first := strings.SplitN(keys, "/", 2)[0]
+	parts := strings.SplitN(first, ":", 2)
+	if len(parts) != 2 {
+		return nil, mega.EKEY
+	}
+	return base64.RawURLEncoding.DecodeString(parts[1])
+}
+
+// decryptNodeAttr decrypts node attributes, which are JSON prefixed with "MEGA", encrypted with AES-CBC and a zero IV.
+func decryptNodeAttr(key []byte, data string) (nodeAttr, error) {
+	var attr nodeAttr
+	encrypted, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil || len(encrypted)%aes.BlockSize != 0 {
+		return attr, mega.EBADATTR
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return attr, err
+	}
+
+	decrypted := make([]byte, len(encrypted))
+	cipher.NewCBCDecrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(decrypted, encrypted)
+	if !bytes.HasPrefix(decrypted, []byte("MEGA")) {
+		return attr, mega.EBADATTR
+	}
+	err = json.Unmarshal(bytes.TrimRight(decrypted[4:], "\x00"), &attr)
+	if err != nil {
+		return attr, mega.EBADATTR
+	}
+	return attr, nil
+}
+
+// decryptECB decrypts data block by block with AES in ECB mode, which Mega uses for node keys.
+func decryptECB(key []byte, data []byte) ([]byte, error) {
+	if len(data)%aes.BlockSize != 0 {
+		return nil, mega.EKEY
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]byte, len(data))
+	for i := 0; i < len(data); i += aes.BlockSize {
+		block.Decrypt(result[i:i+aes.BlockSize], data[i:i+aes.BlockSize])
+	}
+	return result, nil
+}
+
+// chunkSizes returns sizes of the chunks, for which Mega computes MACs: 128 KiB, 256 KiB and so on up to 1 MiB, then 1 MiB each.
+func chunkSizes(size int64) []int {
+	var sizes []int
+	for i := 1; size > 0; i++ {
+		chunk := int64(1048576)
+		if i <= 8 {
+			chunk = int64(i) * 131072
+		}
+		if size < chunk {
+			chunk = size
+		}
+		sizes = append(sizes, int(chunk))
+		size -= chunk
+	}
+	return sizes
+}
+
+func padNull(data []byte, blockSize int) []byte {
+	if len(data)%blockSize == 0 {
+		return data
+	}
+	return append(append([]byte{}, data...), make([]byte, blockSize-len(data)%blockSize)...)
+}
@@ -0,0 +1,489 @@
+package megabrowser
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/t3rm1n4l/go-mega"
+)
+
+const (
+	publicFolderHandle = "fOLDER01"
+	publicDirHandle    = "dIRECT01"
+	publicFileHandle   = "fILE0001"
+	publicFolderName   = "shared"
+	publicFileContent  = "content of the shared file, long enough to span two AES blocks"
+)
+
+var publicFolderKey = []byte("0123456789abcdef")
+
+// fakeMegaServer emulates the Mega API for a single public folder and a single public file.
+type fakeMegaServer struct {
+	*httptest.Server
+	nodes      []mega.FSNode
+	fileKey    []byte
+	fileAttr   string
+	content    []byte
+	apiErrno   int
+	tamperData bool
+	// apiQuotaLeft and dataQuotaLeft make the download request or the data transfer fail over quota, which resets in given number of seconds.
+	apiQuotaLeft  int
+	dataQuotaLeft int
+}
+
+func TestParsePublicLink(t *testing.T) {
+	tests := []struct {
+		name        string
+		link        string
+		expIsFolder bool
+		expHandle   string
+		expErr      bool
+	}{
+		{
+			name:        "should parse folder link",
+			link:        "https://mega.nz/folder/" + publicFolderHandle + "#" + encodeKey(publicFolderKey),
+			expIsFolder: true,
+			expHandle:   publicFolderHandle,
+		},
+		{
+			name:        "should parse folder link pointing to a subfolder",
+			link:        "https://mega.nz/folder/" + publicFolderHandle + "#" + encodeKey(publicFolderKey) + "/folder/" + publicDirHandle,
+			expIsFolder: true,
+			expHandle:   publicFolderHandle,
+		},
+		{
+			name:        "should parse legacy folder link",
+			link:        "https://mega.nz/#F!" + publicFolderHandle + "!" + encodeKey(publicFolderKey),
+			expIsFolder: true,
+			expHandle:   publicFolderHandle,
+		},
+		{
+			name:        "should parse file link",
+			link:        "https://mega.nz/file/" + publicFileHandle + "#" + encodeKey(publicFolderKey),
+			expIsFolder: false,
+			expHandle:   publicFileHandle,
+		},
+		{
+			name:        "should parse legacy file link",
+			link:        "https://mega.co.nz/#!" + publicFileHandle + "!" + encodeKey(publicFolderKey),
+			expIsFolder: false,
+			expHandle:   publicFileHandle,
+		},
+		{
+			name:   "should fail, if link has no handle",
+			link:   "https://mega.nz/folder/#" + encodeKey(publicFolderKey),
+			expErr: true,
+		},
+		{
+			name:   "should fail, if key is malformed",
+			link:   "https://mega.nz/folder/" + publicFolderHandle + "#not*base64",
+			expErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			isFolder, handle, key, err := parsePublicLink(test.link)
+
+			if test.expErr {
+				assert.ErrorIs(t, err, ErrInvalidLink)
+				return
+			}
+			require.Nil(t, err)
+			assert.Equal(t, test.expIsFolder, isFolder)
+			assert.Equal(t, test.expHandle, handle)
+			assert.Equal(t, publicFolderKey, key)
+		})
+	}
+}
+
+func TestNewPublicFolderFailsForFileLink(t *testing.T) {
+	folder, err := NewPublicFolder("https://mega.nz/file/" + publicFileHandle + "#" + encodeKey(make([]byte, fileKeySize)))
+
+	assert.Nil(t, folder)
+	assert.ErrorIs(t, err, ErrInvalidLink)
+}
+
+func TestPublicFolderLoadAndDownload(t *testing.T) {
+	server := newFakeMegaServer(t)
+	folder := newTestPublicFolder(t, server)
+
+	err := folder.Load()
+
+	require.Nil(t, err)
+	assert.Equal(t, publicFolderName, folder.Root().GetName())
+	rootChildren, err := folder.Children(publicFolderHandle)
+	require.Nil(t, err)
+	require.Len(t, rootChildren, 1)
+	assert.Equal(t, expDirName, rootChildren[0].GetName())
+	assert.Equal(t, directoryType, rootChildren[0].GetType())
+	dirChildren, err := folder.Children(publicDirHandle)
+	require.Nil(t, err)
+	require.Len(t, dirChildren, 1)
+	assert.Equal(t, expFileName, dirChildren[0].GetName())
+	assert.Equal(t, int64(len(publicFileContent)), dirChildren[0].GetSize())
+
+	file, err := folder.File(publicFileHandle)
+	require.Nil(t, err)
+	dstPath := filepath.Join(t.TempDir(), "file.txt")
+	progress := make(chan int, 10)
+	err = file.Download(dstPath, &progress)
+
+	require.Nil(t, err)
+	assertFileContent(t, dstPath, publicFileContent)
+	downloaded := 0
+	for bytes := range progress {
+		downloaded += bytes
+	}
+	assert.Equal(t, len(publicFileContent), downloaded)
+}
+
+func TestPublicFolderLookupFailCase(t *testing.T) {
+	server := newFakeMegaServer(t)
+	folder := newTestPublicFolder(t, server)
+	require.Nil(t, folder.Load())
+
+	_, err := folder.Children(publicFileHandle)
+	assert.NotNil(t, err)
+
+	_, err = folder.File(publicDirHandle)
+	assert.NotNil(t, err)
+}
+
+func TestPublicFolderLoadFailsOnAPIError(t *testing.T) {
+	server := newFakeMegaServer(t)
+	server.apiErrno = -9
+	folder := newTestPublicFolder(t, server)
+
+	err := folder.Load()
+
+	assert.Equal(t, mega.ENOENT, err)
+}
+
+func TestPublicFileDownloadFailsOnMACMismatch(t *testing.T) {
+	server := newFakeMegaServer(t)
+	server.tamperData = true
+	folder := newTestPublicFolder(t, server)
+	require.Nil(t, folder.Load())
+	file, err := folder.File(publicFileHandle)
+	require.Nil(t, err)
+	dstPath := filepath.Join(t.TempDir(), "file.txt")
+
+	err = file.Download(dstPath, nil)
+
+	assert.Equal(t, mega.EMACMISMATCH, err)
+	assert.NoFileExists(t, dstPath)
+}
+
+func TestPublicLinkRequestsFailIfContextIsCancelled(t *testing.T) {
+	server := newFakeMegaServer(t)
+	folder := newTestPublicFolder(t, server)
+	require.Nil(t, folder.Load())
+	file, err := folder.File(publicFileHandle)
+	require.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dstPath := filepath.Join(t.TempDir(), "file.txt")
+
+	loadErr := folder.LoadContext(ctx)
+	downloadErr := file.DownloadContext(ctx, dstPath, nil)
+	_, openErr := OpenPublicFileContext(ctx, "https://mega.nz/file/"+publicFileHandle+"#"+encodeKey(server.fileKey), WithPublicLinkAPIURL(server.URL), WithPublicLinkHTTPClient(server.Client()))
+
+	assert.ErrorIs(t, loadErr, context.Canceled)
+	assert.ErrorIs(t, downloadErr, context.Canceled)
+	assert.ErrorIs(t, openErr, context.Canceled)
+	assert.NoFileExists(t, dstPath)
+}
+
+func TestNewMegaAPIClientTimesOut(t *testing.T) {
+	api := newMegaAPI()
+
+	require.NotEqual(t, http.DefaultClient, api.client)
+	transport, ok := api.client.Transport.(*http.Transport)
+	require.True(t, ok)
+	assert.Equal(t, tlsHandshakeTimeout, transport.TLSHandshakeTimeout)
+	assert.Equal(t, responseHeaderTimeout, transport.ResponseHeaderTimeout)
+	assert.NotNil(t, transport.DialContext)
+}
+
+func TestOpenPublicFile(t *testing.T) {
+	server := newFakeMegaServer(t)
+	link := "https://mega.nz/file/" + publicFileHandle + "#" + encodeKey(server.fileKey)
+
+	file, err := OpenPublicFile(link, WithPublicLinkAPIURL(server.URL), WithPublicLinkHTTPClient(server.Client()))
+
+	require.Nil(t, err)
+	assert.Equal(t, expFileName, file.GetName())
+	assert.Equal(t, int64(len(publicFileContent)), file.GetSize())
+	dstPath := filepath.Join(t.TempDir(), "file.txt")
+	err = NewMegaDownloader().DownloadPublicFile(file, dstPath)
+	require.Nil(t, err)
+	assertFileContent(t, dstPath, publicFileContent)
+}
+
+func TestNewPublicLinkFailsForMalformedKey(t *testing.T) {
+	folder, err := NewPublicLink("https://mega.nz/file/" + publicFileHandle + "#" + encodeKey(publicFolderKey))
+
+	assert.Nil(t, folder)
+	assert.ErrorIs(t, err, ErrInvalidLink)
+}
+
+func TestPublicFileLinkLoadAndDownload(t *testing.T) {
+	server := newFakeMegaServer(t)
+	folder := newTestPublicFileLink(t, server)
+
+	err := folder.Load()
+
+	require.Nil(t, err)
+	assert.Equal(t, "", folder.Root().GetName())
+	assert.Equal(t, directoryType, folder.Root().GetType())
+	children, err := folder.Children(folder.Root().GetHash())
+	require.Nil(t, err)
+	require.Len(t, children, 1)
+	assert.Equal(t, expFileName, children[0].GetName())
+	assert.Equal(t, int64(len(publicFileContent)), children[0].GetSize())
+	dstPath := filepath.Join(t.TempDir(), "file.txt")
+	err = folder.Download(publicFileHandle, dstPath, nil)
+	require.Nil(t, err)
+	assertFileContent(t, dstPath, publicFileContent)
+}
+
+func TestSyncWithPublicFileLink(t *testing.T) {
+	server := newFakeMegaServer(t)
+	storageBrowser, err := New(
+		WithPublicFolder(newTestPublicFileLink(t, server)),
+		WithDownloader(NewMegaDownloader()),
+	)
+	require.Nil(t, err)
+	require.Nil(t, storageBrowser.Initialize())
+	localDir := t.TempDir()
+
+	entries, err := storageBrowser.Sync(localDir)
+
+	require.Nil(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, expFileName, entries[0].Path)
+	assertFileContent(t, filepath.Join(localDir, expFileName), publicFileContent)
+}
+
+func TestInitializeWithPublicFolder(t *testing.T) {
+	tests := []struct {
+		name         string
+		rootNodeName string
+		givenPath    string
+		expErr       error
+	}{
+		{
+			name:         "should use shared folder as project root, if root node name is empty",
+			rootNodeName: "",
+			givenPath:    expDirName + "/" + expFileName,
+			expErr:       nil,
+		},
+		{
+			name:         "should use shared folder as project root, if root node name matches its name",
+			rootNodeName: publicFolderName,
+			givenPath:    expDirName + "/" + expFileName,
+			expErr:       nil,
+		},
+		{
+			name:         "should use a directory of the shared folder as project root, if root node name matches it",
+			rootNodeName: expDirName,
+			givenPath:    expFileName,
+			expErr:       nil,
+		},
+		{
+			name:         "should fail, if could not find the project root node",
+			rootNodeName: "unexpectedDir",
+			expErr:       ErrRootNodeNotFound,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newFakeMegaServer(t)
+			storageBrowser, err := New(
+				WithPublicFolder(newTestPublicFolder(t, server)),
+				WithRootNode(test.rootNodeName),
+				WithDownloader(NewMegaDownloader()),
+			)
+			require.Nil(t, err)
+
+			err = storageBrowser.Initialize()
+
+			require.Equal(t, test.expErr, err)
+			if err != nil {
+				return
+			}
+			dstPath := filepath.Join(t.TempDir(), "file.txt")
+			err = storageBrowser.UpdateFileFromPath(test.givenPath, dstPath)
+			require.Nil(t, err)
+			assertFileContent(t, dstPath, publicFileContent)
+		})
+	}
+}
+
+func TestPublicFolderRequiresPublicDownloader(t *testing.T) {
+	server := newFakeMegaServer(t)
+	storageBrowser, err := New(
+		WithPublicFolder(newTestPublicFolder(t, server)),
+		WithDownloader(&mockDownloader{}),
+	)
+	require.Nil(t, err)
+	require.Nil(t, storageBrowser.Initialize())
+
+	err = storageBrowser.UpdateFileFromPath(expDirName+"/"+expFileName, "file.txt")
+
+	assert.Equal(t, errNoPublicDownloader, err)
+}
+
+func newFakeMegaServer(t *testing.T) *fakeMegaServer {
+	fileKey := []byte("fedcba9876543210")
+	nonce := []byte("noncenon")
+	content := encryptCTR(t, fileKey, nonce, []byte(publicFileContent))
+	fullKey := make([]byte, fileKeySize)
+	copy(fullKey[16:], nonce)
+	copy(fullKey[24:], condensedMAC(t, fileKey, nonce, []byte(publicFileContent)))
+	for i := 0; i < 16; i++ {
+		fullKey[i] = fileKey[i] ^ fullKey[i+16]
+	}
+	dirKey := []byte("directorykey0001")
+	rootKey := []byte("rootdirectorykey")
+
+	server := &fakeMegaServer{
+		nodes: []mega.FSNode{
+			{Hash: publicFolderHandle, T: directoryType, Key: "owner:" + encryptECB(t, publicFolderKey, rootKey), Attr: encryptAttr(t, rootKey, publicFolderName)},
+			{Hash: publicDirHandle, Parent: publicFolderHandle, T: directoryType, Key: "owner:" + encryptECB(t, publicFolderKey, dirKey), Attr: encryptAttr(t, dirKey, expDirName)},
+			{Hash: publicFileHandle, Parent: publicDirHandle, T: fileType, Key: "owner:" + encryptECB(t, publicFolderKey, fullKey), Attr: encryptAttr(t, fileKey, expFileName), Sz: int64(len(publicFileContent))},
+			{Hash: "uNREADAB", Parent: publicDirHandle, T: fileType, Key: "owner:broken", Attr: "broken"},
+		},
+		fileKey:  fullKey,
+		fileAttr: encryptAttr(t, fileKey, expFileName),
+		content:  content,
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.handle))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (s *fakeMegaServer) handle(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/dl/") {
+		if s.dataQuotaLeft > 0 {
+			w.Header().Set(quotaTimeLeftHeader, strconv.Itoa(s.dataQuotaLeft))
+			w.WriteHeader(megaOverQuotaStatusCode)
+			return
+		}
+		content := append([]byte{}, s.content...)
+		if s.tamperData {
+			content[0] ^= 0xff
+		}
+		_, _ = w.Write(content)
+		return
+	}
+
+	if s.apiErrno != 0 {
+		_ = json.NewEncoder(w).Encode(s.apiErrno)
+		return
+	}
+	var msgs []publicFileMsg
+	_ = json.NewDecoder(r.Body).Decode(&msgs)
+	msg := msgs[0]
+	var res interface{}
+	switch {
+	case msg.Cmd == "f" && r.URL.Query().Get("n") == publicFolderHandle:
+		res = mega.FilesResp{F: s.nodes}
+	case msg.Cmd == "g" && msg.G == 1 && s.apiQuotaLeft > 0:
+		res = map[string]interface{}{"e": -17, "tl": s.apiQuotaLeft}
+	case msg.Cmd == "g" && msg.G == 1:
+		res = map[string]interface{}{"g": s.URL + "/dl/" + msg.N + msg.P, "s": len(publicFileContent)}
+	case msg.Cmd == "g" && msg.P == publicFileHandle:
+		res = map[string]interface{}{"s": len(publicFileContent), "at": s.fileAttr}
+	default:
+		res = -9
+	}
+	_ = json.NewEncoder(w).Encode([]interface{}{res})
+}
+
+func newTestPublicFolder(t *testing.T, server *fakeMegaServer) *PublicFolder {
+	link := "https://mega.nz/folder/" + publicFolderHandle + "#" + encodeKey(publicFolderKey)
+	folder, err := NewPublicFolder(link, WithPublicLinkAPIURL(server.URL), WithPublicLinkHTTPClient(server.Client()))
+	require.Nil(t, err)
+	return folder
+}
+
+func newTestPublicFileLink(t *testing.T, server *fakeMegaServer) *PublicFolder {
+	link := "https://mega.nz/file/" + publicFileHandle + "#" + encodeKey(server.fileKey)
+	folder, err := NewPublicLink(link, WithPublicLinkAPIURL(server.URL), WithPublicLinkHTTPClient(server.Client()))
+	require.Nil(t, err)
+	return folder
+}
+
+func encodeKey(key []byte) string {
+	return base64.RawURLEncoding.EncodeToString(key)
+}
+
+func encryptECB(t *testing.T, key []byte, data []byte) string {
+	block, err := aes.NewCipher(key)
+	require.Nil(t, err)
+	result := make([]byte, len(data))
+	for i := 0; i < len(data); i += aes.BlockSize {
+		block.Encrypt(result[i:i+aes.BlockSize], data[i:i+aes.BlockSize])
+	}
+	return encodeKey(result)
+}
+
+func encryptAttr(t *testing.T, key []byte, name string) string {
+	block, err := aes.NewCipher(key)
+	require.Nil(t, err)
+	data, err := json.Marshal(nodeAttr{Name: name})
+	require.Nil(t, err)
+	data = padNull(append([]byte("MEGA"), data...), aes.BlockSize)
+	cipher.NewCBCEncrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(data, data)
+	return encodeKey(data)
+}
+
+func encryptCTR(t *testing.T, key []byte, nonce []byte, data []byte) []byte {
+	block, err := aes.NewCipher(key)
+	require.Nil(t, err)
+	result := make([]byte, len(data))
+	cipher.NewCTR(block, append(append([]byte{}, nonce...), make([]byte, 8)...)).XORKeyStream(result, data)
+	return result
+}
+
+func condensedMAC(t *testing.T, key []byte, nonce []byte, data []byte) []byte {
+	block, err := aes.NewCipher(key)
+	require.Nil(t, err)
+	chunkMAC := make([]byte, aes.BlockSize)
+	padded := padNull(data, aes.BlockSize)
+	mac := cipher.NewCBCEncrypter(block, append(append([]byte{}, nonce...), nonce...))
+	for i := 0; i < len(padded); i += aes.BlockSize {
+		mac.CryptBlocks(chunkMAC, padded[i:i+aes.BlockSize])
+	}
+	fileMAC := make([]byte, aes.BlockSize)
+	cipher.NewCBCEncrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(fileMAC, chunkMAC)
+	result := make([]byte, 8)
+	for i := 0; i < 4; i++ {
+		result[i] = fileMAC[i] ^ fileMAC[i+4]
+		result[i+4] = fileMAC[i+8] ^ fileMAC[i+12]
+	}
+	return result
+}
+
+func assertFileContent(t *testing.T, path string, expContent string) {
+	file, err := os.Open(path)
+	require.Nil(t, err)
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	require.Nil(t, err)
+	assert.Equal(t, expContent, string(content))
+}
@@ -0,0 +1,408 @@
+package megabrowser
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/t3rm1n4l/go-mega"
+)
+
+var errNoUploadClient = fmt.Errorf("client does not support uploading files")
+
+// PublishStatus describes what Publisher did with a local file.
+type PublishStatus string
+
+const (
+	// PublishStatusUploaded means that the file did not exist in the Mega repository and was uploaded.
+	PublishStatusUploaded PublishStatus = "uploaded"
+	// PublishStatusReplaced means that the remote file differed from the local one and was replaced.
+	PublishStatusReplaced PublishStatus = "replaced"
+	// PublishStatusUnchanged means that the remote file matched the local one and was left untouched.
+	PublishStatusUnchanged PublishStatus = "unchanged"
+)
+
+// PublishEntry describes a single local file of the published directory and what was done with it.
+type PublishEntry struct {
+	Path   string        `json:"path"`
+	Size   int64         `json:"size"`
+	Status PublishStatus `json:"status"`
+}
+
+// Manifest lists every file of a published release, with paths relative to the project root.
+type Manifest struct {
+	Version string         `json:"version"`
+	Files   []ManifestFile `json:"files"`
+}
+
+// ManifestFile describes a single file of a release, SHA256 is the hex encoded checksum of its content.
+type ManifestFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Publisher uploads a local directory into the project root directory of a Mega repository, the opposite of Sync.
+type Publisher struct {
+	browser      *MegaBrowser
+	client       UploadClient
+	versionFile  string
+	manifestFile string
+	signingKey   ed25519.PrivateKey
+	keepReleases int
+}
+
+// PublisherOption configures a Publisher created with NewPublisher.
+type PublisherOption func(p *Publisher)
+
+// WithVersionFile makes Publish generate a file of given name in the project root, containing the published version.
+func WithVersionFile(name string) PublisherOption {
+	return func(p *Publisher) {
+		p.versionFile = name
+	}
+}
+
+// WithManifestFile makes Publish generate a file of given name in the project root, containing the Manifest of the published files in JSON format.
+func WithManifestFile(name string) PublisherOption {
+	return func(p *Publisher) {
+		p.manifestFile = name
+	}
+}
+
+// WithSigningKey makes Publish sign the manifest with given key and upload the signature next to it, into a file named after the manifest with ".sig" appended, so that browsers verify the manifest with the public key, see WithManifestKey. It has no effect without WithManifestFile.
+func WithSigningKey(key ed25519.PrivateKey) PublisherOption {
+	return func(p *Publisher) {
+		p.signingKey = key
+	}
+}
+
+// WithKeepReleases makes PublishRelease move releases other than the newest n to the trash, after switching the current release. Zero n keeps every release.
+func WithKeepReleases(n int) PublisherOption {
+	return func(p *Publisher) {
+		p.keepReleases = n
+	}
+}
+
+/*
+NewPublisher creates a publisher uploading to the repository of given browser. The browser credentials, session and root node name are used, and it does not have to be initialized.
+
+Returns an error if:
+
+	the browser works in an anonymous session, see ErrAccountRequired
+	the browser client does not implement UploadClient
+*/
+func NewPublisher(browser *MegaBrowser, opts ...PublisherOption) (*Publisher, error) {
+	err := browser.requireAccount()
+	if err != nil {
+		return nil, err
+	}
+	client, ok := browser.megaClient.(UploadClient)
+	if !ok {
+		return nil, errNoUploadClient
+	}
+
+	publisher := &Publisher{
+		browser: browser,
+		client:  client,
+	}
+	for _, opt := range opts {
+		opt(publisher)
+	}
+	return publisher, nil
+}
+
+/*
+Publish uploads every file of localDir to the project root directory, creating the root directory and its subdirectories if they do not exist. Returns the list of entries describing the published files.
+
+A remote file is replaced, if its size differs from the local file size or the local file was modified after the remote one. The new file is uploaded before the old one is moved to the trash, so the file is never missing. Remote files not existing locally are left untouched. Files not passing the browser filters and local files other than regular files and directories are omitted.
+
+The manifest, its signature and the version file, if enabled, are uploaded last, always replacing the previous ones, so that they do not announce a release before all its files are published. The manifest lists the version file as well.
+
+Returns an error if:
+
+	failed to log in, see MegaBrowser.Initialize
+	version is empty, while the version file or the manifest is enabled
+	failed to read the local directory or any of its files
+	an error occured while getting children of a node
+	failed to create a directory, upload or trash a file
+*/
+func (p *Publisher) Publish(localDir string, version string) ([]PublishEntry, error) {
+	if version == "" && (p.versionFile != "" || p.manifestFile != "") {
+		return nil, fmt.Errorf("version must be set to generate the version file or manifest")
+	}
+
+	root, rootHash, err := p.rootDirectory()
+	if err != nil {
+		return nil, err
+	}
+	return p.publishTree(root, rootHash, localDir, version)
+}
+
+// publishTree uploads files of localDir into the remote directory, whose hash is empty if it was just created, followed by the manifest, its signature and the version file.
+func (p *Publisher) publishTree(dir *mega.Node, dirHash string, localDir string, version string) ([]PublishEntry, error) {
+	manifest := Manifest{Version: version, Files: []ManifestFile{}}
+	var entries []PublishEntry
+	err := p.publishDirectory(dir, dirHash, localDir, "", func(entry PublishEntry, checksum string) {
+		entries = append(entries, entry)
+		if entry.Path != p.versionFile {
+			manifest.Files = append(manifest.Files, ManifestFile{Path: entry.Path, Size: entry.Size, SHA256: checksum})
+		}
+	})
+	if err != nil {
+		return entries, err
+	}
+
+	versionContent := []byte(version + "\n")
+	if p.versionFile != "" {
+		checksum := sha256.Sum256(versionContent)
+		manifest.Files = append(manifest.Files, ManifestFile{Path: p.versionFile, Size: int64(len(versionContent)), SHA256: hex.EncodeToString(checksum[:])})
+	}
+	if p.manifestFile != "" {
+		content, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return entries, err
+		}
+		entry, err := p.publishGenerated(dir, dirHash, p.manifestFile, content)
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, entry)
+
+		if p.signingKey != nil {
+			entry, err := p.publishGenerated(dir, dirHash, p.manifestFile+signatureSuffix, signManifest(p.signingKey, content))
+			if err != nil {
+				return entries, err
+			}
+			entries = append(entries, entry)
+		}
+	}
+	if p.versionFile != "" {
+		entry, err := p.publishGenerated(dir, dirHash, p.versionFile, versionContent)
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// rootDirectory logs in and returns the project root directory node and its hash, creating the directory if it does not exist. The hash is empty for a new directory, which has no children yet.
+func (p *Publisher) rootDirectory() (*mega.Node, string, error) {
+	mb := p.browser
+	err := mb.login()
+	if err != nil {
+		return nil, "", err
+	}
+
+	accountRoot := mb.megaFs.GetRoot()
+	nodes, err := mb.megaFs.GetChildren(accountRoot)
+	if err != nil {
+		return nil, "", err
+	}
+	rootNodeHash, err := mb.getRootNodeHash(describeNodes(mb.megaFs, nodes, accountRoot), mb.rootNodeName)
+	if err == nil {
+		return mb.megaFs.HashLookup(rootNodeHash), rootNodeHash, nil
+	}
+	if !errors.Is(err, ErrRootNodeNotFound) {
+		return nil, "", err
+	}
+
+	root, err := p.client.CreateDir(mb.rootNodeName, accountRoot)
+	if err != nil {
+		return nil, "", err
+	}
+	return root, "", nil
+}
+
+// publishDirectory uploads files of localDir into the remote directory, whose hash is empty if it was just created, calling published for every local file with its checksum, which is computed only if the manifest is enabled.
+func (p *Publisher) publishDirectory(dir *mega.Node, dirHash string, localDir string, dirPath string, published func(entry PublishEntry, checksum string)) error {
+	remoteNodes, err := p.remoteChildren(dirHash)
+	if err != nil {
+		return err
+	}
+
+	localEntries, err := os.ReadDir(localDir)
+	if err != nil {
+		return err
+	}
+
+	for _, localEntry := range localEntries {
+		name := localEntry.Name()
+		remotePath := path.Join(dirPath, name)
+		localPath := filepath.Join(localDir, name)
+		if dirPath == "" && (name == p.versionFile || name == p.manifestFile) {
+			continue
+		}
+		remote := remoteNodes[name]
+
+		if localEntry.IsDir() {
+			subdir, subdirHash, err := p.directory(dir, name, remote)
+			if err != nil {
+				return err
+			}
+			err = p.publishDirectory(subdir, subdirHash, localPath, remotePath, published)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if !localEntry.Type().IsRegular() || !p.browser.filters.matches(remotePath) {
+			continue
+		}
+
+		info, err := localEntry.Info()
+		if err != nil {
+			return err
+		}
+		entry := PublishEntry{Path: remotePath, Size: info.Size(), Status: PublishStatusUnchanged}
+		if remote == nil || remote.GetType() != fileType || remoteFileChanged(remote, info) {
+			entry.Status, err = p.upload(localPath, dir, name, remote)
+			if err != nil {
+				return err
+			}
+		}
+
+		var checksum string
+		if p.manifestFile != "" {
+			checksum, err = fileChecksum(localPath)
+			if err != nil {
+				return err
+			}
+		}
+		published(entry, checksum)
+	}
+	return nil
+}
+
+// remoteChildren returns children of the remote directory of given hash by their names. Returns an empty map for a new directory.
+func (p *Publisher) remoteChildren(dirHash string) (map[string]Node, error) {
+	children := map[string]Node{}
+	if dirHash == "" {
+		return children, nil
+	}
+	nodes, err := p.browser.listChildren(dirHash)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range nodes {
+		children[node.GetName()] = node
+	}
+	return children, nil
+}
+
+// directory returns the existing remote directory node and its hash, or creates a new directory of given name, whose hash is empty.
+func (p *Publisher) directory(parent *mega.Node, name string, remote Node) (*mega.Node, string, error) {
+	if remote != nil && remote.GetType() == directoryType {
+		return p.browser.megaFs.HashLookup(remote.GetHash()), remote.GetHash(), nil
+	}
+	subdir, err := p.client.CreateDir(name, parent)
+	if err != nil {
+		return nil, "", err
+	}
+	return subdir, "", nil
+}
+
+// upload uploads the local file to the parent directory and moves the replaced remote file, if there is one, to the trash.
+func (p *Publisher) upload(localPath string, parent *mega.Node, name string, replaced Node) (PublishStatus, error) {
+	_, err := p.client.UploadFile(localPath, parent, name, nil)
+	if err != nil {
+		return "", err
+	}
+	if replaced == nil {
+		return PublishStatusUploaded, nil
+	}
+	err = p.client.Delete(p.browser.megaFs.HashLookup(replaced.GetHash()), false)
+	if err != nil {
+		return "", err
+	}
+	return PublishStatusReplaced, nil
+}
+
+// publishGenerated uploads a file of given name and content to the remote directory, and then moves every existing file of the name to the trash, including files left behind by a previous publish failing to remove them. Readers pick the newest of the files, see getNodeHashOfNewestFile, so they read the new content, even if removing fails.
+func (p *Publisher) publishGenerated(dir *mega.Node, dirHash string, name string, content []byte) (PublishEntry, error) {
+	replaced, err := p.remoteFiles(dirHash, name)
+	if err != nil {
+		return PublishEntry{}, err
+	}
+
+	tmp, err := os.CreateTemp("", "megabrowser-*")
+	if err != nil {
+		return PublishEntry{}, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(content)
+	closeErr := tmp.Close()
+	if err != nil {
+		return PublishEntry{}, err
+	}
+	if closeErr != nil {
+		return PublishEntry{}, closeErr
+	}
+
+	_, err = p.client.UploadFile(tmp.Name(), dir, name, nil)
+	if err != nil {
+		return PublishEntry{}, err
+	}
+	status := PublishStatusUploaded
+	for _, node := range replaced {
+		err = p.client.Delete(p.browser.megaFs.HashLookup(node.GetHash()), false)
+		if err != nil {
+			return PublishEntry{}, fmt.Errorf("failed to remove previous %s: %w", name, err)
+		}
+		status = PublishStatusReplaced
+	}
+	return PublishEntry{Path: name, Size: int64(len(content)), Status: status}, nil
+}
+
+// remoteFiles returns every file of given name in the remote directory of given hash. Returns no files for a new directory.
+func (p *Publisher) remoteFiles(dirHash string, name string) ([]Node, error) {
+	if dirHash == "" {
+		return nil, nil
+	}
+	nodes, err := p.browser.listChildren(dirHash)
+	if err != nil {
+		return nil, err
+	}
+	var files []Node
+	for _, node := range nodes {
+		if node.GetName() == name && node.GetType() == fileType {
+			files = append(files, node)
+		}
+	}
+	return files, nil
+}
+
+// remoteFileChanged returns true, if the remote file size differs from the local file size, or the local file was modified after the remote file was uploaded. Remote files of unknown modification time are compared by size only.
+func remoteFileChanged(remote Node, info fs.FileInfo) bool {
+	if remote.GetSize() != info.Size() {
+		return true
+	}
+	if remote.GetTimeStamp().IsZero() {
+		return false
+	}
+	return info.ModTime().After(remote.GetTimeStamp())
+}
+
+// fileChecksum returns the hex encoded SHA256 checksum of the file content.
+func fileChecksum(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
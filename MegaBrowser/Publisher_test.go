@@ -0,0 +1,321 @@
+package megabrowser
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/t3rm1n4l/go-mega"
+)
+
+type mockUploadClient struct {
+	mockClient
+	paths     map[*mega.Node]string
+	uploads   map[string]string
+	created   []string
+	deleted   []string
+	errUpload error
+	errDelete error
+}
+
+type mockLookupFs struct {
+	mockFs
+	nodes map[string]*mega.Node
+}
+
+func TestPublish(t *testing.T) {
+	tests := []struct {
+		name       string
+		rootExists bool
+		expCreated []string
+		expDeleted []string
+		expEntries []PublishEntry
+	}{
+		{
+			name:       "should upload new and changed files only, if project root exists",
+			rootExists: true,
+			expCreated: []string{rootNodeName + "/newdir"},
+			expDeleted: []string{rootNodeName + "/changed.txt", rootNodeName + "/version.txt"},
+			expEntries: []PublishEntry{
+				{Path: "changed.txt", Size: 7, Status: PublishStatusReplaced},
+				{Path: "newdir/deep.txt", Size: 4, Status: PublishStatusUploaded},
+				{Path: "same.txt", Size: 4, Status: PublishStatusUnchanged},
+				{Path: "sub/nested.txt", Size: 6, Status: PublishStatusUploaded},
+				{Path: "manifest.json", Status: PublishStatusUploaded},
+				{Path: "manifest.json.sig", Status: PublishStatusUploaded},
+				{Path: "version.txt", Size: 6, Status: PublishStatusReplaced},
+			},
+		},
+		{
+			name:       "should create project root and upload every file, if project root does not exist",
+			rootExists: false,
+			expCreated: []string{rootNodeName, rootNodeName + "/newdir", rootNodeName + "/sub"},
+			expDeleted: nil,
+			expEntries: []PublishEntry{
+				{Path: "changed.txt", Size: 7, Status: PublishStatusUploaded},
+				{Path: "newdir/deep.txt", Size: 4, Status: PublishStatusUploaded},
+				{Path: "same.txt", Size: 4, Status: PublishStatusUploaded},
+				{Path: "sub/nested.txt", Size: 6, Status: PublishStatusUploaded},
+				{Path: "manifest.json", Status: PublishStatusUploaded},
+				{Path: "manifest.json.sig", Status: PublishStatusUploaded},
+				{Path: "version.txt", Size: 6, Status: PublishStatusUploaded},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			localDir := writeReleaseDir(t)
+			client, fs := newMockRepository()
+			getRootNodeHash := mockGetRootNodeHash
+			if !test.rootExists {
+				getRootNodeHash = func(nodes []Node, rootNodeName string) (string, error) {
+					return "", ErrRootNodeNotFound
+				}
+			}
+			browser, err := New(
+				WithCredentials(login, pass),
+				WithRootNode(rootNodeName),
+				WithClient(client),
+				WithFs(fs),
+				WithRootNodeHashFunc(getRootNodeHash),
+				WithChildrenFunc(mockGetReleaseChildren),
+			)
+			require.Nil(t, err)
+			publisher, err := NewPublisher(browser, WithVersionFile("version.txt"), WithManifestFile("manifest.json"), WithSigningKey(mirrorKey))
+			require.Nil(t, err)
+
+			entries, err := publisher.Publish(localDir, "1.5.0")
+
+			require.Nil(t, err)
+			assert.Equal(t, test.expCreated, client.created)
+			assert.Equal(t, test.expDeleted, client.deleted)
+			require.Len(t, entries, len(test.expEntries))
+			for i, expEntry := range test.expEntries {
+				assert.Equal(t, expEntry.Path, entries[i].Path)
+				assert.Equal(t, expEntry.Status, entries[i].Status)
+				if expEntry.Size != 0 {
+					assert.Equal(t, expEntry.Size, entries[i].Size)
+				}
+				if expEntry.Status == PublishStatusUnchanged {
+					assert.NotContains(t, client.uploads, rootNodeName+"/"+expEntry.Path)
+				}
+			}
+			assert.Equal(t, "1.5.0\n", client.uploads[rootNodeName+"/version.txt"])
+
+			var manifest Manifest
+			err = json.Unmarshal([]byte(client.uploads[rootNodeName+"/manifest.json"]), &manifest)
+			require.Nil(t, err)
+			assert.Equal(t, "1.5.0", manifest.Version)
+			require.Len(t, manifest.Files, 5)
+			sameChecksum := sha256.Sum256([]byte("same"))
+			assert.Equal(t, ManifestFile{Path: "same.txt", Size: 4, SHA256: hex.EncodeToString(sameChecksum[:])}, manifest.Files[2])
+			versionChecksum := sha256.Sum256([]byte("1.5.0\n"))
+			assert.Equal(t, ManifestFile{Path: "version.txt", Size: 6, SHA256: hex.EncodeToString(versionChecksum[:])}, manifest.Files[4])
+			signature := client.uploads[rootNodeName+"/manifest.json.sig"]
+			assert.Nil(t, verifySignature(mirrorKey.Public().(ed25519.PublicKey), []byte(client.uploads[rootNodeName+"/manifest.json"]), []byte(signature)))
+		})
+	}
+}
+
+func TestPublishFailCase(t *testing.T) {
+	tests := []struct {
+		name      string
+		version   string
+		errUpload error
+		expErr    string
+	}{
+		{
+			name:    "should fail, if version is empty and version file is enabled",
+			version: "",
+			expErr:  "version must be set",
+		},
+		{
+			name:      "should fail, if upload fails",
+			version:   "1.5.0",
+			errUpload: errDownload,
+			expErr:    errDownload.Error(),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, fs := newMockRepository()
+			client.errUpload = test.errUpload
+			browser, err := New(
+				WithCredentials(login, pass),
+				WithRootNode(rootNodeName),
+				WithClient(client),
+				WithFs(fs),
+				WithRootNodeHashFunc(mockGetRootNodeHash),
+				WithChildrenFunc(mockGetReleaseChildren),
+			)
+			require.Nil(t, err)
+			publisher, err := NewPublisher(browser, WithVersionFile("version.txt"))
+			require.Nil(t, err)
+
+			_, err = publisher.Publish(writeReleaseDir(t), test.version)
+
+			require.NotNil(t, err)
+			assert.Contains(t, err.Error(), test.expErr)
+		})
+	}
+}
+
+func TestNewPublisherFailCase(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []Option
+		expErr error
+	}{
+		{
+			name:   "should fail, if browser session is anonymous",
+			opts:   []Option{WithClient(&mockUploadClient{}), WithAnonymousSession()},
+			expErr: ErrAccountRequired,
+		},
+		{
+			name:   "should fail, if client does not support uploading",
+			opts:   []Option{WithClient(&mockClient{})},
+			expErr: errNoUploadClient,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			browser, err := New(test.opts...)
+			require.Nil(t, err)
+
+			publisher, err := NewPublisher(browser)
+
+			assert.Nil(t, publisher)
+			assert.Equal(t, test.expErr, err)
+		})
+	}
+}
+
+func TestRemoteFileChanged(t *testing.T) {
+	localPath := filepath.Join(t.TempDir(), "file")
+	require.Nil(t, os.WriteFile(localPath, []byte("same"), 0666))
+	info, err := os.Stat(localPath)
+	require.Nil(t, err)
+
+	tests := []struct {
+		name       string
+		remote     Node
+		expChanged bool
+	}{
+		{
+			name:       "should be changed, if sizes differ",
+			remote:     &mockNode{size: 1},
+			expChanged: true,
+		},
+		{
+			name:       "should be unchanged, if sizes match and remote modification time is unknown",
+			remote:     &mockNode{size: 4},
+			expChanged: false,
+		},
+		{
+			name:       "should be changed, if local file was modified after the remote one",
+			remote:     &mockNode{size: 4, timestamp: info.ModTime().Add(-time.Hour)},
+			expChanged: true,
+		},
+		{
+			name:       "should be unchanged, if remote file was modified after the local one",
+			remote:     &mockNode{size: 4, timestamp: info.ModTime().Add(time.Hour)},
+			expChanged: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expChanged, remoteFileChanged(test.remote, info))
+		})
+	}
+}
+
+// writeReleaseDir creates a local directory to publish, including a version file which is expected to be ignored.
+func writeReleaseDir(t *testing.T) string {
+	localDir := t.TempDir()
+	files := map[string]string{
+		"same.txt":        "same",
+		"changed.txt":     "changed",
+		"version.txt":     "local",
+		"sub/nested.txt":  "nested",
+		"newdir/deep.txt": "deep",
+	}
+	for name, content := range files {
+		filePath := filepath.Join(localDir, filepath.FromSlash(name))
+		require.Nil(t, os.MkdirAll(filepath.Dir(filePath), 0777))
+		require.Nil(t, os.WriteFile(filePath, []byte(content), 0666))
+	}
+	return localDir
+}
+
+// newMockRepository creates a client and filesystem of a repository containing the project root with a single subdirectory.
+func newMockRepository() (*mockUploadClient, *mockLookupFs) {
+	fs := &mockLookupFs{nodes: map[string]*mega.Node{
+		expRootNodeHash: {},
+		"subhash":       {},
+		"samehash":      {},
+		"changedhash":   {},
+		"versionhash":   {},
+	}}
+	client := &mockUploadClient{
+		paths: map[*mega.Node]string{
+			fs.nodes[expRootNodeHash]: rootNodeName,
+			fs.nodes["subhash"]:       rootNodeName + "/sub",
+			fs.nodes["samehash"]:      rootNodeName + "/same.txt",
+			fs.nodes["changedhash"]:   rootNodeName + "/changed.txt",
+			fs.nodes["versionhash"]:   rootNodeName + "/version.txt",
+		},
+		uploads: map[string]string{},
+	}
+	return client, fs
+}
+
+func mockGetReleaseChildren(fs Fs, nodeHash string) ([]Node, error) {
+	if nodeHash != expRootNodeHash {
+		return nil, nil
+	}
+	return []Node{
+		&mockNode{name: "same.txt", nodeType: fileType, hash: "samehash", size: 4},
+		&mockNode{name: "changed.txt", nodeType: fileType, hash: "changedhash", size: 1},
+		&mockNode{name: "version.txt", nodeType: fileType, hash: "versionhash", size: 6},
+		&mockNode{name: "sub", nodeType: directoryType, hash: "subhash"},
+	}, nil
+}
+
+func (m *mockUploadClient) UploadFile(srcpath string, parent *mega.Node, name string, progress *chan int) (*mega.Node, error) {
+	if m.errUpload != nil {
+		return nil, m.errUpload
+	}
+	content, err := os.ReadFile(srcpath)
+	if err != nil {
+		return nil, err
+	}
+	m.uploads[path.Join(m.paths[parent], name)] = string(content)
+	return &mega.Node{}, nil
+}
+
+func (m *mockUploadClient) CreateDir(name string, parent *mega.Node) (*mega.Node, error) {
+	node := &mega.Node{}
+	m.paths[node] = path.Join(m.paths[parent], name)
+	m.created = append(m.created, m.paths[node])
+	return node, nil
+}
+
+func (m *mockUploadClient) Delete(node *mega.Node, destroy bool) error {
+	if m.errDelete != nil {
+		return m.errDelete
+	}
+	m.deleted = append(m.deleted, m.paths[node])
+	return nil
+}
+
+func (m *mockLookupFs) HashLookup(hash string) *mega.Node {
+	return m.nodes[hash]
+}
@@ -0,0 +1,135 @@
+package megabrowser
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/t3rm1n4l/go-mega"
+)
+
+// ErrQuotaUnsupported is returned by MegaBrowser.Quota, if the client does not implement QuotaClient.
+var ErrQuotaUnsupported = errors.New("account quota is not supported by the client")
+
+// quotaTimeLeftHeader is the header of a response of the Mega storage servers, which rejected a transfer over quota, holding the number of seconds until the quota resets.
+const quotaTimeLeftHeader = "X-MEGA-Time-Left"
+
+// megaOverQuotaStatusCode is the status, which the Mega storage servers respond with, when the transfer quota is exceeded.
+const megaOverQuotaStatusCode = 509
+
+// megaOverQuotaStatus starts errors of the client from t3rm1n4l/go-mega package, if a storage server rejected a chunk over quota.
+const megaOverQuotaStatus = "Http Status: 509"
+
+// defaultQuotaWait is the time a downloader waits before retrying a download over quota, if the server did not say when the quota resets.
+const defaultQuotaWait = 10 * time.Minute
+
+/*
+QuotaError is returned by MegaDownloader, if a download failed, because the transfer quota of the account, or of the IP address for public links, is exceeded. Wait is the time until the quota resets, as given by the server, or zero if it is unknown.
+
+The client from t3rm1n4l/go-mega package does not expose the wait time, so it is known only for public links, unless the client returns a QuotaError itself.
+*/
+type QuotaError struct {
+	Wait time.Duration
+	Err  error
+}
+
+func (e *QuotaError) Error() string {
+	if e.Wait > 0 {
+		return fmt.Sprintf("transfer quota exceeded, resets in %s: %v", e.Wait.Round(time.Second), e.Err)
+	}
+	return fmt.Sprintf("transfer quota exceeded: %v", e.Err)
+}
+
+func (e *QuotaError) Unwrap() error {
+	return e.Err
+}
+
+// Quota is the storage and transfer quota and usage of a Mega account in bytes. TransferTotal is zero, if Mega does not report a transfer limit for the account.
+type Quota struct {
+	StorageUsed   uint64 `json:"storageUsed"`
+	StorageTotal  uint64 `json:"storageTotal"`
+	TransferUsed  uint64 `json:"transferUsed"`
+	TransferTotal uint64 `json:"transferTotal"`
+}
+
+// QuotaClient is a StorageClient able to report the account quota, like the client from t3rm1n4l/go-mega package. MegaBrowser.Quota requires it.
+type QuotaClient interface {
+	StorageClient
+	GetQuota() (mega.QuotaResp, error)
+}
+
+/*
+Quota returns the storage and transfer quota and usage of the account. Paid accounts have a fixed transfer quota, which includes the transfer used serving their shares. Free accounts have a limit, which Mega adjusts over time, and their usage is the transfer of the recent hours. A download over the transfer quota fails with QuotaError.
+
+Returns an error if:
+
+	the browser is in an anonymous session, see ErrAccountRequired
+	the client does not implement QuotaClient, see ErrQuotaUnsupported
+	the quota request failed
+*/
+func (mb *MegaBrowser) Quota() (Quota, error) {
+	err := mb.requireAccount()
+	if err != nil {
+		return Quota{}, err
+	}
+	client, ok := mb.megaClient.(QuotaClient)
+	if !ok {
+		return Quota{}, ErrQuotaUnsupported
+	}
+	res, err := client.GetQuota()
+	if err != nil {
+		return Quota{}, err
+	}
+	quota := Quota{StorageUsed: res.Cstrg, StorageTotal: res.Mstrg}
+	if res.Mxfer > 0 {
+		quota.TransferUsed = res.Caxfer + res.Csxfer
+		quota.TransferTotal = res.Mxfer
+		return quota, nil
+	}
+	for _, used := range res.Tah {
+		quota.TransferUsed += used
+	}
+	quota.TransferTotal = res.Tal
+	return quota, nil
+}
+
+// SetQuotaWait makes the downloader pause, when the transfer quota is exceeded, and resume once it resets, waiting at most maxWait in total for a single file. Zero maxWait disables waiting, so that a download over quota fails at once with QuotaError.
+func (md *MegaDownloader) SetQuotaWait(maxWait time.Duration) {
+	md.quotaMaxWait = maxWait
+}
+
+// quotaDelay returns how long to wait for the quota to reset after err, if the downloader waited for given time already. Returns false, if the download must not wait, because waiting is disabled or the quota does not reset in time.
+func (md *MegaDownloader) quotaDelay(err *QuotaError, waited time.Duration) (time.Duration, bool) {
+	remaining := md.quotaMaxWait - waited
+	if remaining <= 0 {
+		return 0, false
+	}
+	if err.Wait > 0 {
+		return err.Wait, err.Wait <= remaining
+	}
+	return min(defaultQuotaWait, remaining), true
+}
+
+// asQuotaError returns err as QuotaError, if it says that the transfer quota is exceeded.
+func asQuotaError(err error) (*QuotaError, bool) {
+	var quotaErr *QuotaError
+	if errors.As(err, &quotaErr) {
+		return quotaErr, true
+	}
+	if errors.Is(err, mega.EOVERQUOTA) || strings.HasPrefix(err.Error(), megaOverQuotaStatus) {
+		return &QuotaError{Err: err}, true
+	}
+	return nil, false
+}
+
+// quotaWait returns the time until the quota resets, as given by the X-MEGA-Time-Left header of resp, or zero if it is missing or malformed.
+func quotaWait(resp *http.Response) time.Duration {
+	seconds, err := strconv.ParseInt(resp.Header.Get(quotaTimeLeftHeader), 10, 64)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
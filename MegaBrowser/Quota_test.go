@@ -0,0 +1,229 @@
+package megabrowser
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/t3rm1n4l/go-mega"
+)
+
+// overQuotaClient fails downloads with the given errors one by one, after which downloads succeed.
+type overQuotaClient struct {
+	mockClient
+	errs []error
+}
+
+// quotaClient reports a fixed account quota.
+type quotaClient struct {
+	mockClient
+	quota mega.QuotaResp
+	err   error
+}
+
+func TestDownloadFileOverQuota(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxWait   time.Duration
+		errs      []error
+		expErr    error
+		expSleeps []time.Duration
+	}{
+		{
+			name:   "should fail at once with quota error, if waiting is disabled",
+			errs:   []error{mega.EOVERQUOTA},
+			expErr: &QuotaError{Err: mega.EOVERQUOTA},
+		},
+		{
+			name:      "should resume after the quota resets, if it resets in time",
+			maxWait:   2 * time.Hour,
+			errs:      []error{&QuotaError{Wait: time.Hour, Err: mega.EOVERQUOTA}},
+			expSleeps: []time.Duration{time.Hour},
+		},
+		{
+			name:    "should fail, if the quota does not reset in time",
+			maxWait: 2 * time.Hour,
+			errs:    []error{&QuotaError{Wait: 3 * time.Hour, Err: mega.EOVERQUOTA}},
+			expErr:  &QuotaError{Wait: 3 * time.Hour, Err: mega.EOVERQUOTA},
+		},
+		{
+			name:      "should retry after default wait, if the server does not say when the quota resets",
+			maxWait:   25 * time.Minute,
+			errs:      []error{errors.New(megaOverQuotaStatus + " Bandwidth Limit Exceeded"), mega.EOVERQUOTA, mega.EOVERQUOTA},
+			expSleeps: []time.Duration{defaultQuotaWait, defaultQuotaWait, 5 * time.Minute},
+		},
+		{
+			name:      "should fail, if it waited for max wait already",
+			maxWait:   20 * time.Minute,
+			errs:      []error{mega.EOVERQUOTA, mega.EOVERQUOTA, mega.EOVERQUOTA},
+			expErr:    &QuotaError{Err: mega.EOVERQUOTA},
+			expSleeps: []time.Duration{defaultQuotaWait, defaultQuotaWait},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &overQuotaClient{errs: test.errs}
+			downloader := NewMegaDownloader()
+			downloader.getNodeSize = mockGetNodeSize
+			downloader.getNodeHash = mockGetNodeHash
+			downloader.SetQuotaWait(test.maxWait)
+			var sleeps []time.Duration
+			downloader.sleep = func(ctx context.Context, d time.Duration) error {
+				sleeps = append(sleeps, d)
+				return nil
+			}
+
+			err := downloadFile(downloader, client, filepath.Join(t.TempDir(), "file.txt"))
+
+			assert.Equal(t, test.expErr, err)
+			assert.Equal(t, test.expSleeps, sleeps)
+		})
+	}
+}
+
+func TestPublicFileDownloadOverQuota(t *testing.T) {
+	tests := []struct {
+		name          string
+		apiQuotaLeft  int
+		dataQuotaLeft int
+		expWait       time.Duration
+	}{
+		{
+			name:         "should report wait time, if download request is over quota",
+			apiQuotaLeft: 90,
+			expWait:      90 * time.Second,
+		},
+		{
+			name:          "should report wait time, if data transfer is over quota",
+			dataQuotaLeft: 3600,
+			expWait:       time.Hour,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newFakeMegaServer(t)
+			server.apiQuotaLeft = test.apiQuotaLeft
+			server.dataQuotaLeft = test.dataQuotaLeft
+			folder := newTestPublicFolder(t, server)
+			require.Nil(t, folder.Load())
+			file, err := folder.File(publicFileHandle)
+			require.Nil(t, err)
+			downloader := NewMegaDownloader()
+			downloader.progressOutput = nil
+
+			err = downloader.DownloadPublicFile(file, filepath.Join(t.TempDir(), "file.txt"))
+
+			var quotaErr *QuotaError
+			require.ErrorAs(t, err, &quotaErr)
+			assert.Equal(t, test.expWait, quotaErr.Wait)
+		})
+	}
+}
+
+func TestDownloadFileOverQuotaStopsWaitingIfContextIsCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	client := &overQuotaClient{errs: []error{mega.EOVERQUOTA}}
+	downloader := NewMegaDownloader()
+	downloader.getNodeSize = mockGetNodeSize
+	downloader.getNodeHash = mockGetNodeHash
+	downloader.SetQuotaWait(time.Hour)
+	start := time.Now()
+
+	err := downloadFile(downloader.WithContext(ctx), client, filepath.Join(t.TempDir(), "file.txt"))
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Minute)
+}
+
+func TestQuota(t *testing.T) {
+	tests := []struct {
+		name     string
+		client   StorageClient
+		opts     []Option
+		expQuota Quota
+		expErr   error
+	}{
+		{
+			name:     "should return storage quota, if client reports it",
+			client:   &quotaClient{quota: mega.QuotaResp{Mstrg: 1000, Cstrg: 250}},
+			expQuota: Quota{StorageUsed: 250, StorageTotal: 1000},
+		},
+		{
+			name:     "should return transfer quota, if account has a fixed transfer quota",
+			client:   &quotaClient{quota: mega.QuotaResp{Mstrg: 1000, Cstrg: 250, Mxfer: 5000, Caxfer: 1200, Csxfer: 300}},
+			expQuota: Quota{StorageUsed: 250, StorageTotal: 1000, TransferUsed: 1500, TransferTotal: 5000},
+		},
+		{
+			name:     "should return transfer of recent hours, if account is free",
+			client:   &quotaClient{quota: mega.QuotaResp{Mstrg: 1000, Cstrg: 250, Tah: []uint64{100, 0, 200}, Tal: 4000}},
+			expQuota: Quota{StorageUsed: 250, StorageTotal: 1000, TransferUsed: 300, TransferTotal: 4000},
+		},
+		{
+			name:   "should fail, if quota request fails",
+			client: &quotaClient{err: mega.EACCESS},
+			expErr: mega.EACCESS,
+		},
+		{
+			name:   "should fail, if client does not report quota",
+			client: &mockClient{},
+			expErr: ErrQuotaUnsupported,
+		},
+		{
+			name:   "should fail, if session is anonymous",
+			client: &quotaClient{},
+			opts:   []Option{WithAnonymousSession()},
+			expErr: ErrAccountRequired,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			browser, err := New(append([]Option{WithClient(test.client)}, test.opts...)...)
+			require.Nil(t, err)
+
+			quota, err := browser.Quota()
+
+			assert.Equal(t, test.expErr, err)
+			assert.Equal(t, test.expQuota, quota)
+		})
+	}
+}
+
+func TestQuotaErrorMessage(t *testing.T) {
+	assert.Equal(t, "transfer quota exceeded: Request over quota", (&QuotaError{Err: mega.EOVERQUOTA}).Error())
+	assert.Equal(t, "transfer quota exceeded, resets in 1h30m0s: Request over quota", (&QuotaError{Wait: 90 * time.Minute, Err: mega.EOVERQUOTA}).Error())
+	assert.ErrorIs(t, &QuotaError{Err: mega.EOVERQUOTA}, mega.EOVERQUOTA)
+}
+
+func TestNewMegaBrowserFromConfigWithQuotaWait(t *testing.T) {
+	cfg, err := LoadConfig("", func(key string) string {
+		return map[string]string{
+			EnvLocalDir:  t.TempDir(),
+			EnvQuotaWait: "2h",
+		}[key]
+	})
+	require.Nil(t, err)
+
+	browser, err := NewMegaBrowserFromConfig(cfg)
+
+	require.Nil(t, err)
+	assert.Equal(t, 2*time.Hour, browser.downloader.(*MegaDownloader).quotaMaxWait)
+}
+
+func (c *overQuotaClient) DownloadFile(src *mega.Node, dstpath string, progress *chan int) error {
+	close(*progress)
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		return err
+	}
+	return nil
+}
+
+func (c *quotaClient) GetQuota() (mega.QuotaResp, error) {
+	return c.quota, c.err
+}
@@ -0,0 +1,213 @@
+package megabrowser
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// releasesDirName is the name of the directory inside the project root, containing a directory for every published release.
+	releasesDirName = "releases"
+	// currentReleaseFile is the name of the file inside the project root, containing the version of the current release.
+	currentReleaseFile = "current"
+)
+
+// Release returns version of the current release, which the browser works on, if it was created with WithReleases. Returns an empty string otherwise.
+func (mb *MegaBrowser) Release() string {
+	return mb.release
+}
+
+/*
+PublishRelease uploads every file of localDir into a new release directory, e.g. releases/1.5.0 inside the project root directory, and then switches the current release pointer to it. Browsers created with WithReleases never observe a partially published release, because the pointer is switched only after every file is uploaded, and the previous release directory is left untouched.
+
+The new pointer is uploaded before the old one is moved to the trash, so for a moment both exist, or both stay, if moving the old one fails. A browser always reads the newest pointer, so it sees the new release as soon as its pointer is uploaded, and a later publish removes every stale pointer.
+
+If the publisher was created with WithKeepReleases, releases other than the newest ones are moved to the trash afterwards. The current release is never removed.
+
+Returns an error if:
+
+	version is empty or is not a valid directory name
+	failed to log in, see MegaBrowser.Initialize
+	release of given version already exists
+	failed to publish the files, see Publish
+	failed to switch the pointer or to remove old releases
+*/
+func (p *Publisher) PublishRelease(localDir string, version string) ([]PublishEntry, error) {
+	err := validateReleaseName(version)
+	if err != nil {
+		return nil, err
+	}
+
+	root, rootHash, err := p.rootDirectory()
+	if err != nil {
+		return nil, err
+	}
+	rootNodes, err := p.remoteChildren(rootHash)
+	if err != nil {
+		return nil, err
+	}
+	releasesDir, releasesHash, err := p.directory(root, releasesDirName, rootNodes[releasesDirName])
+	if err != nil {
+		return nil, err
+	}
+	releases, err := p.remoteChildren(releasesHash)
+	if err != nil {
+		return nil, err
+	}
+	if _, exists := releases[version]; exists {
+		return nil, fmt.Errorf("release %s already exists", version)
+	}
+
+	releaseDir, err := p.client.CreateDir(version, releasesDir)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := p.publishTree(releaseDir, "", localDir, version)
+	if err != nil {
+		return entries, err
+	}
+
+	_, err = p.publishGenerated(root, rootHash, currentReleaseFile, []byte(version+"\n"))
+	if err != nil {
+		return entries, err
+	}
+	return entries, p.removeOldReleases(releases, version)
+}
+
+// removeOldReleases moves release directories other than the newest ones and the current one to the trash. Does nothing, if the publisher keeps every release.
+func (p *Publisher) removeOldReleases(releases map[string]Node, current string) error {
+	if p.keepReleases <= 0 {
+		return nil
+	}
+
+	versions := []string{current}
+	for name, node := range releases {
+		if node.GetType() == directoryType {
+			versions = append(versions, name)
+		}
+	}
+	sortVersionsDescending(versions)
+
+	for _, version := range versions[min(p.keepReleases, len(versions)):] {
+		if version == current {
+			continue
+		}
+		err := p.client.Delete(p.browser.megaFs.HashLookup(releases[version].GetHash()), false)
+		if err != nil {
+			return fmt.Errorf("failed to remove release %s: %w", version, err)
+		}
+	}
+	return nil
+}
+
+// resolveCurrentRelease makes the directory of the current release the project root node, or of the release pinned in the release directory of the browser, if there is one, see WithReleaseDir.
+func (mb *MegaBrowser) resolveCurrentRelease() error {
+	mb.projectHash = mb.rootNodeHash
+	var pinned string
+	if mb.releaseDir != "" {
+		history, err := readReleaseHistory(mb.releaseDir)
+		if err != nil {
+			return err
+		}
+		pinned = history.Pinned
+	}
+	return mb.resolveRelease(pinned)
+}
+
+// resolveRelease makes the directory of given release the project root node, or of the release the current release pointer in the project root directory points to, if version is empty.
+func (mb *MegaBrowser) resolveRelease(version string) error {
+	rootNodes, err := mb.listChildren(mb.projectHash)
+	if err != nil {
+		return err
+	}
+	releasesHash, err := getNodeHashOfExpectedDirectory(releasesDirName, &rootNodes)
+	if err != nil {
+		return err
+	}
+
+	pinned := version != ""
+	if !pinned {
+		pointerHash, err := getNodeHashOfNewestFile(currentReleaseFile, &rootNodes)
+		if err != nil {
+			return err
+		}
+		content, err := mb.readRemoteFile(pointerHash)
+		if err != nil {
+			return fmt.Errorf("failed to read current release: %w", err)
+		}
+		version = strings.TrimSpace(string(content))
+		err = validateReleaseName(version)
+		if err != nil {
+			return fmt.Errorf("invalid current release: %w", err)
+		}
+	}
+
+	releaseNodes, err := mb.listChildren(releasesHash)
+	if err != nil {
+		return err
+	}
+	releaseHash, err := getNodeHashOfExpectedDirectory(version, &releaseNodes)
+	if err != nil {
+		return err
+	}
+
+	mb.releasesHash = releasesHash
+	mb.rootNodeHash = releaseHash
+	mb.release = version
+	mb.pinned = pinned
+	return nil
+}
+
+// readRemoteFile downloads a small file of given hash to a temporary file and returns its content, bypassing the downloader, so that no progress is reported.
+func (mb *MegaBrowser) readRemoteFile(hash string) ([]byte, error) {
+	tmp, err := os.CreateTemp("", "megabrowser-*")
+	if err != nil {
+		return nil, err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	err = mb.storage().Download(hash, tmp.Name(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(tmp.Name())
+}
+
+// validateReleaseName returns an error, if given version cannot be used as a release directory name.
+func validateReleaseName(version string) error {
+	if version == "" || version == "." || version == ".." || strings.ContainsAny(version, "/\\") {
+		return fmt.Errorf("invalid release version: %q", version)
+	}
+	return nil
+}
+
+// sortVersionsDescending sorts versions from the newest to the oldest, see compareVersions.
+func sortVersionsDescending(versions []string) {
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) > 0
+	})
+}
+
+// compareVersions compares dot separated versions, e.g. "1.10.0" and "v1.9.2", part by part, numerically if both parts are numbers and lexically otherwise. Returns a positive number, if a is newer than b, a negative one if it is older, and zero if they are equal.
+func compareVersions(a string, b string) int {
+	aParts := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bParts := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		aNumber, aErr := strconv.Atoi(aParts[i])
+		bNumber, bErr := strconv.Atoi(bParts[i])
+		if aErr == nil && bErr == nil {
+			if aNumber != bNumber {
+				return aNumber - bNumber
+			}
+			continue
+		}
+		if result := strings.Compare(aParts[i], bParts[i]); result != 0 {
+			return result
+		}
+	}
+	return len(aParts) - len(bParts)
+}
@@ -0,0 +1,300 @@
+package megabrowser
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/t3rm1n4l/go-mega"
+)
+
+const (
+	releasesHash = "releaseshash"
+	currentHash  = "currenthash"
+)
+
+// mockReleaseClient is a mockUploadClient, which downloads files of given content.
+type mockReleaseClient struct {
+	*mockUploadClient
+	contents map[*mega.Node]string
+}
+
+func TestPublishRelease(t *testing.T) {
+	tests := []struct {
+		name         string
+		version      string
+		keepReleases int
+		expDeleted   []string
+		expErr       string
+	}{
+		{
+			name:         "should publish release and keep every old release, if releases are not limited",
+			version:      "1.11.0",
+			keepReleases: 0,
+			expDeleted:   []string{rootNodeName + "/current"},
+		},
+		{
+			name:         "should publish release and remove the oldest releases beyond the limit",
+			version:      "1.11.0",
+			keepReleases: 2,
+			expDeleted:   []string{rootNodeName + "/current", rootNodeName + "/releases/1.4.0", rootNodeName + "/releases/1.2.0"},
+		},
+		{
+			name:         "should never remove the current release, even if it is not the newest one",
+			version:      "1.4.1",
+			keepReleases: 1,
+			expDeleted:   []string{rootNodeName + "/current", rootNodeName + "/releases/1.4.0", rootNodeName + "/releases/1.2.0"},
+		},
+		{
+			name:    "should fail, if release already exists",
+			version: "1.4.0",
+			expErr:  "release 1.4.0 already exists",
+		},
+		{
+			name:    "should fail, if version is not a valid directory name",
+			version: "../1.5.0",
+			expErr:  "invalid release version",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, fs := newMockReleaseRepository("1.10.0")
+			browser, err := New(
+				WithCredentials(login, pass),
+				WithRootNode(rootNodeName),
+				WithClient(client),
+				WithFs(fs),
+				WithRootNodeHashFunc(mockGetRootNodeHash),
+				WithChildrenFunc(mockGetReleasesChildren),
+			)
+			require.Nil(t, err)
+			publisher, err := NewPublisher(browser, WithVersionFile("version.txt"), WithKeepReleases(test.keepReleases))
+			require.Nil(t, err)
+
+			entries, err := publisher.PublishRelease(writeReleaseDir(t), test.version)
+
+			if test.expErr != "" {
+				require.NotNil(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+				assert.Empty(t, client.uploads)
+				return
+			}
+			require.Nil(t, err)
+			releaseDir := rootNodeName + "/releases/" + test.version
+			assert.Equal(t, []string{releaseDir, releaseDir + "/newdir", releaseDir + "/sub"}, client.created)
+			assert.Equal(t, test.expDeleted, client.deleted)
+			assert.Len(t, entries, 5)
+			assert.Equal(t, "same", client.uploads[releaseDir+"/same.txt"])
+			assert.Equal(t, test.version+"\n", client.uploads[releaseDir+"/version.txt"])
+			assert.Equal(t, test.version+"\n", client.uploads[rootNodeName+"/current"])
+		})
+	}
+}
+
+func TestPublishReleaseKeepsNewPointerIfOldOneCannotBeRemoved(t *testing.T) {
+	client, fs := newMockReleaseRepository("1.10.0")
+	client.errDelete = mega.EACCESS
+	browser, err := New(
+		WithCredentials(login, pass),
+		WithRootNode(rootNodeName),
+		WithClient(client),
+		WithFs(fs),
+		WithRootNodeHashFunc(mockGetRootNodeHash),
+		WithChildrenFunc(mockGetReleasesChildren),
+	)
+	require.Nil(t, err)
+	publisher, err := NewPublisher(browser)
+	require.Nil(t, err)
+
+	_, err = publisher.PublishRelease(writeReleaseDir(t), "1.11.0")
+
+	assert.ErrorIs(t, err, mega.EACCESS)
+	assert.Contains(t, err.Error(), "failed to remove previous current")
+	assert.Equal(t, "1.11.0\n", client.uploads[rootNodeName+"/current"])
+	assert.Empty(t, client.deleted)
+}
+
+func TestInitializeWithReleasesReadsNewestPointer(t *testing.T) {
+	published := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	stale := &mockNode{name: "current", nodeType: fileType, hash: "abandonedhash", size: 6, timestamp: published.Add(-time.Hour)}
+	tests := []struct {
+		name     string
+		pointers []Node
+	}{
+		{
+			name: "should read the newest pointer, if the stale one is listed first",
+			pointers: []Node{
+				stale,
+				&mockNode{name: "current", nodeType: fileType, hash: currentHash, size: 7, timestamp: published},
+			},
+		},
+		{
+			name: "should read the newest pointer, if the stale one is listed last",
+			pointers: []Node{
+				&mockNode{name: "current", nodeType: fileType, hash: currentHash, size: 7, timestamp: published},
+				stale,
+			},
+		},
+		{
+			name: "should read the pointer of the greatest hash, if pointers are equally old",
+			pointers: []Node{
+				&mockNode{name: "current", nodeType: fileType, hash: currentHash, size: 7, timestamp: published},
+				&mockNode{name: "current", nodeType: fileType, hash: "abandonedhash", size: 6, timestamp: published},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, fs := newMockReleaseRepository("1.10.0\n")
+			fs.nodes["abandonedhash"] = &mega.Node{}
+			client.contents[fs.nodes["abandonedhash"]] = "1.4.0\n"
+			browser, err := New(
+				WithCredentials(login, pass),
+				WithRootNode(rootNodeName),
+				WithClient(client),
+				WithFs(fs),
+				WithRootNodeHashFunc(mockGetRootNodeHash),
+				WithChildrenFunc(func(fs Fs, nodeHash string) ([]Node, error) {
+					if nodeHash == expRootNodeHash {
+						return append(test.pointers, &mockNode{name: "releases", nodeType: directoryType, hash: releasesHash}), nil
+					}
+					return mockGetReleasesChildren(fs, nodeHash)
+				}),
+				WithReleases(),
+			)
+			require.Nil(t, err)
+
+			err = browser.Initialize()
+
+			require.Nil(t, err)
+			assert.Equal(t, "1.10.0", browser.Release())
+		})
+	}
+}
+
+func TestInitializeWithReleases(t *testing.T) {
+	tests := []struct {
+		name            string
+		pointer         string
+		expRootNodeHash string
+		expRelease      string
+		expErr          string
+	}{
+		{
+			name:            "should browse the current release, if pointer is valid",
+			pointer:         "1.10.0\n",
+			expRootNodeHash: "release-1.10.0",
+			expRelease:      "1.10.0",
+		},
+		{
+			name:    "should fail, if pointer refers to a missing release",
+			pointer: "2.0.0\n",
+			expErr:  "could not find directory: 2.0.0",
+		},
+		{
+			name:    "should fail, if pointer is malformed",
+			pointer: "../../secret\n",
+			expErr:  "invalid current release",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, fs := newMockReleaseRepository(test.pointer)
+			browser, err := New(
+				WithCredentials(login, pass),
+				WithRootNode(rootNodeName),
+				WithClient(client),
+				WithFs(fs),
+				WithRootNodeHashFunc(mockGetRootNodeHash),
+				WithChildrenFunc(mockGetReleasesChildren),
+				WithReleases(),
+			)
+			require.Nil(t, err)
+
+			err = browser.Initialize()
+
+			if test.expErr != "" {
+				require.NotNil(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+				return
+			}
+			require.Nil(t, err)
+			assert.Equal(t, test.expRootNodeHash, browser.rootNodeHash)
+			assert.Equal(t, test.expRelease, browser.Release())
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b      string
+		expResult int
+	}{
+		{a: "1.10.0", b: "1.9.2", expResult: 1},
+		{a: "v1.2.0", b: "1.2.0", expResult: 0},
+		{a: "1.2", b: "1.2.1", expResult: -1},
+		{a: "1.2.0-rc1", b: "1.2.0-rc2", expResult: -1},
+	}
+	for _, test := range tests {
+		t.Run(test.a+" vs "+test.b, func(t *testing.T) {
+			result := compareVersions(test.a, test.b)
+
+			switch {
+			case test.expResult > 0:
+				assert.Positive(t, result)
+			case test.expResult < 0:
+				assert.Negative(t, result)
+			default:
+				assert.Zero(t, result)
+			}
+		})
+	}
+}
+
+// newMockReleaseRepository creates a client and filesystem of a repository, whose project root contains releases 1.2.0, 1.4.0 and 1.10.0, each with an app.bin file, and the current release pointer of given content.
+func newMockReleaseRepository(pointer string) (*mockReleaseClient, *mockLookupFs) {
+	client, fs := newMockRepository()
+	fs.nodes[releasesHash] = &mega.Node{}
+	fs.nodes[currentHash] = &mega.Node{}
+	client.paths[fs.nodes[releasesHash]] = rootNodeName + "/releases"
+	client.paths[fs.nodes[currentHash]] = rootNodeName + "/current"
+	for _, version := range []string{"1.2.0", "1.4.0", "1.10.0"} {
+		fs.nodes["release-"+version] = &mega.Node{}
+		fs.nodes["app-release-"+version] = &mega.Node{}
+		client.paths[fs.nodes["release-"+version]] = rootNodeName + "/releases/" + version
+	}
+	return &mockReleaseClient{
+		mockUploadClient: client,
+		contents:         map[*mega.Node]string{fs.nodes[currentHash]: pointer},
+	}, fs
+}
+
+func mockGetReleasesChildren(fs Fs, nodeHash string) ([]Node, error) {
+	switch nodeHash {
+	case expRootNodeHash:
+		return []Node{
+			&mockNode{name: "current", nodeType: fileType, hash: currentHash, size: 7},
+			&mockNode{name: "releases", nodeType: directoryType, hash: releasesHash},
+		}, nil
+	case releasesHash:
+		return []Node{
+			&mockNode{name: "1.2.0", nodeType: directoryType, hash: "release-1.2.0"},
+			&mockNode{name: "1.4.0", nodeType: directoryType, hash: "release-1.4.0"},
+			&mockNode{name: "1.10.0", nodeType: directoryType, hash: "release-1.10.0"},
+		}, nil
+	case "release-1.2.0", "release-1.4.0", "release-1.10.0":
+		return []Node{
+			&mockNode{name: "app.bin", nodeType: fileType, hash: "app-" + nodeHash, size: 1},
+		}, nil
+	}
+	return nil, nil
+}
+
+func (m *mockReleaseClient) DownloadFile(src *mega.Node, dstpath string, progress *chan int) error {
+	if progress != nil {
+		defer close(*progress)
+	}
+	return os.WriteFile(dstpath, []byte(m.contents[src]), 0666)
+}
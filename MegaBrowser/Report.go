@@ -0,0 +1,145 @@
+package megabrowser
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileStatus describes the outcome of updating a single file, see FileReport.
+type FileStatus string
+
+const (
+	// FileDownloaded means that the file was downloaded, from the source given in FileReport.Source.
+	FileDownloaded FileStatus = "downloaded"
+	// FileSkipped means that the local file was up to date, so it was not downloaded.
+	FileSkipped FileStatus = "skipped"
+	// FileFailed means that the file could not be downloaded or did not match the manifest, see FileReport.Error.
+	FileFailed FileStatus = "failed"
+	// FileDeleted means that the local file was removed. Browser operations never remove files of the project, the status is meant for callers pruning files, which are no longer part of it, see UpdateReport.Add.
+	FileDeleted FileStatus = "deleted"
+)
+
+// Sources of downloaded files, see FileReport.Source.
+const (
+	SourceMega    = "mega"
+	SourcePublic  = "public"
+	SourceBackend = "backend"
+	SourceMirror  = "mirror"
+	SourcePeer    = "peer"
+)
+
+// FileReport is the outcome of updating a single file.
+type FileReport struct {
+	// Path is the path of the file relative to the project root.
+	Path   string     `json:"path"`
+	Status FileStatus `json:"status"`
+	// Bytes is the size of the downloaded file, or of the remote file, if it was skipped. It is zero for failed files.
+	Bytes    int64    `json:"bytes"`
+	Duration Duration `json:"duration"`
+	// Source is where the file was downloaded from, one of the Source constants. It is empty for skipped files.
+	Source string `json:"source,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+/*
+UpdateReport lists the outcome of every file of an update operation, e.g. SyncReport. It can be serialized to JSON, e.g. for telemetry, and String summarizes it for humans.
+
+Files are listed in the order of the plan. If an operation stops at the first failure, files it did not start are not listed.
+*/
+type UpdateReport struct {
+	Started  time.Time `json:"started"`
+	Duration Duration  `json:"duration"`
+	// Release is the release the files were updated to, if the browser works on releases.
+	Release string       `json:"release,omitempty"`
+	Files   []FileReport `json:"files"`
+
+	mutex sync.Mutex
+}
+
+// Add appends given file outcomes to the report. It is safe for concurrent use.
+func (ur *UpdateReport) Add(files ...FileReport) {
+	ur.mutex.Lock()
+	defer ur.mutex.Unlock()
+	ur.Files = append(ur.Files, files...)
+}
+
+// Count returns the number of files of given status.
+func (ur *UpdateReport) Count(status FileStatus) int {
+	ur.mutex.Lock()
+	defer ur.mutex.Unlock()
+	count := 0
+	for _, file := range ur.Files {
+		if file.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// DownloadedBytes returns the size of every downloaded file together.
+func (ur *UpdateReport) DownloadedBytes() int64 {
+	ur.mutex.Lock()
+	defer ur.mutex.Unlock()
+	var bytes int64
+	for _, file := range ur.Files {
+		if file.Status == FileDownloaded {
+			bytes += file.Bytes
+		}
+	}
+	return bytes
+}
+
+// String summarizes the report in a single line.
+func (ur *UpdateReport) String() string {
+	return fmt.Sprintf("%d downloaded, %d skipped, %d failed, %d deleted, %d bytes in %s",
+		ur.Count(FileDownloaded), ur.Count(FileSkipped), ur.Count(FileFailed), ur.Count(FileDeleted), ur.DownloadedBytes(), time.Duration(ur.Duration))
+}
+
+// newReport starts a report of an update operation.
+func (mb *MegaBrowser) newReport() *UpdateReport {
+	return &UpdateReport{
+		Started: mb.now(),
+		Files:   []FileReport{},
+	}
+}
+
+// finishReport records the duration of the operation and the release the browser works on in the report.
+func (mb *MegaBrowser) finishReport(report *UpdateReport) {
+	report.Duration = Duration(mb.now().Sub(report.Started))
+	report.Release = mb.release
+}
+
+// fileReport reports the outcome of updating the file of given path, relative to the project root, at localPath from given source, which started at start and failed with err, if it is not nil.
+func (mb *MegaBrowser) fileReport(remotePath string, localPath string, source string, start time.Time, err error) FileReport {
+	report := FileReport{
+		Path:     remotePath,
+		Status:   FileDownloaded,
+		Duration: Duration(mb.now().Sub(start)),
+		Source:   source,
+	}
+	if err != nil {
+		report.Status = FileFailed
+		report.Error = err.Error()
+		return report
+	}
+	if info, err := os.Stat(localPath); err == nil {
+		report.Bytes = info.Size()
+	}
+	return report
+}
+
+// source returns where the browser downloads files from, one of the Source constants other than SourcePeer.
+func (mb *MegaBrowser) source() string {
+	switch {
+	case mb.mirror != "":
+		return SourceMirror
+	case mb.publicFolder != nil:
+		return SourcePublic
+	case mb.backend != nil:
+		return SourceBackend
+	default:
+		return SourceMega
+	}
+}
@@ -0,0 +1,132 @@
+package megabrowser
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncReport(t *testing.T) {
+	downloader := NewMegaDownloader()
+	downloader.progressOutput = nil
+	browser, err := New(
+		WithBackend(NewLocalBackend(writeLocalRepository(t))),
+		WithDownloader(downloader),
+	)
+	require.Nil(t, err)
+	require.Nil(t, browser.Initialize())
+	localDir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(localDir, "readme.txt"), []byte("readme"), 0666))
+
+	report, err := browser.SyncReport(context.Background(), localDir)
+
+	require.Nil(t, err)
+	require.NotNil(t, report)
+	files := map[string]FileReport{}
+	for _, file := range report.Files {
+		files[file.Path] = file
+	}
+	require.Len(t, files, 2)
+	downloaded := files[expDirName+"/"+expFileName]
+	assert.Equal(t, FileDownloaded, downloaded.Status)
+	assert.Equal(t, int64(7), downloaded.Bytes)
+	assert.Equal(t, SourceBackend, downloaded.Source)
+	assert.Empty(t, downloaded.Error)
+	assert.Equal(t, FileReport{Path: "readme.txt", Status: FileSkipped, Bytes: 6}, files["readme.txt"])
+	assert.Equal(t, 1, report.Count(FileDownloaded))
+	assert.Equal(t, 1, report.Count(FileSkipped))
+	assert.Equal(t, int64(7), report.DownloadedBytes())
+}
+
+func TestSyncReportListsFailedFiles(t *testing.T) {
+	repository := writeMirror(t, "1.0.0", map[string]string{"app.bin": "binary"})
+	require.Nil(t, os.WriteFile(filepath.Join(repository, "app.bin"), []byte("tamper"), 0666))
+	downloader := NewMegaDownloader()
+	downloader.progressOutput = nil
+	browser, err := New(
+		WithBackend(NewLocalBackend(repository)),
+		WithDownloader(downloader),
+		WithManifest(mirrorManifest),
+	)
+	require.Nil(t, err)
+	require.Nil(t, browser.Initialize())
+
+	report, err := browser.SyncReport(context.Background(), t.TempDir())
+
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	require.NotNil(t, report)
+	var failed []FileReport
+	for _, file := range report.Files {
+		if file.Status == FileFailed {
+			failed = append(failed, file)
+		}
+	}
+	require.Len(t, failed, 1)
+	assert.Equal(t, "app.bin", failed[0].Path)
+	assert.Equal(t, int64(0), failed[0].Bytes)
+	assert.Contains(t, failed[0].Error, ErrChecksumMismatch.Error())
+}
+
+func TestUpdateFileFromPathReport(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	downloader := NewMegaDownloader()
+	downloader.progressOutput = nil
+	browser, err := New(
+		WithBackend(NewLocalBackend(writeLocalRepository(t))),
+		WithDownloader(downloader),
+		WithClock(func() time.Time {
+			now = now.Add(time.Second)
+			return now
+		}),
+	)
+	require.Nil(t, err)
+	require.Nil(t, browser.Initialize())
+	localPath := filepath.Join(t.TempDir(), "readme.txt")
+
+	report, err := browser.UpdateFileFromPathReport(context.Background(), "readme.txt", localPath)
+
+	require.Nil(t, err)
+	require.Len(t, report.Files, 1)
+	assert.Equal(t, "readme.txt", report.Files[0].Path)
+	assert.Equal(t, FileDownloaded, report.Files[0].Status)
+	assert.Equal(t, int64(6), report.Files[0].Bytes)
+	assert.Equal(t, Duration(time.Second), report.Files[0].Duration)
+	assert.Equal(t, Duration(2*time.Second), report.Duration)
+	assert.Equal(t, "1 downloaded, 0 skipped, 0 failed, 0 deleted, 6 bytes in 2s", report.String())
+}
+
+func TestUpdateReportJSON(t *testing.T) {
+	report := &UpdateReport{
+		Started:  time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Duration: Duration(1500 * time.Millisecond),
+		Release:  "1.0.0",
+	}
+	report.Add(
+		FileReport{Path: "app.bin", Status: FileDownloaded, Bytes: 6, Duration: Duration(time.Second), Source: SourceMega},
+		FileReport{Path: "old.bin", Status: FileFailed, Duration: Duration(time.Millisecond), Source: SourcePeer, Error: "mock error"},
+	)
+
+	content, err := json.Marshal(report)
+
+	require.Nil(t, err)
+	assert.JSONEq(t, `{
+		"started": "2024-05-01T12:00:00Z",
+		"duration": "1.5s",
+		"release": "1.0.0",
+		"files": [
+			{"path": "app.bin", "status": "downloaded", "bytes": 6, "duration": "1s", "source": "mega"},
+			{"path": "old.bin", "status": "failed", "bytes": 0, "duration": "1ms", "source": "peer", "error": "mock error"}
+		]
+	}`, string(content))
+	var decoded UpdateReport
+	require.Nil(t, json.Unmarshal(content, &decoded))
+	assert.Equal(t, report.Files, decoded.Files)
+	assert.Equal(t, "0 downloaded, 0 skipped, 1 failed, 0 deleted, 0 bytes in 0s", (&UpdateReport{Files: decoded.Files[1:]}).String())
+}
@@ -0,0 +1,279 @@
+package megabrowser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+var errReleasesDisabled = fmt.Errorf("browser does not work on releases, see WithReleases")
+
+// ErrNoPreviousRelease is returned by Rollback, if no release was synchronized into the local directory before the current one.
+var ErrNoPreviousRelease = errors.New("no previous release to roll back to")
+
+const (
+	// releaseHistoryFile is the name of the file inside a synchronized directory, listing releases synchronized into it.
+	releaseHistoryFile = ".megabrowser-releases.json"
+	// releaseHistoryLimit is the maximum number of releases kept in the release history.
+	releaseHistoryLimit = 10
+)
+
+// releaseHistory lists releases synchronized into a local directory, from the oldest to the current one. Pinned is the release the directory was rolled back to, which it keeps instead of following the current release, until it is unpinned.
+type releaseHistory struct {
+	Releases []string `json:"releases"`
+	Pinned   string   `json:"pinned,omitempty"`
+}
+
+/*
+ListReleases returns versions of every release published in the Mega repository, from the newest to the oldest.
+
+Returns an error if:
+
+	the browser does not work on releases or is not initialized
+	an error occured while getting children of a node
+*/
+func (mb *MegaBrowser) ListReleases() ([]string, error) {
+	if mb.releasesHash == "" {
+		return nil, errReleasesDisabled
+	}
+	nodes, err := mb.listChildren(mb.releasesHash)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, node := range nodes {
+		if node.GetType() == directoryType {
+			versions = append(versions, node.GetName())
+		}
+	}
+	sortVersionsDescending(versions)
+	return versions, nil
+}
+
+/*
+UseRelease makes the browser work on the release of given version instead of the current one, until it is initialized again.
+
+Returns an error if:
+
+	the browser does not work on releases or is not initialized
+	version is not a valid directory name
+	an error occured while getting children of a node
+	release of given version does not exist
+	could not read the manifest of the release
+*/
+func (mb *MegaBrowser) UseRelease(version string) error {
+	if mb.releasesHash == "" {
+		return errReleasesDisabled
+	}
+	err := validateReleaseName(version)
+	if err != nil {
+		return err
+	}
+
+	nodes, err := mb.listChildren(mb.releasesHash)
+	if err != nil {
+		return err
+	}
+	releaseHash, err := getNodeHashOfExpectedDirectory(version, &nodes)
+	if err != nil {
+		return err
+	}
+	mb.rootNodeHash = releaseHash
+	mb.release = version
+	mb.pinned = false
+	return mb.loadManifest()
+}
+
+/*
+Rollback restores files of localDir to the release synchronized into it before the current one, which becomes the current release of the browser. Every file of that release is downloaded again, because files of equal size may still differ between releases. Files added by the rolled back release are left untouched.
+
+Releases removed from the Mega repository since they were synchronized are skipped, so localDir is restored to the newest earlier release, which still exists. Publishers removing old releases should keep at least two of them, see WithKeepReleases.
+
+The release is then pinned in localDir, so that synchronizing localDir keeps it, instead of reinstalling the current release, until localDir is unpinned, see Unpin.
+
+Returns an error if:
+
+	the browser does not work on releases or is not initialized
+	failed to read the release history of localDir
+	no release synchronized into localDir before the current one still exists in the Mega repository, see ErrNoPreviousRelease
+	an error occured while getting children of a node
+	failed to download any of the files
+	failed to save the release history
+*/
+func (mb *MegaBrowser) Rollback(localDir string) ([]SyncEntry, error) {
+	return mb.rollback(localDir, mb.newReport())
+}
+
+// RollbackReport rolls localDir back like Rollback does and returns the outcome of every file, see UpdateReport. The report is returned also if the rollback fails, listing the files processed until then.
+func (mb *MegaBrowser) RollbackReport(localDir string) (*UpdateReport, error) {
+	report := mb.newReport()
+	_, err := mb.rollback(localDir, report)
+	return report, err
+}
+
+// rollback rolls localDir back to the previous release, adding the outcome of every file to report, and returns the plan that was executed.
+func (mb *MegaBrowser) rollback(localDir string, report *UpdateReport) ([]SyncEntry, error) {
+	defer mb.finishReport(report)
+	if mb.releasesHash == "" {
+		return nil, errReleasesDisabled
+	}
+	history, err := readReleaseHistory(localDir)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := mb.previousRelease(history)
+	if err != nil {
+		return nil, err
+	}
+
+	err = mb.UseRelease(history.Releases[previous])
+	if err != nil {
+		return nil, fmt.Errorf("failed to roll back to release %s: %w", history.Releases[previous], err)
+	}
+	mb.pinned = true
+	entries, err := mb.plan(localDir)
+	if err != nil {
+		return nil, err
+	}
+	err = mb.download(context.Background(), localDir, entries, true, report)
+	if err != nil {
+		return entries, err
+	}
+	mb.sharePeerFiles(localDir)
+
+	history.Releases = history.Releases[:previous+1]
+	history.Pinned = mb.release
+	return entries, history.write(localDir)
+}
+
+// previousRelease returns the index of the newest release in history before the last one, which still exists in the Mega repository. Returns ErrNoPreviousRelease, if there is none.
+func (mb *MegaBrowser) previousRelease(history releaseHistory) (int, error) {
+	if len(history.Releases) < 2 {
+		return 0, ErrNoPreviousRelease
+	}
+	versions, err := mb.ListReleases()
+	if err != nil {
+		return 0, err
+	}
+	for i := len(history.Releases) - 2; i >= 0; i-- {
+		if slices.Contains(versions, history.Releases[i]) {
+			return i, nil
+		}
+		mb.logger.Warn("release no longer exists, skipping it", "release", history.Releases[i])
+	}
+	return 0, ErrNoPreviousRelease
+}
+
+/*
+Unpin makes localDir follow the current release again, after it was pinned to an earlier release by Rollback, so that the next synchronization of localDir installs the current release. The browser works on the current release again, if it worked on the pinned one. Does nothing, if localDir is not pinned.
+
+Returns an error if:
+
+	the browser does not work on releases or is not initialized
+	failed to read or save the release history of localDir
+	could not read the current release pointer or find the release directory
+	could not read the manifest of the release
+*/
+func (mb *MegaBrowser) Unpin(localDir string) error {
+	if mb.releasesHash == "" {
+		return errReleasesDisabled
+	}
+	history, err := readReleaseHistory(localDir)
+	if err != nil {
+		return err
+	}
+	if history.Pinned != "" {
+		history.Pinned = ""
+		err = history.write(localDir)
+		if err != nil {
+			return err
+		}
+	}
+	if !mb.pinned {
+		return nil
+	}
+	err = mb.resolveRelease("")
+	if err != nil {
+		return err
+	}
+	return mb.loadManifest()
+}
+
+// followPinnedRelease makes the browser work on the release pinned in localDir, if there is one, or on the current release, if it worked on a release pinned in another directory. Does nothing, if the browser does not work on releases or works on a mirror.
+func (mb *MegaBrowser) followPinnedRelease(localDir string) error {
+	if mb.releasesHash == "" || mb.projectHash == "" || mb.mirror != "" {
+		return nil
+	}
+	history, err := readReleaseHistory(localDir)
+	if err != nil {
+		return err
+	}
+	switch {
+	case history.Pinned != "" && history.Pinned != mb.release:
+		err = mb.resolveRelease(history.Pinned)
+	case history.Pinned == "" && mb.pinned:
+		err = mb.resolveRelease("")
+	default:
+		mb.pinned = history.Pinned != ""
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return mb.loadManifest()
+}
+
+// recordRelease appends the current release to the release history of localDir, unless it is already the last one there. Does nothing, if the browser does not work on releases.
+func (mb *MegaBrowser) recordRelease(localDir string) error {
+	if mb.release == "" {
+		return nil
+	}
+	history, err := readReleaseHistory(localDir)
+	if err != nil {
+		return err
+	}
+	if len(history.Releases) > 0 && history.Releases[len(history.Releases)-1] == mb.release {
+		return nil
+	}
+
+	history.Releases = append(history.Releases, mb.release)
+	if len(history.Releases) > releaseHistoryLimit {
+		history.Releases = history.Releases[len(history.Releases)-releaseHistoryLimit:]
+	}
+	return history.write(localDir)
+}
+
+// readReleaseHistory reads the release history of localDir. Returns an empty history, if the history file does not exist.
+func readReleaseHistory(localDir string) (releaseHistory, error) {
+	var history releaseHistory
+	content, err := os.ReadFile(filepath.Join(localDir, releaseHistoryFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return history, nil
+		}
+		return history, err
+	}
+	err = json.Unmarshal(content, &history)
+	if err != nil {
+		return history, fmt.Errorf("failed to parse release history: %w", err)
+	}
+	return history, nil
+}
+
+// write saves the release history in localDir, creating the directory if it does not exist.
+func (rh releaseHistory) write(localDir string) error {
+	content, err := json.Marshal(rh)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(localDir, 0777)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(localDir, releaseHistoryFile), content, 0666)
+}
@@ -0,0 +1,238 @@
+package megabrowser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListReleases(t *testing.T) {
+	browser, _ := newInitializedReleaseBrowser(t)
+
+	versions, err := browser.ListReleases()
+
+	require.Nil(t, err)
+	assert.Equal(t, []string{"1.10.0", "1.4.0", "1.2.0"}, versions)
+}
+
+func TestReleaseOperationsFailWithoutReleases(t *testing.T) {
+	browser, err := New()
+	require.Nil(t, err)
+
+	_, err = browser.ListReleases()
+	assert.Equal(t, errReleasesDisabled, err)
+	err = browser.UseRelease("1.4.0")
+	assert.Equal(t, errReleasesDisabled, err)
+	_, err = browser.Rollback(t.TempDir())
+	assert.Equal(t, errReleasesDisabled, err)
+}
+
+func TestSyncRecordsRelease(t *testing.T) {
+	tests := []struct {
+		name       string
+		history    []string
+		expHistory []string
+	}{
+		{
+			name:       "should start release history, if there is none",
+			history:    nil,
+			expHistory: []string{"1.10.0"},
+		},
+		{
+			name:       "should append release to history, if it differs from the last one",
+			history:    []string{"1.2.0", "1.4.0"},
+			expHistory: []string{"1.2.0", "1.4.0", "1.10.0"},
+		},
+		{
+			name:       "should not duplicate release, if it was already synchronized",
+			history:    []string{"1.4.0", "1.10.0"},
+			expHistory: []string{"1.4.0", "1.10.0"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			localDir := t.TempDir()
+			if test.history != nil {
+				require.Nil(t, releaseHistory{Releases: test.history}.write(localDir))
+			}
+			browser, _ := newInitializedReleaseBrowser(t)
+
+			_, err := browser.Sync(localDir)
+
+			require.Nil(t, err)
+			history, err := readReleaseHistory(localDir)
+			require.Nil(t, err)
+			assert.Equal(t, test.expHistory, history.Releases)
+		})
+	}
+}
+
+func TestRollback(t *testing.T) {
+	localDir := t.TempDir()
+	require.Nil(t, releaseHistory{Releases: []string{"1.4.0", "1.10.0"}}.write(localDir))
+	require.Nil(t, os.WriteFile(filepath.Join(localDir, "app.bin"), []byte("x"), 0666))
+	browser, downloader := newInitializedReleaseBrowser(t)
+
+	entries, err := browser.Rollback(localDir)
+
+	require.Nil(t, err)
+	assert.Equal(t, "1.4.0", browser.Release())
+	assert.Equal(t, "release-1.4.0", browser.rootNodeHash)
+	require.Len(t, entries, 1)
+	assert.Equal(t, SyncStatusUpToDate, entries[0].Status)
+	assert.Equal(t, []string{filepath.Join(localDir, "app.bin")}, downloader.downloadedPaths)
+	history, err := readReleaseHistory(localDir)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"1.4.0"}, history.Releases)
+
+	_, err = browser.Rollback(localDir)
+
+	assert.Equal(t, ErrNoPreviousRelease, err)
+}
+
+func TestRollbackSkipsRemovedReleases(t *testing.T) {
+	localDir := t.TempDir()
+	require.Nil(t, releaseHistory{Releases: []string{"1.2.0", "1.3.0", "1.10.0"}}.write(localDir))
+	browser, _ := newInitializedReleaseBrowser(t)
+
+	_, err := browser.Rollback(localDir)
+
+	require.Nil(t, err)
+	assert.Equal(t, "1.2.0", browser.Release())
+	history, err := readReleaseHistory(localDir)
+	require.Nil(t, err)
+	assert.Equal(t, releaseHistory{Releases: []string{"1.2.0"}, Pinned: "1.2.0"}, history)
+}
+
+func TestRollbackFailsIfPreviousReleasesWereRemoved(t *testing.T) {
+	localDir := t.TempDir()
+	require.Nil(t, releaseHistory{Releases: []string{"1.3.0", "1.10.0"}}.write(localDir))
+	browser, downloader := newInitializedReleaseBrowser(t)
+
+	_, err := browser.Rollback(localDir)
+
+	assert.Equal(t, ErrNoPreviousRelease, err)
+	assert.Equal(t, "1.10.0", browser.Release())
+	assert.Empty(t, downloader.downloadedPaths)
+}
+
+func TestSyncAfterRollbackKeepsPinnedRelease(t *testing.T) {
+	localDir := t.TempDir()
+	require.Nil(t, releaseHistory{Releases: []string{"1.4.0", "1.10.0"}}.write(localDir))
+	require.Nil(t, os.WriteFile(filepath.Join(localDir, "app.bin"), []byte("x"), 0666))
+	browser, downloader := newInitializedReleaseBrowser(t)
+	_, err := browser.Rollback(localDir)
+	require.Nil(t, err)
+	require.Nil(t, browser.Initialize())
+	require.Equal(t, "1.10.0", browser.Release())
+	downloader.downloadedPaths = nil
+
+	_, err = browser.Sync(localDir)
+
+	require.Nil(t, err)
+	assert.Equal(t, "1.4.0", browser.Release())
+	assert.Equal(t, "release-1.4.0", browser.rootNodeHash)
+	assert.Empty(t, downloader.downloadedPaths)
+	history, err := readReleaseHistory(localDir)
+	require.Nil(t, err)
+	assert.Equal(t, releaseHistory{Releases: []string{"1.4.0"}, Pinned: "1.4.0"}, history)
+}
+
+func TestSyncOfDirectoryWithoutPinFollowsCurrentRelease(t *testing.T) {
+	pinnedDir := t.TempDir()
+	require.Nil(t, releaseHistory{Releases: []string{"1.4.0"}, Pinned: "1.4.0"}.write(pinnedDir))
+	browser, _ := newInitializedReleaseBrowser(t, WithReleaseDir(pinnedDir))
+	require.Equal(t, "1.4.0", browser.Release())
+
+	_, err := browser.Sync(t.TempDir())
+
+	require.Nil(t, err)
+	assert.Equal(t, "1.10.0", browser.Release())
+	assert.Equal(t, "release-1.10.0", browser.rootNodeHash)
+}
+
+func TestInitializeWithReleaseDirHonorsPinnedRelease(t *testing.T) {
+	tests := []struct {
+		name       string
+		history    releaseHistory
+		expRelease string
+	}{
+		{
+			name:       "should work on pinned release, if release directory is pinned",
+			history:    releaseHistory{Releases: []string{"1.4.0"}, Pinned: "1.4.0"},
+			expRelease: "1.4.0",
+		},
+		{
+			name:       "should work on current release, if release directory is not pinned",
+			history:    releaseHistory{Releases: []string{"1.4.0"}},
+			expRelease: "1.10.0",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			localDir := t.TempDir()
+			require.Nil(t, test.history.write(localDir))
+
+			browser, _ := newInitializedReleaseBrowser(t, WithReleaseDir(localDir))
+
+			assert.Equal(t, test.expRelease, browser.Release())
+			assert.Equal(t, "release-"+test.expRelease, browser.rootNodeHash)
+		})
+	}
+}
+
+func TestUnpin(t *testing.T) {
+	localDir := t.TempDir()
+	require.Nil(t, releaseHistory{Releases: []string{"1.4.0", "1.10.0"}}.write(localDir))
+	browser, downloader := newInitializedReleaseBrowser(t)
+	_, err := browser.Rollback(localDir)
+	require.Nil(t, err)
+	downloader.downloadedPaths = nil
+
+	err = browser.Unpin(localDir)
+
+	require.Nil(t, err)
+	assert.Equal(t, "1.10.0", browser.Release())
+	history, err := readReleaseHistory(localDir)
+	require.Nil(t, err)
+	assert.Equal(t, releaseHistory{Releases: []string{"1.4.0"}}, history)
+
+	_, err = browser.Sync(localDir)
+
+	require.Nil(t, err)
+	assert.Equal(t, "1.10.0", browser.Release())
+	history, err = readReleaseHistory(localDir)
+	require.Nil(t, err)
+	assert.Equal(t, releaseHistory{Releases: []string{"1.4.0", "1.10.0"}}, history)
+}
+
+func TestUnpinFailsWithoutReleases(t *testing.T) {
+	browser, err := New()
+	require.Nil(t, err)
+
+	err = browser.Unpin(t.TempDir())
+
+	assert.Equal(t, errReleasesDisabled, err)
+}
+
+// newInitializedReleaseBrowser creates a browser with given options working on the releases of the mock release repository, whose current release is 1.10.0.
+func newInitializedReleaseBrowser(t *testing.T, opts ...Option) (*MegaBrowser, *mockDownloader) {
+	downloader := &mockDownloader{}
+	client, fs := newMockReleaseRepository("1.10.0\n")
+	browser, err := New(append([]Option{
+		WithCredentials(login, pass),
+		WithRootNode(rootNodeName),
+		WithClient(client),
+		WithFs(fs),
+		WithDownloader(downloader),
+		WithRootNodeHashFunc(mockGetRootNodeHash),
+		WithChildrenFunc(mockGetReleasesChildren),
+		WithReleases(),
+	}, opts...)...)
+	require.Nil(t, err)
+	require.Nil(t, browser.Initialize())
+	return browser, downloader
+}
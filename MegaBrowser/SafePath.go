@@ -0,0 +1,177 @@
+package megabrowser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ErrUnsafePath is returned, if a remote node name could escape the local directory or is not a valid file name, or if a download would write outside the directory it is confined to, e.g. through a symbolic link, see MegaDownloader.SetRootDir.
+var ErrUnsafePath = errors.New("unsafe path")
+
+// reservedNames are device names, which cannot be used as file names on Windows, regardless of their extension.
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true, "CONIN$": true, "CONOUT$": true,
+	"COM0": true, "COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"COM¹": true, "COM²": true, "COM³": true,
+	"LPT0": true, "LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+	"LPT¹": true, "LPT²": true, "LPT³": true,
+}
+
+// windowsReservedChars cannot be used in file names on Windows, in addition to control characters.
+const windowsReservedChars = `<>:"/\|?*`
+
+// rootDirKey is the context key of the directory, which downloads made with the context are confined to, see withRootDir.
+type rootDirKey struct{}
+
+// confiner is a Downloader checking that a local path is inside the directories its downloads are confined to, like MegaDownloader does.
+type confiner interface {
+	confine(path string) error
+}
+
+// SetRootDir confines every download to given directory, so that a download fails with ErrUnsafePath, if its local path is outside the directory or any existing part of it is a symbolic link pointing outside. An empty directory disables this confinement, but files synchronized by the browser are always confined to the directory they are synchronized into.
+func (md *MegaDownloader) SetRootDir(dir string) {
+	md.rootDir = dir
+}
+
+// confine checks that path is inside the root directory of the downloader and the directory of its context, if they are set, see confinePath.
+func (md *MegaDownloader) confine(path string) error {
+	for _, root := range []string{md.rootDir, contextRootDir(md.ctx)} {
+		if root == "" {
+			continue
+		}
+		err := confinePath(root, path)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withRootDir returns a context confining downloads made with it to dir, in addition to the root directory of the downloader.
+func withRootDir(ctx context.Context, dir string) context.Context {
+	return context.WithValue(ctx, rootDirKey{}, dir)
+}
+
+// contextRootDir returns the directory set with withRootDir, or an empty string, if there is none.
+func contextRootDir(ctx context.Context) string {
+	dir, _ := ctx.Value(rootDirKey{}).(string)
+	return dir
+}
+
+// confine checks that path is inside the directories, which downloads made with ctx are confined to. The browser checks paths it writes to itself, e.g. files fetched from peers, the same way its downloader does.
+func (mb *MegaBrowser) confine(ctx context.Context, path string) error {
+	if downloader, ok := mb.contextDownloader(ctx).(confiner); ok {
+		return downloader.confine(path)
+	}
+	if root := contextRootDir(ctx); root != "" {
+		return confinePath(root, path)
+	}
+	return nil
+}
+
+// validateNodeName returns ErrUnsafePath, if a remote node name cannot be used as a local file name on the current platform, see validateNodeNameFor.
+func validateNodeName(name string) error {
+	return validateNodeNameFor(runtime.GOOS, name)
+}
+
+// validatePathElements returns ErrUnsafePath, if any element of a remote path, split at separators, is not a valid node name, see validateNodeName, so that the path cannot escape the local directory it is resolved into.
+func validatePathElements(elements []string) error {
+	for _, element := range elements {
+		err := validateNodeName(element)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+validateNodeNameFor returns ErrUnsafePath, if a remote node name cannot be used as a local file name on the platform of given GOOS.
+
+On every platform a name must not be empty, contain a slash or a NUL character, or be a relative path element. On Windows it must not contain any of windowsReservedChars or control characters, end with a dot or a space, or be a reserved device name, with or without an extension.
+*/
+func validateNodeNameFor(goos string, name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\x00") {
+		return fmt.Errorf("%w: invalid node name %q", ErrUnsafePath, name)
+	}
+	if goos != "windows" {
+		return nil
+	}
+	if strings.ContainsAny(name, windowsReservedChars) || strings.IndexFunc(name, isControl) >= 0 || strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+		return fmt.Errorf("%w: invalid node name %q", ErrUnsafePath, name)
+	}
+	base, _, _ := strings.Cut(name, ".")
+	if reservedNames[strings.ToUpper(strings.TrimRight(base, " "))] {
+		return fmt.Errorf("%w: reserved node name %q", ErrUnsafePath, name)
+	}
+	return nil
+}
+
+// isControl returns true for control characters, which cannot be used in file names on Windows.
+func isControl(r rune) bool {
+	return r < 0x20
+}
+
+/*
+confinePath checks that path is inside rootDir, and that no existing part of it below rootDir is a symbolic link pointing outside rootDir. Parts, which do not exist yet, are created as directories by the downloader, so they cannot be links. Both paths are relative to the working directory, unless they are absolute.
+
+A link may still be created between the check and the write, so writers check the path once more right before creating the file, after its directory was created.
+
+Returns an error if:
+
+	path is outside rootDir, see ErrUnsafePath
+	a symbolic link below rootDir points outside, see ErrUnsafePath
+	failed to resolve the paths or a symbolic link
+*/
+func confinePath(rootDir string, path string) error {
+	root, err := filepath.Abs(rootDir)
+	if err != nil {
+		return err
+	}
+	target, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(root, target)
+	if err != nil || !isLocalPath(rel) {
+		return fmt.Errorf("%w: %s is outside %s", ErrUnsafePath, path, rootDir)
+	}
+
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+	current := root
+	for _, element := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, element)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		resolved, err := filepath.EvalSymlinks(current)
+		if err != nil {
+			return fmt.Errorf("%w: %s is a broken symbolic link", ErrUnsafePath, current)
+		}
+		resolvedRel, err := filepath.Rel(resolvedRoot, resolved)
+		if err != nil || !isLocalPath(resolvedRel) {
+			return fmt.Errorf("%w: %s links outside %s", ErrUnsafePath, current, rootDir)
+		}
+	}
+	return nil
+}
+
+// isLocalPath returns true, if a relative path, returned by filepath.Rel, does not lead out of its base directory.
+func isLocalPath(rel string) bool {
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
@@ -0,0 +1,264 @@
+package megabrowser
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateNodeName(t *testing.T) {
+	tests := []struct {
+		name     string
+		goos     string
+		nodeName string
+		expErr   bool
+	}{
+		{name: "should accept a regular file name", goos: "linux", nodeName: "app.bin", expErr: false},
+		{name: "should accept a name starting with dots", goos: "linux", nodeName: "..hidden", expErr: false},
+		{name: "should accept a reserved device name on other platforms than Windows", goos: "linux", nodeName: "aux.c", expErr: false},
+		{name: "should accept characters reserved on Windows on other platforms", goos: "darwin", nodeName: `a\b:c?.`, expErr: false},
+		{name: "should reject an empty name", goos: "linux", nodeName: "", expErr: true},
+		{name: "should reject a current directory name", goos: "linux", nodeName: ".", expErr: true},
+		{name: "should reject a parent directory name", goos: "linux", nodeName: "..", expErr: true},
+		{name: "should reject a name containing a slash", goos: "linux", nodeName: "../etc", expErr: true},
+		{name: "should reject a name containing a NUL character", goos: "linux", nodeName: "app\x00.bin", expErr: true},
+		{name: "should accept a regular file name on Windows", goos: "windows", nodeName: "app.bin", expErr: false},
+		{name: "should accept a name containing a reserved name on Windows", goos: "windows", nodeName: "console.log", expErr: false},
+		{name: "should reject a name containing a backslash on Windows", goos: "windows", nodeName: "..\\windows", expErr: true},
+		{name: "should reject a name containing a colon on Windows", goos: "windows", nodeName: "file.txt:stream", expErr: true},
+		{name: "should reject a name containing a reserved character on Windows", goos: "windows", nodeName: "what?.txt", expErr: true},
+		{name: "should reject a name containing a control character on Windows", goos: "windows", nodeName: "app\t.bin", expErr: true},
+		{name: "should reject a name ending with a dot on Windows", goos: "windows", nodeName: "app.", expErr: true},
+		{name: "should reject a name ending with a space on Windows", goos: "windows", nodeName: "app ", expErr: true},
+		{name: "should reject a reserved device name on Windows", goos: "windows", nodeName: "CON", expErr: true},
+		{name: "should reject a reserved device name with an extension on Windows", goos: "windows", nodeName: "nul.txt", expErr: true},
+		{name: "should reject a reserved device name of any case on Windows", goos: "windows", nodeName: "Com1", expErr: true},
+		{name: "should reject a reserved device name followed by spaces on Windows", goos: "windows", nodeName: "aux .c", expErr: true},
+		{name: "should reject a reserved device name with a superscript digit on Windows", goos: "windows", nodeName: "lpt¹", expErr: true},
+		{name: "should reject a console device name on Windows", goos: "windows", nodeName: "CONIN$", expErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateNodeNameFor(test.goos, test.nodeName)
+
+			if test.expErr {
+				assert.ErrorIs(t, err, ErrUnsafePath)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestConfinePath(t *testing.T) {
+	outside := t.TempDir()
+	root := t.TempDir()
+	require.Nil(t, os.Mkdir(filepath.Join(root, "dir"), 0777))
+	require.Nil(t, os.Symlink(outside, filepath.Join(root, "escape")))
+	require.Nil(t, os.Symlink(filepath.Join(root, "dir"), filepath.Join(root, "inside")))
+	require.Nil(t, os.Symlink(filepath.Join(outside, "file.txt"), filepath.Join(root, "file.txt")))
+	require.Nil(t, os.Symlink(filepath.Join(root, "missing"), filepath.Join(root, "broken")))
+	tests := []struct {
+		name   string
+		path   string
+		expErr bool
+	}{
+		{name: "should accept a file inside the root", path: filepath.Join(root, "dir", "file.txt"), expErr: false},
+		{name: "should accept a file in directories not created yet", path: filepath.Join(root, "new", "dir", "file.txt"), expErr: false},
+		{name: "should accept a link pointing inside the root", path: filepath.Join(root, "inside", "file.txt"), expErr: false},
+		{name: "should reject a path leading out of the root", path: filepath.Join(root, "..", "file.txt"), expErr: true},
+		{name: "should reject a path outside the root", path: filepath.Join(outside, "file.txt"), expErr: true},
+		{name: "should reject a directory link pointing outside the root", path: filepath.Join(root, "escape", "file.txt"), expErr: true},
+		{name: "should reject a file link pointing outside the root", path: filepath.Join(root, "file.txt"), expErr: true},
+		{name: "should reject a broken link", path: filepath.Join(root, "broken", "file.txt"), expErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := confinePath(root, test.path)
+
+			if test.expErr {
+				assert.ErrorIs(t, err, ErrUnsafePath)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestDownloadFileFailsIfPathIsUnsafe(t *testing.T) {
+	outside := t.TempDir()
+	root := t.TempDir()
+	require.Nil(t, os.Symlink(outside, filepath.Join(root, "escape")))
+	victim := filepath.Join(outside, "victim.txt")
+	require.Nil(t, os.WriteFile(victim, []byte("victim"), 0666))
+	tests := []struct {
+		name string
+		path string
+	}{
+		{
+			name: "should fail, if path leads out of the root directory",
+			path: filepath.Join(root, "..", filepath.Base(outside), "victim.txt"),
+		},
+		{
+			name: "should fail, if path goes through a link pointing out of the root directory",
+			path: filepath.Join(root, "escape", "victim.txt"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &mockClient{}
+			downloader := NewMegaDownloader()
+			downloader.getNodeSize = mockGetNodeSize
+			downloader.getNodeHash = mockGetNodeHash
+			downloader.progressOutput = nil
+			downloader.SetRootDir(root)
+
+			err := downloadFile(downloader, client, test.path)
+
+			assert.ErrorIs(t, err, ErrUnsafePath)
+			assert.FileExists(t, victim)
+		})
+	}
+}
+
+func TestDownloadFileFailsIfDirectoryIsReplacedWithLink(t *testing.T) {
+	outside := t.TempDir()
+	root := t.TempDir()
+	client := &mockClient{}
+	downloader := NewMegaDownloader()
+	downloader.getNodeSize = mockGetNodeSize
+	downloader.getNodeHash = mockGetNodeHash
+	downloader.progressOutput = nil
+	downloader.mkDir = func(path string, perm os.FileMode) error {
+		return os.Symlink(outside, path)
+	}
+	downloader.SetRootDir(root)
+
+	err := downloadFile(downloader, client, filepath.Join(root, "dir", "file.txt"))
+
+	assert.ErrorIs(t, err, ErrUnsafePath)
+}
+
+func TestDownloadFileFailsIfPathIsOutsideContextRootDir(t *testing.T) {
+	root := t.TempDir()
+	client := &mockClient{}
+	downloader := NewMegaDownloader()
+	downloader.getNodeSize = mockGetNodeSize
+	downloader.getNodeHash = mockGetNodeHash
+	downloader.progressOutput = nil
+
+	err := downloadFile(downloader.WithContext(withRootDir(context.Background(), root)), client, filepath.Join(t.TempDir(), "file.txt"))
+
+	assert.ErrorIs(t, err, ErrUnsafePath)
+}
+
+func TestDownloadFileToRootDir(t *testing.T) {
+	root := t.TempDir()
+	client := &mockClient{}
+	downloader := NewMegaDownloader()
+	downloader.getNodeSize = mockGetNodeSize
+	downloader.getNodeHash = mockGetNodeHash
+	downloader.progressOutput = nil
+	downloader.SetRootDir(root)
+
+	err := downloadFile(downloader, client, filepath.Join(root, "dir", "file.txt"))
+
+	assert.Nil(t, err)
+	assert.DirExists(t, filepath.Join(root, "dir"))
+}
+
+func TestPlanFailsIfNodeNameIsUnsafe(t *testing.T) {
+	browser, err := New(
+		WithFs(&mockFs{}),
+		WithChildrenFunc(func(fs Fs, nodeHash string) ([]Node, error) {
+			return []Node{&mockNode{name: "../../etc/passwd", nodeType: fileType, hash: expFileHash}}, nil
+		}),
+	)
+	require.Nil(t, err)
+	browser.rootNodeHash = expRootNodeHash
+
+	entries, err := browser.Plan(t.TempDir())
+
+	assert.ErrorIs(t, err, ErrUnsafePath)
+	assert.Nil(t, entries)
+}
+
+func TestPathResolutionFailsIfPathIsUnsafe(t *testing.T) {
+	browser, err := New(
+		WithFs(&mockFs{}),
+		WithChildrenFunc(func(fs Fs, nodeHash string) ([]Node, error) {
+			return []Node{&mockNode{name: "..", nodeType: directoryType, hash: expDirHash}}, nil
+		}),
+	)
+	require.Nil(t, err)
+	browser.rootNodeHash = expRootNodeHash
+	tests := []struct {
+		name    string
+		resolve func() error
+	}{
+		{
+			name: "should fail stat, if path leads out of the project root",
+			resolve: func() error {
+				_, err := browser.Stat("../etc/passwd")
+				return err
+			},
+		},
+		{
+			name: "should fail listing a directory, if path leads out of the project root",
+			resolve: func() error {
+				_, err := browser.ListDirectory("dir/../..")
+				return err
+			},
+		},
+		{
+			name: "should fail listing a directory, if it has a child of an unsafe name",
+			resolve: func() error {
+				_, err := browser.ListDirectory("")
+				return err
+			},
+		},
+		{
+			name: "should fail updating a file, if path leads out of the project root",
+			resolve: func() error {
+				return browser.UpdateFileFromPath("../etc/passwd", filepath.Join(t.TempDir(), "passwd"))
+			},
+		},
+		{
+			name: "should fail listing versions, if path leads out of the project root",
+			resolve: func() error {
+				_, err := browser.ListVersions("../etc/passwd")
+				return err
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.resolve()
+
+			assert.ErrorIs(t, err, ErrUnsafePath)
+		})
+	}
+}
+
+func TestSyncFailsIfLocalDirectoryLinksOutside(t *testing.T) {
+	outside := t.TempDir()
+	localDir := t.TempDir()
+	require.Nil(t, os.Symlink(outside, filepath.Join(localDir, expDirName)))
+	downloader := NewMegaDownloader()
+	downloader.progressOutput = nil
+	browser, err := New(
+		WithBackend(NewLocalBackend(writeLocalRepository(t))),
+		WithDownloader(downloader),
+	)
+	require.Nil(t, err)
+	require.Nil(t, browser.Initialize())
+
+	_, err = browser.Sync(localDir)
+
+	assert.ErrorIs(t, err, ErrUnsafePath)
+	assert.NoFileExists(t, filepath.Join(outside, expFileName))
+}
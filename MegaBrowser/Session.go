@@ -0,0 +1,110 @@
+package megabrowser
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrNoSession is returned by a SessionStore, if there is no saved session.
+var ErrNoSession = errors.New("no saved session")
+
+// Session is an authenticated session of a SessionClient, which can be resumed without logging in again.
+type Session struct {
+	// Token identifies the session, for Mega it holds the session id and the master key.
+	Token   string    `json:"token"`
+	SavedAt time.Time `json:"savedAt"`
+}
+
+// SessionStore persists a session between runs.
+type SessionStore interface {
+	// Load returns the saved session, or ErrNoSession if there is none.
+	Load() (Session, error)
+	Save(session Session) error
+	Clear() error
+}
+
+// FileSessionStore keeps a session in a file encrypted with a passphrase, so that the session token is never stored in plaintext.
+type FileSessionStore struct {
+	path       string
+	passphrase func() ([]byte, error)
+}
+
+// NewFileSessionStore creates a store keeping the session in a file at given path, encrypted with the passphrase returned by the passphrase function.
+func NewFileSessionStore(path string, passphrase func() ([]byte, error)) *FileSessionStore {
+	return &FileSessionStore{
+		path:       path,
+		passphrase: passphrase,
+	}
+}
+
+/*
+Load reads and decrypts the session file.
+
+Returns an error if:
+
+	the file does not exist, see ErrNoSession
+	failed to read the file or get the passphrase
+	the file could not be decrypted, see ErrWrongPassphrase
+*/
+func (ss *FileSessionStore) Load() (Session, error) {
+	content, err := os.ReadFile(ss.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Session{}, ErrNoSession
+		}
+		return Session{}, err
+	}
+
+	passphrase, err := ss.passphrase()
+	if err != nil {
+		return Session{}, err
+	}
+
+	plaintext, err := decryptWithPassphrase(content, passphrase)
+	if err != nil {
+		return Session{}, err
+	}
+
+	var session Session
+	err = json.Unmarshal(plaintext, &session)
+	if err != nil {
+		return Session{}, ErrWrongPassphrase
+	}
+	return session, nil
+}
+
+// Save encrypts the session and writes it to the file, readable only by its owner. The directory of the file is created, if it does not exist.
+func (ss *FileSessionStore) Save(session Session) error {
+	plaintext, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := ss.passphrase()
+	if err != nil {
+		return err
+	}
+
+	content, err := encryptWithPassphrase(plaintext, passphrase)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(ss.path), 0700)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(ss.path, content, 0600)
+}
+
+// Clear removes the session file. Does nothing, if the file does not exist.
+func (ss *FileSessionStore) Clear() error {
+	err := os.Remove(ss.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package megabrowser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSessionStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions", "session")
+	store := NewFileSessionStore(path, staticPassphrase("passphrase"))
+	session := Session{
+		Token:   "token",
+		SavedAt: time.Date(2024, 1, 24, 0, 0, 0, 0, time.UTC),
+	}
+
+	_, err := store.Load()
+	assert.Equal(t, ErrNoSession, err)
+
+	err = store.Save(session)
+	require.Nil(t, err)
+	content, err := os.ReadFile(path)
+	require.Nil(t, err)
+	assert.NotContains(t, string(content), session.Token)
+
+	loaded, err := store.Load()
+	require.Nil(t, err)
+	assert.Equal(t, session, loaded)
+
+	_, err = NewFileSessionStore(path, staticPassphrase("wrong")).Load()
+	assert.Equal(t, ErrWrongPassphrase, err)
+
+	err = store.Clear()
+	require.Nil(t, err)
+	_, err = store.Load()
+	assert.Equal(t, ErrNoSession, err)
+	assert.Nil(t, store.Clear())
+}
+
+func TestInitializeWithSessionStore(t *testing.T) {
+	now := time.Date(2024, 1, 24, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name              string
+		savedSession      *Session
+		errResume         error
+		expCredentialsUse int
+		expSavedToken     string
+	}{
+		{
+			name:              "should resume saved session, if it is not expired",
+			savedSession:      &Session{Token: "saved-token", SavedAt: now.Add(-time.Minute)},
+			errResume:         nil,
+			expCredentialsUse: 0,
+			expSavedToken:     "saved-token",
+		},
+		{
+			name:              "should log in and save new session, if saved session is expired",
+			savedSession:      &Session{Token: "saved-token", SavedAt: now.Add(-2 * time.Hour)},
+			errResume:         nil,
+			expCredentialsUse: 1,
+			expSavedToken:     "new-token",
+		},
+		{
+			name:              "should log in and save new session, if saved session could not be resumed",
+			savedSession:      &Session{Token: "saved-token", SavedAt: now},
+			errResume:         errLogin,
+			expCredentialsUse: 1,
+			expSavedToken:     "new-token",
+		},
+		{
+			name:              "should log in and save new session, if there is no saved session",
+			savedSession:      nil,
+			errResume:         nil,
+			expCredentialsUse: 1,
+			expSavedToken:     "new-token",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := &mockSessionStore{session: test.savedSession}
+			credentialsUse := 0
+			provider := CredentialProviderFunc(func() (Credentials, error) {
+				credentialsUse++
+				return Credentials{Login: login, Password: pass}, nil
+			})
+			storageBrowser, err := New(
+				WithCredentialProvider(provider),
+				WithSessionStore(store, time.Hour),
+				WithClock(func() time.Time { return now }),
+				WithClient(&mockSessionClient{token: "new-token", errResume: test.errResume}),
+				WithFs(&mockFs{}),
+				WithRootNodeHashFunc(mockGetRootNodeHash),
+			)
+			require.Nil(t, err)
+
+			err = storageBrowser.Initialize()
+
+			require.Nil(t, err)
+			assert.Equal(t, test.expCredentialsUse, credentialsUse)
+			require.NotNil(t, store.session)
+			assert.Equal(t, test.expSavedToken, store.session.Token)
+		})
+	}
+}
+
+func TestInitializeFailsIfSessionCouldNotBeSaved(t *testing.T) {
+	storageBrowser, err := New(
+		WithCredentials(login, pass),
+		WithSessionStore(&mockSessionStore{errSave: errLogin}, 0),
+		WithClient(&mockSessionClient{token: "new-token"}),
+		WithFs(&mockFs{}),
+		WithRootNodeHashFunc(mockGetRootNodeHash),
+	)
+	require.Nil(t, err)
+
+	err = storageBrowser.Initialize()
+
+	require.NotNil(t, err)
+	assert.ErrorIs(t, err, errLogin)
+}
+
+type mockSessionStore struct {
+	session *Session
+	errSave error
+}
+
+func (m *mockSessionStore) Load() (Session, error) {
+	if m.session == nil {
+		return Session{}, ErrNoSession
+	}
+	return *m.session, nil
+}
+
+func (m *mockSessionStore) Save(session Session) error {
+	if m.errSave != nil {
+		return m.errSave
+	}
+	m.session = &session
+	return nil
+}
+
+func (m *mockSessionStore) Clear() error {
+	m.session = nil
+	return nil
+}
+
+func staticPassphrase(passphrase string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		return []byte(passphrase), nil
+	}
+}
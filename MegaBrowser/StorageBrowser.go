@@ -1,37 +1,80 @@
 package megabrowser
 
 import (
+	"context"
+	"crypto/ed25519"
+	"errors"
 	"fmt"
+	"log/slog"
+	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/t3rm1n4l/go-mega"
+	"go.opentelemetry.io/otel/trace"
 )
 
-var errGetRootNodeHash = fmt.Errorf("failed to get root node hash")
+var errNoCredentials = fmt.Errorf("no credential provider set")
+var errNoPublicDownloader = fmt.Errorf("downloader does not support public links")
+var errNoMFAClient = fmt.Errorf("client does not support multi-factor authentication")
+
+// ErrRootNodeNotFound is returned by Initialize, if the project root node could not be found. Functions given to WithRootNodeHashFunc should return it in that case, so that Publisher creates the project root.
+var ErrRootNodeNotFound = errors.New("failed to get root node hash")
+
+// ErrMFARequired is returned by Initialize, if the account has multi-factor authentication enabled and no code was supplied, see WithMFACode and WithMFACodeFunc.
+var ErrMFARequired = errors.New("multi-factor authentication code required")
+
+// ErrAccountRequired is returned by operations, which need a logged in Mega account, if the browser works in an anonymous session.
+var ErrAccountRequired = errors.New("operation requires a Mega account, not available in an anonymous session")
 
 type StorageBrowser interface {
 	GetObjectNode(file string) (string, error)
 }
 
 type MegaBrowser struct {
-	login           string
-	pass            string
+	credentials     CredentialProvider
+	mfaCode         func() (string, error)
+	sessionToken    string
+	sessionStore    SessionStore
+	sessionMaxAge   time.Duration
 	rootNodeName    string
 	megaClient      StorageClient
 	megaFs          Fs
+	publicFolder    *PublicFolder
+	backend         Backend
+	mirrors         []Mirror
+	mirror          string
+	fallback        *fallbackState
+	manifestFile    string
+	manifestKey     ed25519.PublicKey
+	manifest        *manifestIndex
+	peers           *Peers
+	anonymous       bool
+	releases        bool
+	release         string
+	releasesHash    string
+	releaseDir      string
+	projectHash     string
+	pinned          bool
 	downloader      Downloader
 	getRootNodeHash getRootNodeHashFunc
 	getChildren     getChildrenFunc
 	targetSeparator string
 	rootNodeHash    string
+	filters         FiltersConfig
+	concurrency     int
+	logger          *slog.Logger
+	metrics         *Metrics
+	tracer          trace.Tracer
+	now             func() time.Time
 }
 
 type getRootNodeHashFunc func(nodes []Node, rootNodeName string) (string, error)
 type getChildrenFunc func(fs Fs, nodeHash string) ([]Node, error)
 
 /*
-NewMegaBrowser creates a browser object for a Mega repository.
+NewMegaBrowser creates a browser object for a Mega repository. It is a shorthand for New with WithCredentials, WithRootNode, WithClient, WithFs and WithDownloader options.
 
 Expected input parameters are:
 
@@ -39,51 +82,104 @@ Expected input parameters are:
 	rootNodeName - name of the directory, containing the updated project.
 	megaClient - client for the Mega repository. Can be created with mega.New() function from t3rm1n4l/go-mega package. Make sure to create the megaClient object before actually calling NewMegaBrowser() function.
 	fs - system of Mega nodes. FS parameter of the megaClient above can be used.
-	downloader - object responsible for downloading and updating project files. Can be created with NewMegaDownloader(client StorageClient) function from this package.
+	downloader - object responsible for downloading and updating project files. Can be created with NewMegaDownloader() function from this package.
 */
 func NewMegaBrowser(login string, pass string, rootNodeName string, megaClient StorageClient, fs Fs, downloader Downloader) *MegaBrowser {
-	browser := &MegaBrowser{
-		login:           login,
-		pass:            pass,
-		rootNodeName:    rootNodeName,
-		megaClient:      megaClient,
-		megaFs:          fs,
-		downloader:      downloader,
-		getRootNodeHash: getRootNodeHash,
-		getChildren:     getChildren,
-		targetSeparator: "/",
-	}
+	// None of these options can fail.
+	browser, _ := New(
+		WithCredentials(login, pass),
+		WithRootNode(rootNodeName),
+		WithClient(megaClient),
+		WithFs(fs),
+		WithDownloader(downloader),
+	)
 	return browser
 }
 
 /*
 Initialize logs in to the Mega repository and initializes the browser parameters, based on that repository.
 
+If the browser was created with WithPublicFolder, the folder tree is loaded instead, without logging in. A browser created with WithBackend does not log in either. A browser created with WithAnonymousSession never logs in, so it can only browse a public folder or a backend. The project root node is then the root of the shared folder or the backend itself, if the root node name is empty or matches its name, otherwise a directory of that name inside it.
+
+If the browser client implements SessionClient, the session given with WithSessionToken, or otherwise the one saved in the session store, is resumed first. Credentials are requested from the credential provider only if there is no session to resume or resuming it failed. If the account has multi-factor authentication enabled, a code is then requested with the function given with WithMFACodeFunc, or the one given with WithMFACode is used, and the login is repeated with that code. A new session established with credentials is saved in the session store.
+
+If the browser was created with WithReleases, the project root node is then replaced with the directory of the current release, see Release, or of the release pinned in the directory given with WithReleaseDir. If it was created with WithManifest, the manifest is then read from the project root node, and every file downloaded afterwards is verified against it.
+
+If any of the above fails and the browser was created with WithMirrors, the mirrors are tried in the given order, and the browser works on the first one, whose manifest could be read and has a valid signature, see Mirror. Mirrors are not tried, if the account rejected the credentials or requires a multi-factor code, because they are meant for an unavailable repository, not for a misconfigured one. The primary source is tried again, whenever the browser is initialized.
+
 Returns an error if:
 
-	failed to login
+	failed to login, to load the public folder or to read the backend
+	the account requires a multi-factor code and none was supplied, see ErrMFARequired
+	the session is anonymous and there is neither a public folder nor a backend, see ErrAccountRequired
+	failed to save the new session
 	an error occured while getting children of a repository root node
 	could not find the project root node
+	could not read the current release pointer or find the release directory
+	could not read the manifest or its signature is not valid, see ErrInvalidSignature
+	none of the mirrors could be used either, in which case the errors of every source are joined
 */
 func (mb *MegaBrowser) Initialize() error {
-	err := mb.megaClient.Login(mb.login, mb.pass)
-	if err != nil {
-		return err
-	}
+	return mb.InitializeContext(context.Background())
+}
 
-	nodes, err := mb.megaFs.GetChildren(mb.megaFs.GetRoot())
+// InitializeContext initializes the browser like Initialize does, tracing it with a span, which is a child of the span in ctx, see WithTracerProvider.
+func (mb *MegaBrowser) InitializeContext(ctx context.Context) (err error) {
+	ctx, span := mb.tracer.Start(ctx, "Initialize", trace.WithAttributes(attrRoot.String(mb.rootNodeName)))
+	defer func() { endSpan(span, err) }()
+
+	start := mb.now()
+	mb.restorePrimary()
+	err = mb.initializePrimary(ctx)
+	if err != nil && len(mb.mirrors) > 0 && !mb.authenticationFailed(err) {
+		err = mb.initializeMirrors(err)
+	}
 	if err != nil {
+		mb.logger.Error("initialization failed", "root", mb.rootNodeName, "duration", mb.now().Sub(start), "error", err)
 		return err
 	}
+	span.SetAttributes(attrHash.String(mb.rootNodeHash))
+	mb.logger.Info("initialized", "root", mb.rootNodeName, "hash", mb.rootNodeHash, "release", mb.release, "mirror", mb.mirror, "duration", mb.now().Sub(start))
+	return nil
+}
+
+// Mirror returns name of the mirror the browser works on, see WithMirrors. Returns an empty string, if it works on the primary source.
+func (mb *MegaBrowser) Mirror() string {
+	return mb.mirror
+}
+
+// Peers returns peers the browser shares files with, see WithPeers, or nil if it has none.
+func (mb *MegaBrowser) Peers() *Peers {
+	return mb.peers
+}
+
+// Metrics returns metrics the browser counts its operations in, see WithMetrics, or nil if it has none.
+func (mb *MegaBrowser) Metrics() *Metrics {
+	return mb.metrics
+}
 
-	convertedNodes := nodeStructArrToInterfaceArr(nodes)
-	rootNodeHash, err := mb.getRootNodeHash(convertedNodes, mb.rootNodeName)
+// initializePrimary initializes the browser on the Mega account, public folder or backend it was created with.
+func (mb *MegaBrowser) initializePrimary(ctx context.Context) error {
+	var err error
+	switch {
+	case mb.publicFolder != nil:
+		err = mb.initializePublicFolder(ctx)
+	case mb.backend != nil:
+		err = mb.initializeBackend()
+	default:
+		err = mb.initializeAccount(ctx)
+	}
 	if err != nil {
 		return err
 	}
-	mb.rootNodeHash = rootNodeHash
 
-	return nil
+	if mb.releases {
+		err = mb.resolveCurrentRelease()
+		if err != nil {
+			return err
+		}
+	}
+	return mb.loadManifest()
 }
 
 /*
@@ -92,15 +188,26 @@ GetObjectNode takes path to a file and returns its hash from the Mega repository
 Returns an error if:
 
 	given path is empty
+	any element of given path is not a safe local file name, see ErrUnsafePath
 	an error occured while getting children of a node
 	expected to find node of a file or directory, but did not find it
 */
 func (mb *MegaBrowser) GetObjectNode(file string) (string, error) {
+	return mb.getObjectNode(context.Background(), file)
+}
+
+// getObjectNode resolves a file path like GetObjectNode does, tracing every step with a span, which is a child of the span in ctx.
+func (mb *MegaBrowser) getObjectNode(ctx context.Context, file string) (string, error) {
+	mb.metrics.countLookup()
 	splitPath := strings.Split(filepath.ToSlash(file), mb.targetSeparator)
 	len := len(splitPath)
 	if len == 1 && splitPath[0] == "" {
 		return "", fmt.Errorf("trying to find object node for an empty path")
 	}
+	err := validatePathElements(splitPath)
+	if err != nil {
+		return "", err
+	}
 
 	var targetFile string
 	var currentDir string
@@ -110,20 +217,31 @@ func (mb *MegaBrowser) GetObjectNode(file string) (string, error) {
 	}
 
 	for i, _ := range splitPath {
-		childNodes, err := mb.getChildren(mb.megaFs, currentDir)
+		_, span := mb.tracer.Start(ctx, "resolve", trace.WithAttributes(attrName.String(splitPath[i]), attrPath.String(file)))
+		childNodes, err := mb.listChildren(currentDir)
 		if err != nil {
+			endSpan(span, err)
 			return "", err
 		}
 
 		if i == len-1 {
 			result, err := getNodeHashOfExpectedFile(targetFile, &childNodes)
 			if err != nil {
+				endSpan(span, err)
+				mb.logger.Debug("path not found", "path", file, "error", err)
 				return "", err
 			}
+			span.SetAttributes(attrHash.String(result))
+			endSpan(span, nil)
+			mb.logger.Debug("path resolved", "path", file, "hash", result)
 			return result, nil
 		} else {
 			var err error
 			currentDir, err = getNodeHashOfExpectedDirectory(splitPath[i], &childNodes)
+			if err == nil {
+				span.SetAttributes(attrHash.String(currentDir))
+			}
+			endSpan(span, err)
 			if err != nil {
 				return "", err
 			}
@@ -132,9 +250,423 @@ func (mb *MegaBrowser) GetObjectNode(file string) (string, error) {
 	return "", fmt.Errorf("could not find object node for %s", file)
 }
 
-// UpdateFile updates a file at specified localDownloadPath with a file downloaded from Mega node
-func (mb *MegaBrowser) UpdateFile(node *mega.Node, localDownloadPath string) error {
-	return mb.downloader.DownloadFile(node, localDownloadPath)
+/*
+Stat takes path to a file or directory and returns its node from the Mega repository, whose path is relative to the project root, see Node.GetPath.
+
+Returns an error if:
+
+	given path is empty
+	any element of given path is not a safe local file name, see ErrUnsafePath
+	an error occured while getting children of a node
+	expected to find node of a file or directory, but did not find it
+*/
+func (mb *MegaBrowser) Stat(path string) (Node, error) {
+	return mb.StatContext(context.Background(), path)
+}
+
+// StatContext returns the node of given path like Stat does, tracing every path resolution step with a span, which is a child of the span in ctx, see WithTracerProvider.
+func (mb *MegaBrowser) StatContext(ctx context.Context, path string) (Node, error) {
+	mb.metrics.countLookup()
+	splitPath := mb.splitPath(path)
+	if len(splitPath) == 0 {
+		return nil, fmt.Errorf("trying to stat an empty path")
+	}
+	err := validatePathElements(splitPath)
+	if err != nil {
+		return nil, err
+	}
+
+	parentDir, err := mb.resolveDirectory(ctx, splitPath[:len(splitPath)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	name := splitPath[len(splitPath)-1]
+	_, span := mb.tracer.Start(ctx, "resolve", trace.WithAttributes(attrName.String(name), attrPath.String(strings.Join(splitPath, "/"))))
+	childNodes, err := mb.children(parentDir, strings.Join(splitPath[:len(splitPath)-1], "/"))
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+
+	for _, child := range childNodes {
+		if child.GetName() == name {
+			span.SetAttributes(attrHash.String(child.GetHash()))
+			endSpan(span, nil)
+			mb.logger.Debug("path resolved", "path", child.GetPath(), "hash", child.GetHash())
+			return child, nil
+		}
+	}
+	err = fmt.Errorf("could not find object: %s", name)
+	endSpan(span, err)
+	mb.logger.Debug("path not found", "path", path)
+	return nil, err
+}
+
+/*
+ListDirectory takes path to a directory and returns its child nodes from the Mega repository. An empty path lists the project root directory. Paths of the returned nodes are relative to the project root, see Node.GetPath.
+
+Returns an error if:
+
+	any element of given path or name of a child node is not a safe local file name, see ErrUnsafePath
+	an error occured while getting children of a node
+	expected to find node of a directory, but did not find it
+*/
+func (mb *MegaBrowser) ListDirectory(path string) ([]Node, error) {
+	return mb.ListDirectoryContext(context.Background(), path)
+}
+
+// ListDirectoryContext lists a directory like ListDirectory does, tracing every path resolution step with a span, which is a child of the span in ctx, see WithTracerProvider.
+func (mb *MegaBrowser) ListDirectoryContext(ctx context.Context, path string) ([]Node, error) {
+	mb.metrics.countLookup()
+	dirs := mb.splitPath(path)
+	err := validatePathElements(dirs)
+	if err != nil {
+		return nil, err
+	}
+	dirHash, err := mb.resolveDirectory(ctx, dirs)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := mb.children(dirHash, strings.Join(dirs, "/"))
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range nodes {
+		err = validateNodeName(node.GetName())
+		if err != nil {
+			return nil, fmt.Errorf("%w in %q", err, strings.Join(dirs, "/"))
+		}
+	}
+	return nodes, nil
+}
+
+// SessionToken returns token of the session established by Initialize, which can be given to WithSessionToken to skip the password login next time. Returns an empty string, if the client does not implement SessionClient.
+func (mb *MegaBrowser) SessionToken() string {
+	return mb.sessionToken
+}
+
+// Anonymous returns true, if the browser works without a Mega account, i.e. it was created with WithAnonymousSession, WithPublicFolder or WithBackend.
+func (mb *MegaBrowser) Anonymous() bool {
+	return mb.anonymous || mb.backend != nil
+}
+
+// UpdateFile updates a file at specified localDownloadPath with a file node of the storage the browser works on, e.g. returned by Stat. Returns ErrAccountRequired in an anonymous session without a public folder or another backend.
+func (mb *MegaBrowser) UpdateFile(node Node, localDownloadPath string) error {
+	return mb.updateFileByHash(context.Background(), node.GetHash(), localDownloadPath)
+}
+
+/*
+UpdateFileFromPath resolves a file path in the Mega repository and updates a file at specified localDownloadPath with it.
+
+Returns an error if:
+
+	could not find the file node, see GetObjectNode
+	failed to download the file
+	the file does not match the manifest, see ErrChecksumMismatch
+*/
+func (mb *MegaBrowser) UpdateFileFromPath(file string, localDownloadPath string) error {
+	return mb.UpdateFileFromPathContext(context.Background(), file, localDownloadPath)
+}
+
+// UpdateFileFromPathContext updates a file like UpdateFileFromPath does, tracing the path resolution, the download and the verification with spans, which are children of the span in ctx, see WithTracerProvider.
+func (mb *MegaBrowser) UpdateFileFromPathContext(ctx context.Context, file string, localDownloadPath string) error {
+	hash, err := mb.getObjectNode(ctx, file)
+	if err != nil {
+		return err
+	}
+	return mb.updateFile(ctx, strings.Join(mb.splitPath(file), "/"), hash, localDownloadPath)
+}
+
+// UpdateFileFromPathReport updates a file like UpdateFileFromPathContext does and returns its outcome, see UpdateReport. The report is returned also if the update fails.
+func (mb *MegaBrowser) UpdateFileFromPathReport(ctx context.Context, file string, localDownloadPath string) (*UpdateReport, error) {
+	report := mb.newReport()
+	err := mb.UpdateFileFromPathContext(ctx, file, localDownloadPath)
+	report.Add(mb.fileReport(strings.Join(mb.splitPath(file), "/"), localDownloadPath, mb.source(), report.Started, err))
+	mb.finishReport(report)
+	return report, err
+}
+
+// updateFile downloads the file of given path, relative to the project root, and hash to localDownloadPath and verifies it against the manifest, tracing both with a span, which is a child of the span in ctx.
+func (mb *MegaBrowser) updateFile(ctx context.Context, remotePath string, hash string, localDownloadPath string) (err error) {
+	ctx, span := mb.tracer.Start(ctx, "update", trace.WithAttributes(attrPath.String(remotePath), attrHash.String(hash)))
+	defer func() { endSpan(span, err) }()
+
+	err = mb.updateFileByHash(ctx, hash, localDownloadPath)
+	if err != nil {
+		return err
+	}
+	return mb.verifyFile(ctx, remotePath, localDownloadPath)
+}
+
+// initializeAccount logs in to the Mega repository and finds the project root node in it.
+func (mb *MegaBrowser) initializeAccount(ctx context.Context) error {
+	if mb.anonymous {
+		return fmt.Errorf("anonymous session without a public folder or a backend: %w", ErrAccountRequired)
+	}
+
+	_, span := mb.tracer.Start(ctx, "login")
+	err := mb.login()
+	endSpan(span, err)
+	if err != nil {
+		return err
+	}
+
+	account := mb.storage()
+	root := account.Root()
+	if root == nil {
+		return ErrRootNodeNotFound
+	}
+	nodes, err := account.Children(root.GetHash())
+	if err != nil {
+		return err
+	}
+	rootNodeHash, err := mb.getRootNodeHash(nodes, mb.rootNodeName)
+	if err != nil {
+		return err
+	}
+	mb.rootNodeHash = rootNodeHash
+
+	return nil
+}
+
+// initializePublicFolder loads the public folder tree and finds the project root node in it.
+func (mb *MegaBrowser) initializePublicFolder(ctx context.Context) error {
+	err := mb.publicFolder.LoadContext(ctx)
+	if err != nil {
+		return err
+	}
+	return mb.initializeBackend()
+}
+
+// initializeBackend finds the project root node in the backend, which is either its root or a directory of the root node name inside it.
+func (mb *MegaBrowser) initializeBackend() error {
+	root := mb.backend.Root()
+	if root == nil {
+		return ErrRootNodeNotFound
+	}
+	if mb.rootNodeName == "" || mb.rootNodeName == root.GetName() {
+		mb.rootNodeHash = root.GetHash()
+		return nil
+	}
+
+	nodes, err := mb.backend.Children(root.GetHash())
+	if err != nil {
+		return err
+	}
+	rootNodeHash, err := mb.getRootNodeHash(nodes, mb.rootNodeName)
+	if err != nil {
+		return err
+	}
+	mb.rootNodeHash = rootNodeHash
+	return nil
+}
+
+// updateFileByHash updates a file at specified localDownloadPath with a file of given hash, from the public folder, or otherwise from the backend the browser works on, see storage.
+func (mb *MegaBrowser) updateFileByHash(ctx context.Context, hash string, localDownloadPath string) error {
+	downloader := mb.contextDownloader(ctx)
+	if mb.publicFolder != nil {
+		downloader, ok := downloader.(PublicDownloader)
+		if !ok {
+			return errNoPublicDownloader
+		}
+		file, err := mb.publicFolder.File(hash)
+		if err != nil {
+			return err
+		}
+		return downloader.DownloadPublicFile(file, localDownloadPath)
+	}
+	if mb.backend == nil {
+		err := mb.requireAccount()
+		if err != nil {
+			return err
+		}
+	}
+	storage := mb.storage()
+	node, err := storage.Stat(hash)
+	if err != nil {
+		return err
+	}
+	return downloader.DownloadFromBackend(storage, node, localDownloadPath)
+}
+
+// storage returns the backend the browser works on, which is the one it was created with, a mirror, or otherwise an adapter of the Mega account, see NewMegaBackend. The adapter is created for every call, so that it uses the current client, filesystem and children function of the browser.
+func (mb *MegaBrowser) storage() Backend {
+	if mb.backend != nil {
+		return mb.backend
+	}
+	return &megaBackend{
+		client:      mb.megaClient,
+		fs:          mb.megaFs,
+		getChildren: mb.getChildren,
+	}
+}
+
+// authenticationFailed returns true, if the browser works on an account and err means that the account rejected the credentials, is blocked or requires a multi-factor code, rather than that it is unavailable.
+func (mb *MegaBrowser) authenticationFailed(err error) bool {
+	if mb.publicFolder != nil || mb.backend != nil {
+		return false
+	}
+	for _, authErr := range []error{ErrMFARequired, errNoCredentials, errNoMFAClient, mega.ENOENT, mega.EACCESS, mega.EBLOCKED, mega.EMFAREQUIRED} {
+		if errors.Is(err, authErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// requireAccount returns ErrAccountRequired, if the browser works in an anonymous session.
+func (mb *MegaBrowser) requireAccount() error {
+	if mb.Anonymous() {
+		return ErrAccountRequired
+	}
+	return nil
+}
+
+// login resumes the session, if possible, otherwise logs in with credentials from the credential provider and saves the new session.
+func (mb *MegaBrowser) login() error {
+	sessionClient, supportsSessions := mb.megaClient.(SessionClient)
+	if supportsSessions {
+		if mb.sessionToken == "" {
+			mb.sessionToken = mb.loadSessionToken()
+		}
+		if mb.sessionToken != "" {
+			err := sessionClient.ResumeSession(mb.sessionToken)
+			if err == nil {
+				mb.logger.Debug("session resumed")
+				return nil
+			}
+			mb.logger.Debug("session not resumed, logging in", "error", err)
+			mb.sessionToken = ""
+		}
+	}
+
+	if mb.credentials == nil {
+		return errNoCredentials
+	}
+	credentials, err := mb.credentials.Credentials()
+	if err != nil {
+		return err
+	}
+	err = mb.megaClient.Login(credentials.Login, credentials.Password)
+	if errors.Is(err, mega.EMFAREQUIRED) {
+		err = mb.multiFactorLogin(credentials)
+	}
+	if err != nil {
+		return err
+	}
+	mb.logger.Debug("logged in")
+
+	if supportsSessions {
+		mb.sessionToken, err = sessionClient.SessionToken()
+		if err != nil {
+			return err
+		}
+		return mb.saveSessionToken()
+	}
+	return nil
+}
+
+// multiFactorLogin logs in with credentials and a multi-factor code, after the client reported the account requires one.
+func (mb *MegaBrowser) multiFactorLogin(credentials Credentials) error {
+	if mb.mfaCode == nil {
+		return ErrMFARequired
+	}
+	mfaClient, ok := mb.megaClient.(MFAClient)
+	if !ok {
+		return errNoMFAClient
+	}
+	code, err := mb.mfaCode()
+	if err != nil {
+		return err
+	}
+	if code == "" {
+		return ErrMFARequired
+	}
+	return mfaClient.MultiFactorLogin(credentials.Login, credentials.Password, code)
+}
+
+// loadSessionToken returns token of the session saved in the session store. Returns an empty string, if there is no store, the session could not be loaded or it is older than the session max age, so that the browser falls back to logging in with credentials.
+func (mb *MegaBrowser) loadSessionToken() string {
+	if mb.sessionStore == nil {
+		return ""
+	}
+	session, err := mb.sessionStore.Load()
+	if err != nil {
+		return ""
+	}
+	if mb.sessionMaxAge > 0 && mb.now().Sub(session.SavedAt) > mb.sessionMaxAge {
+		return ""
+	}
+	return session.Token
+}
+
+// saveSessionToken saves the current session in the session store, if there is one.
+func (mb *MegaBrowser) saveSessionToken() error {
+	if mb.sessionStore == nil {
+		return nil
+	}
+	err := mb.sessionStore.Save(Session{
+		Token:   mb.sessionToken,
+		SavedAt: mb.now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+// splitPath splits given path with the target separator, skipping empty elements, so that leading, trailing and doubled separators are ignored.
+func (mb *MegaBrowser) splitPath(path string) []string {
+	var result []string
+	for _, element := range strings.Split(filepath.ToSlash(path), mb.targetSeparator) {
+		if element != "" {
+			result = append(result, element)
+		}
+	}
+	return result
+}
+
+// listChildren returns children of the node of given hash in the backend the browser works on, counting the request in the browser metrics.
+func (mb *MegaBrowser) listChildren(hash string) ([]Node, error) {
+	mb.metrics.countChildren()
+	return mb.storage().Children(hash)
+}
+
+// children returns children of the node of given hash, wrapped with their paths relative to the project root, where dirPath is the path of that node.
+func (mb *MegaBrowser) children(dirHash string, dirPath string) ([]Node, error) {
+	nodes, err := mb.listChildren(dirHash)
+	if err != nil {
+		return nil, err
+	}
+	for i, node := range nodes {
+		nodes[i] = pathNode{Node: node, path: path.Join(dirPath, node.GetName())}
+	}
+	return nodes, nil
+}
+
+// resolveDirectory walks through given directory names, starting at the project root node, and returns hash of the last one. Every step is traced with a span, which is a child of the span in ctx.
+func (mb *MegaBrowser) resolveDirectory(ctx context.Context, dirs []string) (string, error) {
+	currentDir := mb.rootNodeHash
+	for i, dir := range dirs {
+		_, span := mb.tracer.Start(ctx, "resolve", trace.WithAttributes(attrName.String(dir), attrPath.String(strings.Join(dirs[:i+1], "/"))))
+		childNodes, err := mb.listChildren(currentDir)
+		if err != nil {
+			endSpan(span, err)
+			return "", err
+		}
+		currentDir, err = getNodeHashOfExpectedDirectory(dir, &childNodes)
+		if err == nil {
+			span.SetAttributes(attrHash.String(currentDir))
+		}
+		endSpan(span, err)
+		if err != nil {
+			mb.logger.Debug("directory not found", "path", strings.Join(dirs, "/"), "error", err)
+			return "", err
+		}
+	}
+	mb.logger.Debug("directory resolved", "path", strings.Join(dirs, "/"), "hash", currentDir)
+	return currentDir, nil
 }
 
 // getRoodNodeHash takes an array of nodes and checks if any of them is a project root node.
@@ -151,7 +683,7 @@ func getRootNodeHash(nodes []Node, rootNodeName string) (string, error) {
 			return rootNodeHash, nil
 		}
 	}
-	return "", errGetRootNodeHash
+	return "", ErrRootNodeNotFound
 }
 
 func getChildren(fs Fs, nodeHash string) ([]Node, error) {
@@ -160,5 +692,5 @@ func getChildren(fs Fs, nodeHash string) ([]Node, error) {
 	if err != nil {
 		return nil, err
 	}
-	return nodeStructArrToInterfaceArr(currDirChildNodes), nil
+	return describeNodes(fs, currDirChildNodes, currentDirNode), nil
 }
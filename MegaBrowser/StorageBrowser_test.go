@@ -33,13 +33,27 @@ type mockClient struct {
 	errDownload error
 }
 
+type mockSessionClient struct {
+	mockClient
+	token     string
+	errResume error
+}
+
+type mockMFAClient struct {
+	mockClient
+	errMFALogin error
+	mfaCodes    []string
+}
+
 type mockFs struct {
 	children       []*mega.Node
 	errGetChildren error
 }
 
 type mockDownloader struct {
-	downloadErr error
+	downloadErr     error
+	downloadedPaths []string
+	backends        []Backend
 }
 
 func TestInitializeStorageBrowser(t *testing.T) {
@@ -81,7 +95,7 @@ func TestInitializeStorageBrowser(t *testing.T) {
 			getChildrenError:        nil,
 			getRootNodeHashFunction: nil,
 			expRootNodeHash:         "",
-			expErr:                  errGetRootNodeHash,
+			expErr:                  ErrRootNodeNotFound,
 		},
 	}
 	for _, test := range tests {
@@ -92,12 +106,20 @@ func TestInitializeStorageBrowser(t *testing.T) {
 			mockFs := mockFs{
 				errGetChildren: test.getChildrenError,
 			}
-			storageBrowser := NewMegaBrowser(login, pass, rootNodeName, &mockClient, &mockFs, &mockDownloader{})
+			opts := []Option{
+				WithCredentials(login, pass),
+				WithRootNode(rootNodeName),
+				WithClient(&mockClient),
+				WithFs(&mockFs),
+				WithDownloader(&mockDownloader{}),
+			}
 			if test.getRootNodeHashFunction != nil {
-				storageBrowser.getRootNodeHash = test.getRootNodeHashFunction
+				opts = append(opts, WithRootNodeHashFunc(test.getRootNodeHashFunction))
 			}
+			storageBrowser, err := New(opts...)
+			require.Nil(t, err)
 
-			err := storageBrowser.Initialize()
+			err = storageBrowser.Initialize()
 
 			assert.Equal(t, test.expRootNodeHash, storageBrowser.rootNodeHash)
 			assert.Equal(t, test.expErr, err)
@@ -105,6 +127,195 @@ func TestInitializeStorageBrowser(t *testing.T) {
 	}
 }
 
+func TestInitializeWithSession(t *testing.T) {
+	tests := []struct {
+		name              string
+		sessionToken      string
+		errResume         error
+		expCredentialsUse int
+		expSessionToken   string
+		expErr            error
+	}{
+		{
+			name:              "should resume session without consulting credentials, if session token is valid",
+			sessionToken:      "old-token",
+			errResume:         nil,
+			expCredentialsUse: 0,
+			expSessionToken:   "old-token",
+			expErr:            nil,
+		},
+		{
+			name:              "should log in with credentials, if session could not be resumed",
+			sessionToken:      "old-token",
+			errResume:         errLogin,
+			expCredentialsUse: 1,
+			expSessionToken:   "new-token",
+			expErr:            nil,
+		},
+		{
+			name:              "should log in with credentials and save session token, if there is no session",
+			sessionToken:      "",
+			errResume:         nil,
+			expCredentialsUse: 1,
+			expSessionToken:   "new-token",
+			expErr:            nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			credentialsUse := 0
+			provider := CredentialProviderFunc(func() (Credentials, error) {
+				credentialsUse++
+				return Credentials{Login: login, Password: pass}, nil
+			})
+			client := &mockSessionClient{token: "new-token", errResume: test.errResume}
+			storageBrowser, err := New(
+				WithCredentialProvider(provider),
+				WithSessionToken(test.sessionToken),
+				WithClient(client),
+				WithFs(&mockFs{}),
+				WithRootNodeHashFunc(mockGetRootNodeHash),
+			)
+			require.Nil(t, err)
+
+			err = storageBrowser.Initialize()
+
+			assert.Equal(t, test.expErr, err)
+			assert.Equal(t, test.expCredentialsUse, credentialsUse)
+			assert.Equal(t, test.expSessionToken, storageBrowser.SessionToken())
+		})
+	}
+}
+
+func TestInitializeWithMFA(t *testing.T) {
+	tests := []struct {
+		name        string
+		errLogin    error
+		mfaOption   Option
+		errMFALogin error
+		expMFACodes []string
+		expErr      error
+	}{
+		{
+			name:        "should not request multi-factor code, if account does not require it",
+			errLogin:    nil,
+			mfaOption:   WithMFACodeFunc(func() (string, error) { return "", errLogin }),
+			expMFACodes: nil,
+			expErr:      nil,
+		},
+		{
+			name:        "should log in with precomputed code, if account requires it",
+			errLogin:    mega.EMFAREQUIRED,
+			mfaOption:   WithMFACode("123456"),
+			expMFACodes: []string{"123456"},
+			expErr:      nil,
+		},
+		{
+			name:        "should log in with code from callback, if account requires it",
+			errLogin:    mega.EMFAREQUIRED,
+			mfaOption:   WithMFACodeFunc(func() (string, error) { return "654321", nil }),
+			expMFACodes: []string{"654321"},
+			expErr:      nil,
+		},
+		{
+			name:        "should fail with ErrMFARequired, if account requires code and none is supplied",
+			errLogin:    mega.EMFAREQUIRED,
+			mfaOption:   nil,
+			expMFACodes: nil,
+			expErr:      ErrMFARequired,
+		},
+		{
+			name:        "should fail with ErrMFARequired, if callback returns an empty code",
+			errLogin:    mega.EMFAREQUIRED,
+			mfaOption:   WithMFACode(""),
+			expMFACodes: nil,
+			expErr:      ErrMFARequired,
+		},
+		{
+			name:        "should fail, if callback fails",
+			errLogin:    mega.EMFAREQUIRED,
+			mfaOption:   WithMFACodeFunc(func() (string, error) { return "", errLogin }),
+			expMFACodes: nil,
+			expErr:      errLogin,
+		},
+		{
+			name:        "should fail, if code is rejected",
+			errLogin:    mega.EMFAREQUIRED,
+			mfaOption:   WithMFACode("000000"),
+			errMFALogin: errLogin,
+			expMFACodes: []string{"000000"},
+			expErr:      errLogin,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &mockMFAClient{
+				mockClient:  mockClient{errLogin: test.errLogin},
+				errMFALogin: test.errMFALogin,
+			}
+			opts := []Option{
+				WithCredentials(login, pass),
+				WithClient(client),
+				WithFs(&mockFs{}),
+				WithRootNodeHashFunc(mockGetRootNodeHash),
+			}
+			if test.mfaOption != nil {
+				opts = append(opts, test.mfaOption)
+			}
+			storageBrowser, err := New(opts...)
+			require.Nil(t, err)
+
+			err = storageBrowser.Initialize()
+
+			assert.Equal(t, test.expErr, err)
+			assert.Equal(t, test.expMFACodes, client.mfaCodes)
+		})
+	}
+}
+
+func TestInitializeFailsWithMFAIfClientDoesNotSupportIt(t *testing.T) {
+	storageBrowser, err := New(
+		WithCredentials(login, pass),
+		WithClient(&mockClient{errLogin: mega.EMFAREQUIRED}),
+		WithFs(&mockFs{}),
+		WithMFACode("123456"),
+	)
+	require.Nil(t, err)
+
+	err = storageBrowser.Initialize()
+
+	assert.Equal(t, errNoMFAClient, err)
+}
+
+func TestInitializeFailsWithoutCredentials(t *testing.T) {
+	storageBrowser, err := New(WithClient(&mockClient{}), WithFs(&mockFs{}))
+	require.Nil(t, err)
+
+	err = storageBrowser.Initialize()
+
+	assert.Equal(t, errNoCredentials, err)
+}
+
+func TestAnonymousSessionRequiresAccountForAccountOperations(t *testing.T) {
+	downloader := &mockDownloader{}
+	storageBrowser, err := New(
+		WithCredentials(login, pass),
+		WithClient(&mockClient{}),
+		WithFs(&mockFs{}),
+		WithDownloader(downloader),
+		WithAnonymousSession(),
+	)
+	require.Nil(t, err)
+	assert.True(t, storageBrowser.Anonymous())
+
+	err = storageBrowser.Initialize()
+	assert.ErrorIs(t, err, ErrAccountRequired)
+
+	err = storageBrowser.UpdateFile(&mockNode{nodeType: fileType}, "file")
+	assert.ErrorIs(t, err, ErrAccountRequired)
+	assert.Empty(t, downloader.downloadedPaths)
+}
+
 func TestGetRootNodeHash(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -130,7 +341,7 @@ func TestGetRootNodeHash(t *testing.T) {
 				&mockNode{}, &mockNode{},
 			},
 			expRootNodeHash: "",
-			expErr:          errGetRootNodeHash,
+			expErr:          ErrRootNodeNotFound,
 		},
 	}
 	for _, test := range tests {
@@ -248,14 +459,185 @@ func TestShouldGetChildren(t *testing.T) {
 }
 
 func TestStorageBrowserUpdate(t *testing.T) {
-	downloader := NewMegaDownloader(&mockClient{})
-	storageBrowser := NewMegaBrowser(login, pass, rootNodeName, nil, nil, downloader)
+	downloader := NewMegaDownloader()
+	storageBrowser := NewMegaBrowser(login, pass, rootNodeName, &mockClient{}, &mockFs{}, downloader)
 
-	err := storageBrowser.UpdateFile(nil, strings.Repeat("?", 1000))
+	err := storageBrowser.UpdateFile(&mockNode{nodeType: fileType}, strings.Repeat("?", 1000))
 	require.NotNil(t, err)
 	assert.Contains(t, err.Error(), strings.Repeat("?", 1000))
 }
 
+func TestStat(t *testing.T) {
+	tests := []struct {
+		name             string
+		getChildrenError error
+		givenPath        string
+		expHash          string
+		expErr           error
+	}{
+		{
+			name:      "should successfully stat a file, if all inputs are correct",
+			givenPath: filepath.Join(expDirName, expFileName),
+			expHash:   expFileHash,
+			expErr:    nil,
+		},
+		{
+			name:      "should successfully stat a directory, if all inputs are correct",
+			givenPath: expDirName + "/",
+			expHash:   expDirHash,
+			expErr:    nil,
+		},
+		{
+			name:      "should fail, if could not find expected object",
+			givenPath: filepath.Join(expDirName, "unexpFile"),
+			expHash:   "",
+			expErr:    fmt.Errorf("could not find object: unexpFile"),
+		},
+		{
+			name:      "should fail, if could not find expected directory",
+			givenPath: filepath.Join("unexpectedDir", expFileName),
+			expHash:   "",
+			expErr:    fmt.Errorf("could not find directory: unexpectedDir"),
+		},
+		{
+			name:      "should fail, if given path is empty",
+			givenPath: "/",
+			expHash:   "",
+			expErr:    fmt.Errorf("trying to stat an empty path"),
+		},
+		{
+			name:             "should fail, if could not get children of a node",
+			getChildrenError: errGetChildren,
+			givenPath:        expFileName,
+			expHash:          "",
+			expErr:           errGetChildren,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			storageBrowser := NewMegaBrowser(login, pass, rootNodeName, &mockClient{}, &mockFs{errGetChildren: test.getChildrenError}, &mockDownloader{})
+			if test.getChildrenError == nil {
+				storageBrowser.getChildren = mockGetChildren
+			}
+
+			node, err := storageBrowser.Stat(test.givenPath)
+
+			assert.Equal(t, test.expErr, err)
+			if test.expHash != "" {
+				require.NotNil(t, node)
+				assert.Equal(t, test.expHash, node.GetHash())
+				assert.Equal(t, strings.TrimSuffix(filepath.ToSlash(test.givenPath), "/"), node.GetPath())
+			} else {
+				assert.Nil(t, node)
+			}
+		})
+	}
+}
+
+func TestListDirectory(t *testing.T) {
+	tests := []struct {
+		name      string
+		givenPath string
+		expNames  []string
+		expPaths  []string
+		expErr    error
+	}{
+		{
+			name:      "should list the project root, if given path is empty",
+			givenPath: "",
+			expNames:  []string{expDirName},
+			expPaths:  []string{expDirName},
+			expErr:    nil,
+		},
+		{
+			name:      "should list a directory, if all inputs are correct",
+			givenPath: "/" + expDirName + "/",
+			expNames:  []string{expFileName},
+			expPaths:  []string{expDirName + "/" + expFileName},
+			expErr:    nil,
+		},
+		{
+			name:      "should fail, if could not find expected directory",
+			givenPath: "unexpectedDir",
+			expNames:  nil,
+			expPaths:  nil,
+			expErr:    fmt.Errorf("could not find directory: unexpectedDir"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			storageBrowser := NewMegaBrowser(login, pass, rootNodeName, &mockClient{}, &mockFs{}, &mockDownloader{})
+			storageBrowser.getChildren = mockGetChildren
+
+			nodes, err := storageBrowser.ListDirectory(test.givenPath)
+
+			var names, paths []string
+			for _, node := range nodes {
+				names = append(names, node.GetName())
+				paths = append(paths, node.GetPath())
+			}
+			assert.Equal(t, test.expNames, names)
+			assert.Equal(t, test.expPaths, paths)
+			assert.Equal(t, test.expErr, err)
+		})
+	}
+}
+
+func TestUpdateFileFromPath(t *testing.T) {
+	tests := []struct {
+		name        string
+		givenPath   string
+		downloadErr error
+		expErr      error
+	}{
+		{
+			name:        "should successfully update file, if all inputs are correct",
+			givenPath:   filepath.Join(expDirName, expFileName),
+			downloadErr: nil,
+			expErr:      nil,
+		},
+		{
+			name:        "should fail, if could not find expected file",
+			givenPath:   filepath.Join(expDirName, "unexpFile"),
+			downloadErr: nil,
+			expErr:      fmt.Errorf("could not find file: unexpFile"),
+		},
+		{
+			name:        "should fail, if could not download file",
+			givenPath:   filepath.Join(expDirName, expFileName),
+			downloadErr: errDownload,
+			expErr:      errDownload,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			storageBrowser := NewMegaBrowser(login, pass, rootNodeName, &mockClient{}, &mockFs{}, &mockDownloader{downloadErr: test.downloadErr})
+			storageBrowser.getChildren = mockGetChildren
+
+			err := storageBrowser.UpdateFileFromPath(test.givenPath, "local")
+
+			assert.Equal(t, test.expErr, err)
+		})
+	}
+}
+
+func TestUpdateFileFromPathDownloadsThroughMegaBackend(t *testing.T) {
+	client := &mockClient{}
+	fs := &mockFs{}
+	downloader := &mockDownloader{}
+	storageBrowser := NewMegaBrowser(login, pass, rootNodeName, client, fs, downloader)
+	storageBrowser.getChildren = mockGetChildren
+
+	err := storageBrowser.UpdateFileFromPath(filepath.Join(expDirName, expFileName), "local")
+
+	require.Nil(t, err)
+	require.Len(t, downloader.backends, 1)
+	backend, ok := downloader.backends[0].(*megaBackend)
+	require.True(t, ok)
+	assert.Same(t, client, backend.client)
+	assert.Same(t, fs, backend.fs)
+}
+
 func (m *mockClient) Login(login string, pass string) error {
 	return m.errLogin
 }
@@ -268,19 +650,38 @@ func (m *mockClient) DownloadFile(src *mega.Node, dstpath string, progress *chan
 	return m.errDownload
 }
 
+func (m *mockSessionClient) SessionToken() (string, error) {
+	return m.token, nil
+}
+
+func (m *mockSessionClient) ResumeSession(token string) error {
+	return m.errResume
+}
+
+func (m *mockMFAClient) MultiFactorLogin(login string, pass string, code string) error {
+	m.mfaCodes = append(m.mfaCodes, code)
+	return m.errMFALogin
+}
+
 func (m *mockFs) GetChildren(node *mega.Node) ([]*mega.Node, error) {
 	return m.children, m.errGetChildren
 }
 
 func (m *mockFs) GetRoot() *mega.Node {
-	return nil
+	return &mega.Node{}
 }
 
 func (m *mockFs) HashLookup(string) *mega.Node {
-	return nil
+	return &mega.Node{}
+}
+
+func (m *mockFs) Describe(node *mega.Node) Node {
+	return &mockNode{nodeType: fileType}
 }
 
-func (m *mockDownloader) DownloadFile(node *mega.Node, localDownloadPath string) error {
+func (m *mockDownloader) DownloadFromBackend(backend Backend, node Node, localDownloadPath string) error {
+	m.downloadedPaths = append(m.downloadedPaths, localDownloadPath)
+	m.backends = append(m.backends, backend)
 	return m.downloadErr
 }
 
@@ -0,0 +1,282 @@
+package megabrowser
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SyncStatus describes the state of a local file compared to its counterpart in the Mega repository.
+type SyncStatus string
+
+const (
+	// SyncStatusMissing means that the file does not exist locally.
+	SyncStatusMissing SyncStatus = "missing"
+	// SyncStatusOutdated means that the local file differs from the remote one.
+	SyncStatusOutdated SyncStatus = "outdated"
+	// SyncStatusUpToDate means that the local file matches the remote one.
+	SyncStatusUpToDate SyncStatus = "up-to-date"
+)
+
+// SyncEntry describes a single remote file of the project and the state of its local copy.
+type SyncEntry struct {
+	Path       string     `json:"path"`
+	Hash       string     `json:"hash"`
+	RemoteSize int64      `json:"remoteSize"`
+	LocalSize  int64      `json:"localSize"`
+	Status     SyncStatus `json:"status"`
+}
+
+// NeedsUpdate returns true, if the local file has to be downloaded to match the remote one.
+func (se SyncEntry) NeedsUpdate() bool {
+	return se.Status != SyncStatusUpToDate
+}
+
+/*
+Plan compares every file of the project in the Mega repository with its counterpart in localDir and returns the list of entries describing them, without downloading anything.
+
+A local file is considered outdated, if its size differs from the remote file size. Files not passing the browser filters are omitted, and so are files not listed in the manifest, if the browser has one, see WithManifest, e.g. files left behind by Publish after they were removed locally, because their downloads would fail verification.
+
+If the browser works on releases and localDir was rolled back, the browser switches to the release pinned in localDir first, see Rollback.
+
+Returns an error if:
+
+	failed to read the release history of localDir or to switch to the pinned release
+	an error occured while getting children of a node
+	a remote node name is not a safe local file name, e.g. it contains a path separator or "..", see ErrUnsafePath
+	failed to stat a local file for a reason other than it not existing
+*/
+func (mb *MegaBrowser) Plan(localDir string) ([]SyncEntry, error) {
+	err := mb.followPinnedRelease(localDir)
+	if err != nil {
+		return nil, err
+	}
+	return mb.plan(localDir)
+}
+
+// plan compares the files of the release the browser works on with localDir like Plan does, without following a pinned release.
+func (mb *MegaBrowser) plan(localDir string) ([]SyncEntry, error) {
+	var entries []SyncEntry
+	err := mb.walk(mb.rootNodeHash, "", func(remotePath string, node Node) error {
+		if !mb.filters.matches(remotePath) {
+			return nil
+		}
+		if mb.manifest != nil {
+			if _, ok := mb.manifest.lookup(remotePath); !ok {
+				return nil
+			}
+		}
+		entry := SyncEntry{
+			Path:       remotePath,
+			Hash:       node.GetHash(),
+			RemoteSize: node.GetSize(),
+			Status:     SyncStatusMissing,
+		}
+		info, err := os.Stat(filepath.Join(localDir, filepath.FromSlash(remotePath)))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				return err
+			}
+		} else {
+			entry.LocalSize = info.Size()
+			entry.Status = SyncStatusOutdated
+			if !info.IsDir() && entry.LocalSize == entry.RemoteSize {
+				entry.Status = SyncStatusUpToDate
+			}
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+/*
+Verify compares every file of the project with its counterpart in localDir like Plan does, and additionally compares SHA256 checksums of local files, whose size matches, with the manifest, if the browser has one, see WithManifest. A file whose checksum differs is outdated. Without a manifest only sizes are compared.
+
+Returns an error if:
+
+	failed to plan, see Plan
+	failed to read a local file
+*/
+func (mb *MegaBrowser) Verify(localDir string) ([]SyncEntry, error) {
+	entries, err := mb.Plan(localDir)
+	if err != nil || mb.manifest == nil {
+		return entries, err
+	}
+	for i, entry := range entries {
+		if entry.Status != SyncStatusUpToDate {
+			continue
+		}
+		expected, _ := mb.manifest.lookup(entry.Path)
+		checksum, err := fileChecksum(filepath.Join(localDir, filepath.FromSlash(entry.Path)))
+		if err != nil {
+			return nil, err
+		}
+		if checksum != expected.SHA256 {
+			entries[i].Status = SyncStatusOutdated
+		}
+	}
+	return entries, nil
+}
+
+/*
+Sync updates every missing or outdated file in localDir with its counterpart from the Mega repository, downloading up to the browser concurrency files at once. Returns the plan that was executed, see Plan.
+
+If the browser works on releases, the synchronized release is recorded in localDir, so that it can be rolled back later, see Rollback. A release pinned in localDir by Rollback is synchronized instead of the current one, see Unpin. If the browser has peers, files are fetched from them first, and shared with them once synchronized, see WithPeers.
+
+Returns an error if:
+
+	failed to plan the synchronization
+	failed to download any of the files or any of them does not match the manifest, in which case no further downloads are started
+	any of the files would be written outside localDir through a symbolic link, see ErrUnsafePath
+	failed to record the synchronized release
+*/
+func (mb *MegaBrowser) Sync(localDir string) ([]SyncEntry, error) {
+	return mb.SyncContext(context.Background(), localDir)
+}
+
+// SyncContext synchronizes localDir like Sync does, tracing it with a span, which is a child of the span in ctx, with a child span for every updated file, see WithTracerProvider.
+func (mb *MegaBrowser) SyncContext(ctx context.Context, localDir string) ([]SyncEntry, error) {
+	return mb.sync(ctx, localDir, mb.newReport())
+}
+
+// SyncReport synchronizes localDir like SyncContext does and returns the outcome of every file, see UpdateReport. The report is returned also if the synchronization fails, listing the files processed until then.
+func (mb *MegaBrowser) SyncReport(ctx context.Context, localDir string) (*UpdateReport, error) {
+	report := mb.newReport()
+	_, err := mb.sync(ctx, localDir, report)
+	return report, err
+}
+
+// sync synchronizes localDir, adding the outcome of every file to report, and returns the plan that was executed.
+func (mb *MegaBrowser) sync(ctx context.Context, localDir string, report *UpdateReport) (entries []SyncEntry, err error) {
+	ctx, span := mb.tracer.Start(ctx, "Sync", trace.WithAttributes(attrDir.String(localDir)))
+	defer func() { endSpan(span, err) }()
+	defer mb.finishReport(report)
+
+	start := mb.now()
+	entries, err = mb.Plan(localDir)
+	if err != nil {
+		mb.logger.Error("sync failed", "dir", localDir, "error", err)
+		return nil, err
+	}
+
+	err = mb.download(ctx, localDir, entries, false, report)
+	if err == nil {
+		mb.sharePeerFiles(localDir)
+		err = mb.recordRelease(localDir)
+	}
+	if err != nil {
+		mb.logger.Error("sync failed", "dir", localDir, "duration", mb.now().Sub(start), "error", err)
+		return entries, err
+	}
+	mb.logger.Info("synced", "dir", localDir, "files", len(entries), "updated", countUpdates(entries), "duration", mb.now().Sub(start))
+	return entries, nil
+}
+
+// countUpdates returns the number of given entries needing an update.
+func countUpdates(entries []SyncEntry) int {
+	count := 0
+	for _, entry := range entries {
+		if entry.NeedsUpdate() {
+			count++
+		}
+	}
+	return count
+}
+
+// download downloads files of given entries into localDir, from peers or the browser source, using up to the browser concurrency workers, and adds the outcome of every file to report, in the order of entries. Only entries needing an update are downloaded, unless all is true, and none is written outside localDir or the root directory of the downloader, see confinePath. Every download is traced with a span, which is a child of the span in ctx. Stops at the first error and returns it.
+func (mb *MegaBrowser) download(ctx context.Context, localDir string, entries []SyncEntry, all bool, report *UpdateReport) error {
+	ctx = withRootDir(ctx, localDir)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	files := make([]*FileReport, len(entries))
+	failed := make(chan struct{})
+	queue := make(chan int)
+	for i := 0; i < mb.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range queue {
+				entry := entries[index]
+				localPath := filepath.Join(localDir, filepath.FromSlash(entry.Path))
+				start := mb.now()
+				err := mb.confine(ctx, localPath)
+				if err == nil && mb.fetchFromPeers(ctx, entry.Path, localPath) {
+					file := mb.fileReport(entry.Path, localPath, SourcePeer, start, nil)
+					files[index] = &file
+					mb.metrics.countFiles(1, 0, 0)
+					continue
+				}
+				if err == nil {
+					err = mb.updateFile(ctx, entry.Path, entry.Hash, localPath)
+				}
+				file := mb.fileReport(entry.Path, localPath, mb.source(), start, err)
+				files[index] = &file
+				if err == nil {
+					mb.metrics.countFiles(1, 0, 0)
+				} else {
+					mb.metrics.countFiles(0, 0, 1)
+					once.Do(func() {
+						firstErr = err
+						close(failed)
+					})
+				}
+			}
+		}()
+	}
+
+enqueue:
+	for index, entry := range entries {
+		if !all && !entry.NeedsUpdate() {
+			files[index] = &FileReport{Path: entry.Path, Status: FileSkipped, Bytes: entry.RemoteSize}
+			mb.metrics.countFiles(0, 1, 0)
+			continue
+		}
+		select {
+		case queue <- index:
+		case <-failed:
+			break enqueue
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	for _, file := range files {
+		if file != nil {
+			report.Add(*file)
+		}
+	}
+	return firstErr
+}
+
+// walk recursively visits every file below the directory of given hash, calling fn with the file path relative to that directory.
+func (mb *MegaBrowser) walk(dirHash string, dirPath string, fn func(remotePath string, node Node) error) error {
+	childNodes, err := mb.children(dirHash, dirPath)
+	if err != nil {
+		return err
+	}
+
+	for _, child := range childNodes {
+		childPath := child.GetPath()
+		if err := validateNodeName(child.GetName()); err != nil {
+			return fmt.Errorf("%w in %q", err, dirPath)
+		}
+		if child.GetType() == fileType {
+			err = fn(childPath, child)
+		} else if isContainerType(child.GetType()) {
+			err = mb.walk(child.GetHash(), childPath, fn)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package megabrowser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name         string
+		localContent []byte
+		expStatus    SyncStatus
+	}{
+		{
+			name:         "should mark file as missing, if it does not exist locally",
+			localContent: nil,
+			expStatus:    SyncStatusMissing,
+		},
+		{
+			name:         "should mark file as outdated, if local size differs",
+			localContent: []byte("outdated"),
+			expStatus:    SyncStatusOutdated,
+		},
+		{
+			name:         "should mark file as up to date, if local size matches",
+			localContent: []byte("1"),
+			expStatus:    SyncStatusUpToDate,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			localDir := t.TempDir()
+			if test.localContent != nil {
+				writeLocalFile(t, localDir, test.localContent)
+			}
+			storageBrowser := NewMegaBrowser(login, pass, rootNodeName, &mockClient{}, &mockFs{}, &mockDownloader{})
+			storageBrowser.getChildren = mockGetChildrenWithSize
+
+			entries, err := storageBrowser.Plan(localDir)
+
+			require.Nil(t, err)
+			require.Len(t, entries, 1)
+			assert.Equal(t, expDirName+"/"+expFileName, entries[0].Path)
+			assert.Equal(t, expFileHash, entries[0].Hash)
+			assert.Equal(t, test.expStatus, entries[0].Status)
+		})
+	}
+}
+
+func TestPlanFailsIfCouldNotGetChildren(t *testing.T) {
+	storageBrowser := NewMegaBrowser(login, pass, rootNodeName, &mockClient{}, &mockFs{errGetChildren: errGetChildren}, &mockDownloader{})
+
+	entries, err := storageBrowser.Plan(t.TempDir())
+
+	assert.Nil(t, entries)
+	assert.Equal(t, errGetChildren, err)
+}
+
+func TestSync(t *testing.T) {
+	tests := []struct {
+		name         string
+		localContent []byte
+		downloadErr  error
+		expPaths     []string
+		expErr       error
+	}{
+		{
+			name:         "should download file, if it is missing",
+			localContent: nil,
+			downloadErr:  nil,
+			expPaths:     []string{filepath.Join(expDirName, expFileName)},
+			expErr:       nil,
+		},
+		{
+			name:         "should not download file, if it is up to date",
+			localContent: []byte("1"),
+			downloadErr:  nil,
+			expPaths:     nil,
+			expErr:       nil,
+		},
+		{
+			name:         "should fail, if could not download file",
+			localContent: nil,
+			downloadErr:  errDownload,
+			expPaths:     []string{filepath.Join(expDirName, expFileName)},
+			expErr:       errDownload,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			localDir := t.TempDir()
+			if test.localContent != nil {
+				writeLocalFile(t, localDir, test.localContent)
+			}
+			downloader := &mockDownloader{downloadErr: test.downloadErr}
+			storageBrowser := NewMegaBrowser(login, pass, rootNodeName, &mockClient{}, &mockFs{}, downloader)
+			storageBrowser.getChildren = mockGetChildrenWithSize
+
+			_, err := storageBrowser.Sync(localDir)
+
+			var expPaths []string
+			for _, path := range test.expPaths {
+				expPaths = append(expPaths, filepath.Join(localDir, path))
+			}
+			assert.Equal(t, expPaths, downloader.downloadedPaths)
+			assert.Equal(t, test.expErr, err)
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	repository := writeMirror(t, "1.0.0", map[string]string{"app.bin": "binary", "data.txt": "data"})
+	downloader := NewMegaDownloader()
+	downloader.progressOutput = nil
+	browser, err := New(
+		WithBackend(NewLocalBackend(repository)),
+		WithDownloader(downloader),
+		WithManifest(mirrorManifest),
+	)
+	require.Nil(t, err)
+	require.Nil(t, browser.Initialize())
+	localDir := t.TempDir()
+	_, err = browser.Sync(localDir)
+	require.Nil(t, err)
+	require.Nil(t, os.WriteFile(filepath.Join(localDir, "app.bin"), []byte("tamper"), 0666))
+
+	entries, err := browser.Verify(localDir)
+
+	require.Nil(t, err)
+	statuses := map[string]SyncStatus{}
+	for _, entry := range entries {
+		statuses[entry.Path] = entry.Status
+	}
+	assert.Equal(t, map[string]SyncStatus{
+		"app.bin":                        SyncStatusOutdated,
+		"data.txt":                       SyncStatusUpToDate,
+		mirrorManifest:                   SyncStatusUpToDate,
+		mirrorManifest + signatureSuffix: SyncStatusUpToDate,
+	}, statuses)
+}
+
+func TestPlanOmitsFilesNotListedInManifest(t *testing.T) {
+	repository := writeMirror(t, "1.0.0", map[string]string{"app.bin": "binary"})
+	require.Nil(t, os.WriteFile(filepath.Join(repository, "removed.bin"), []byte("removed"), 0666))
+	browser, err := New(
+		WithBackend(NewLocalBackend(repository)),
+		WithManifest(mirrorManifest),
+	)
+	require.Nil(t, err)
+	require.Nil(t, browser.Initialize())
+
+	entries, err := browser.Plan(t.TempDir())
+
+	require.Nil(t, err)
+	var paths []string
+	for _, entry := range entries {
+		paths = append(paths, entry.Path)
+	}
+	assert.Equal(t, []string{"app.bin", mirrorManifest, mirrorManifest + signatureSuffix}, paths)
+}
+
+func TestSyncLogs(t *testing.T) {
+	logger, output := newTestLogger()
+	downloader := NewMegaDownloader()
+	downloader.progressOutput = nil
+	downloader.SetLogger(logger)
+	browser, err := New(
+		WithBackend(NewLocalBackend(writeLocalRepository(t))),
+		WithDownloader(downloader),
+		WithLogger(logger),
+	)
+	require.Nil(t, err)
+	require.Nil(t, browser.Initialize())
+	localDir := t.TempDir()
+
+	_, err = browser.Sync(localDir)
+
+	require.Nil(t, err)
+	records := map[string]map[string]interface{}{}
+	for _, record := range decodeLogRecords(t, output) {
+		records[record["msg"].(string)] = record
+	}
+	require.Contains(t, records, "initialized")
+	require.Contains(t, records, "file downloaded")
+	assert.Contains(t, records["file downloaded"]["path"], localDir)
+	require.Contains(t, records, "synced")
+	assert.Equal(t, localDir, records["synced"]["dir"])
+	assert.Equal(t, records["synced"]["files"], records["synced"]["updated"])
+}
+
+func writeLocalFile(t *testing.T, localDir string, content []byte) {
+	err := os.MkdirAll(filepath.Join(localDir, expDirName), 0777)
+	require.Nil(t, err)
+	err = os.WriteFile(filepath.Join(localDir, expDirName, expFileName), content, 0666)
+	require.Nil(t, err)
+}
+
+func mockGetChildrenWithSize(fs Fs, nodeHash string) ([]Node, error) {
+	nodes, err := mockGetChildren(fs, nodeHash)
+	for _, node := range nodes {
+		if node.GetType() == fileType {
+			node.(*mockNode).size = 1
+		}
+	}
+	return nodes, err
+}
@@ -0,0 +1,87 @@
+package megabrowser
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation name of tracers of browsers and downloaders.
+const tracerName = "Mic-Cie/mega-browser/MegaBrowser"
+
+// Attribute keys of the spans.
+const (
+	attrRoot    = attribute.Key("megabrowser.root")
+	attrDir     = attribute.Key("megabrowser.dir")
+	attrPath    = attribute.Key("megabrowser.path")
+	attrName    = attribute.Key("megabrowser.name")
+	attrHash    = attribute.Key("megabrowser.hash")
+	attrBytes   = attribute.Key("megabrowser.bytes")
+	attrAttempt = attribute.Key("megabrowser.attempt")
+)
+
+/*
+ContextDownloader is a Downloader able to continue traces of a caller-supplied context, see WithTracerProvider. MegaBrowser downloads through the downloader returned by WithContext, if its downloader implements it, like MegaDownloader does.
+
+WithContext returns a downloader, whose spans are children of the span in ctx, and which implements the same interfaces as the original one.
+*/
+type ContextDownloader interface {
+	Downloader
+	WithContext(ctx context.Context) Downloader
+}
+
+// defaultTracer returns a tracer of the global provider, see otel.SetTracerProvider, which does nothing until an application sets a provider.
+func defaultTracer() trace.Tracer {
+	return otel.GetTracerProvider().Tracer(tracerName)
+}
+
+// WithTracerProvider makes the browser trace Initialize, login, every path resolution step and every synchronized file with given provider, instead of the global one. Spans of the methods taking a context, e.g. InitializeContext, are children of the span in that context. A MegaDownloader has its own provider, usually the same one, see MegaDownloader.SetTracerProvider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(mb *MegaBrowser) error {
+		if provider == nil {
+			return fmt.Errorf("tracer provider must not be nil")
+		}
+		mb.tracer = provider.Tracer(tracerName)
+		return nil
+	}
+}
+
+// SetTracerProvider makes the downloader trace every download, with child spans for removing the outdated file, creating its directory and every transfer attempt, with given provider instead of the global one. A nil provider restores the global one.
+func (md *MegaDownloader) SetTracerProvider(provider trace.TracerProvider) {
+	if provider == nil {
+		md.tracer = defaultTracer()
+		return
+	}
+	md.tracer = provider.Tracer(tracerName)
+}
+
+// WithContext returns a copy of the downloader, whose spans are children of the span in ctx. The copy shares the cache, bandwidth, logger and metrics of the downloader.
+func (md *MegaDownloader) WithContext(ctx context.Context) Downloader {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	copied := *md
+	copied.ctx = ctx
+	return &copied
+}
+
+// contextDownloader returns the browser downloader continuing traces of ctx, if it implements ContextDownloader, otherwise the downloader itself.
+func (mb *MegaBrowser) contextDownloader(ctx context.Context) Downloader {
+	if downloader, ok := mb.downloader.(ContextDownloader); ok {
+		return downloader.WithContext(ctx)
+	}
+	return mb.downloader
+}
+
+// endSpan records err in span, if it is not nil, and ends the span.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
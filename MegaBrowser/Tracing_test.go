@@ -0,0 +1,190 @@
+package megabrowser
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestInitializeContextTracesLogin(t *testing.T) {
+	tests := []struct {
+		name       string
+		loginError error
+		expStatus  codes.Code
+	}{
+		{
+			name:      "should trace initialization and login, if login succeeds",
+			expStatus: codes.Unset,
+		},
+		{
+			name:       "should record the error in both spans, if login fails",
+			loginError: errLogin,
+			expStatus:  codes.Error,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			provider, recorder := newTestTracerProvider()
+			browser, err := New(
+				WithCredentials(login, pass),
+				WithRootNode(rootNodeName),
+				WithClient(&mockClient{errLogin: test.loginError}),
+				WithFs(&mockFs{}),
+				WithDownloader(&mockDownloader{}),
+				WithRootNodeHashFunc(mockGetRootNodeHash),
+				WithTracerProvider(provider),
+			)
+			require.Nil(t, err)
+			ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+
+			err = browser.InitializeContext(ctx)
+			parent.End()
+
+			assert.Equal(t, test.loginError, err)
+			spans := spansByName(recorder)
+			require.Contains(t, spans, "Initialize")
+			require.Contains(t, spans, "login")
+			initialize := spans["Initialize"][0]
+			assert.Equal(t, spans["parent"][0].SpanContext().SpanID(), initialize.Parent().SpanID())
+			assert.Equal(t, initialize.SpanContext().SpanID(), spans["login"][0].Parent().SpanID())
+			assert.Equal(t, test.expStatus, initialize.Status().Code)
+			assert.Equal(t, test.expStatus, spans["login"][0].Status().Code)
+		})
+	}
+}
+
+func TestStatContextTracesResolutionSteps(t *testing.T) {
+	provider, recorder := newTestTracerProvider()
+	browser, err := New(
+		WithRootNode(rootNodeName),
+		WithFs(&mockFs{}),
+		WithChildrenFunc(mockGetChildren),
+		WithTracerProvider(provider),
+	)
+	require.Nil(t, err)
+	browser.rootNodeHash = expRootNodeHash
+
+	_, err = browser.StatContext(context.Background(), expDirName+"/"+expFileName)
+
+	require.Nil(t, err)
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	for i, expName := range []string{expDirName, expFileName} {
+		assert.Equal(t, "resolve", spans[i].Name())
+		assert.Contains(t, spans[i].Attributes(), attrName.String(expName))
+	}
+	assert.Contains(t, spans[0].Attributes(), attrHash.String(expDirHash))
+	assert.Contains(t, spans[1].Attributes(), attrHash.String(expFileHash))
+}
+
+func TestSyncContextTracesDownloads(t *testing.T) {
+	provider, recorder := newTestTracerProvider()
+	repository := writeMirror(t, "1.0.0", map[string]string{"app.bin": "binary"})
+	require.Nil(t, os.WriteFile(filepath.Join(repository, "app.bin"), []byte("binary"), 0666))
+	downloader := NewMegaDownloader()
+	downloader.progressOutput = nil
+	downloader.SetTracerProvider(provider)
+	browser, err := New(
+		WithBackend(NewLocalBackend(repository)),
+		WithDownloader(downloader),
+		WithManifest(mirrorManifest),
+		WithTracerProvider(provider),
+	)
+	require.Nil(t, err)
+	require.Nil(t, browser.Initialize())
+	localDir := t.TempDir()
+
+	_, err = browser.SyncContext(context.Background(), localDir)
+
+	require.Nil(t, err)
+	spans := spansByName(recorder)
+	require.Len(t, spans["Sync"], 1)
+	sync := spans["Sync"][0]
+	assert.Contains(t, sync.Attributes(), attrDir.String(localDir))
+	var update sdktrace.ReadOnlySpan
+	for _, span := range spans["update"] {
+		assert.Equal(t, sync.SpanContext().SpanID(), span.Parent().SpanID())
+		if hasAttribute(span, attrPath.String("app.bin")) {
+			update = span
+		}
+	}
+	require.NotNil(t, update)
+	download := childSpan(t, spans["download"], update)
+	assert.Contains(t, download.Attributes(), attrPath.String(filepath.Join(localDir, "app.bin")))
+	for _, name := range []string{"remove", "mkdir", "transfer"} {
+		childSpan(t, spans[name], download)
+	}
+	verify := childSpan(t, spans["verify"], update)
+	assert.Contains(t, verify.Attributes(), attrBytes.Int64(6))
+}
+
+func TestDownloadFileTracesFailedAttempts(t *testing.T) {
+	provider, recorder := newTestTracerProvider()
+	client := &flakyClient{failures: 1}
+	downloader := NewMegaDownloader()
+	downloader.getNodeSize = mockGetNodeSize
+	downloader.getNodeHash = mockGetNodeHash
+	downloader.progressOutput = nil
+	downloader.retryAttempts = 2
+	downloader.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+	downloader.SetTracerProvider(provider)
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+
+	err := downloadFile(downloader.WithContext(ctx), client, filepath.Join(t.TempDir(), "file.txt"))
+	parent.End()
+
+	require.Nil(t, err)
+	spans := spansByName(recorder)
+	download := childSpan(t, spans["download"], spans["parent"][0])
+	assert.Contains(t, download.Attributes(), attrHash.String("hash"))
+	require.Len(t, spans["transfer"], 2)
+	assert.Equal(t, codes.Error, spans["transfer"][0].Status().Code)
+	assert.Contains(t, spans["transfer"][0].Attributes(), attrAttempt.Int(1))
+	assert.Equal(t, codes.Unset, spans["transfer"][1].Status().Code)
+	assert.Contains(t, spans["transfer"][1].Attributes(), attrAttempt.Int(2))
+	assert.Equal(t, codes.Unset, download.Status().Code)
+}
+
+// newTestTracerProvider creates a tracer provider recording every span in the returned recorder.
+func newTestTracerProvider() (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	return sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)), recorder
+}
+
+// spansByName groups ended spans of the recorder by their names.
+func spansByName(recorder *tracetest.SpanRecorder) map[string][]sdktrace.ReadOnlySpan {
+	spans := map[string][]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = append(spans[span.Name()], span)
+	}
+	return spans
+}
+
+// childSpan returns the span among spans, whose parent is given span, failing the test if there is none.
+func childSpan(t *testing.T, spans []sdktrace.ReadOnlySpan, parent sdktrace.ReadOnlySpan) sdktrace.ReadOnlySpan {
+	for _, span := range spans {
+		if span.Parent().SpanID() == parent.SpanContext().SpanID() {
+			return span
+		}
+	}
+	require.Fail(t, "child span not found", "parent: %s", parent.Name())
+	return nil
+}
+
+func hasAttribute(span sdktrace.ReadOnlySpan, expected attribute.KeyValue) bool {
+	for _, attr := range span.Attributes() {
+		if attr == expected {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package megabrowser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+/*
+ListVersions takes path to a file and returns its version history from the Mega repository, starting with the current version and followed by the previous ones, from the newest to the oldest.
+
+Mega keeps a previous version of a file as a child node of the newer version, so the history is read by following the file nodes down from the current one. Every version has the path of the current one.
+
+Returns an error if:
+
+	could not find the file, see Stat
+	given path is a directory
+	an error occured while getting children of a node
+*/
+func (mb *MegaBrowser) ListVersions(path string) ([]Node, error) {
+	node, err := mb.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if node.GetType() != fileType {
+		return nil, fmt.Errorf("not a file: %s", path)
+	}
+
+	versions := []Node{node}
+	for {
+		children, err := mb.listChildren(node.GetHash())
+		if err != nil {
+			return nil, err
+		}
+		previous := previousVersion(children)
+		if previous == nil {
+			return versions, nil
+		}
+		node = pathNode{Node: previous, path: node.GetPath()}
+		versions = append(versions, node)
+	}
+}
+
+/*
+UpdateFileFromVersion updates a file at specified localDownloadPath with a version of the file at given path, identified by its hash, see ListVersions. The file is downloaded through the browser downloader, like with UpdateFileFromPath.
+
+Returns an error if:
+
+	could not list versions of the file, see ListVersions
+	given hash is not a version of the file
+	failed to download the file
+*/
+func (mb *MegaBrowser) UpdateFileFromVersion(path string, versionHash string, localDownloadPath string) error {
+	versions, err := mb.ListVersions(path)
+	if err != nil {
+		return err
+	}
+	for _, version := range versions {
+		if version.GetHash() == versionHash {
+			return mb.updateFileByHash(context.Background(), versionHash, localDownloadPath)
+		}
+	}
+	return fmt.Errorf("could not find version %s of file: %s", versionHash, path)
+}
+
+// UpdateFileFromVersionReport updates a file like UpdateFileFromVersion does and returns its outcome, see UpdateReport. The report is returned also if the update fails.
+func (mb *MegaBrowser) UpdateFileFromVersionReport(path string, versionHash string, localDownloadPath string) (*UpdateReport, error) {
+	report := mb.newReport()
+	err := mb.UpdateFileFromVersion(path, versionHash, localDownloadPath)
+	report.Add(mb.fileReport(strings.Join(mb.splitPath(path), "/"), localDownloadPath, mb.source(), report.Started, err))
+	mb.finishReport(report)
+	return report, err
+}
+
+// previousVersion returns the file node among children of a file node, which is its previous version. Returns nil, if there is none.
+func previousVersion(children []Node) Node {
+	for _, child := range children {
+		if child.GetType() == fileType {
+			return child
+		}
+	}
+	return nil
+}
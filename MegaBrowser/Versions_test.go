@@ -0,0 +1,122 @@
+package megabrowser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListVersions(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		expVersions []string
+		expErr      string
+	}{
+		{
+			name:        "should list current and previous versions, if file has history",
+			path:        "asset.png",
+			expVersions: []string{"asset-v3", "asset-v2", "asset-v1"},
+		},
+		{
+			name:        "should list current version only, if file has no history",
+			path:        expDirName + "/" + expFileName,
+			expVersions: []string{expFileHash},
+		},
+		{
+			name:   "should fail, if path is a directory",
+			path:   expDirName,
+			expErr: "not a file: " + expDirName,
+		},
+		{
+			name:   "should fail, if file does not exist",
+			path:   "missing.png",
+			expErr: "could not find object: missing.png",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			browser := newVersionsBrowser(t, &mockDownloader{})
+
+			versions, err := browser.ListVersions(test.path)
+
+			if test.expErr != "" {
+				require.NotNil(t, err)
+				assert.Equal(t, test.expErr, err.Error())
+				return
+			}
+			require.Nil(t, err)
+			var hashes []string
+			for _, version := range versions {
+				hashes = append(hashes, version.GetHash())
+				assert.Equal(t, test.path, version.GetPath())
+			}
+			assert.Equal(t, test.expVersions, hashes)
+		})
+	}
+}
+
+func TestUpdateFileFromVersion(t *testing.T) {
+	tests := []struct {
+		name         string
+		versionHash  string
+		expDownloads []string
+		expErr       string
+	}{
+		{
+			name:         "should download the previous version, if it belongs to the file history",
+			versionHash:  "asset-v2",
+			expDownloads: []string{"restored.png"},
+		},
+		{
+			name:        "should fail, if hash is not a version of the file",
+			versionHash: expFileHash,
+			expErr:      "could not find version " + expFileHash + " of file: asset.png",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			downloader := &mockDownloader{}
+			browser := newVersionsBrowser(t, downloader)
+
+			err := browser.UpdateFileFromVersion("asset.png", test.versionHash, "restored.png")
+
+			if test.expErr != "" {
+				require.NotNil(t, err)
+				assert.Equal(t, test.expErr, err.Error())
+			} else {
+				assert.Nil(t, err)
+			}
+			assert.Equal(t, test.expDownloads, downloader.downloadedPaths)
+		})
+	}
+}
+
+func newVersionsBrowser(t *testing.T, downloader *mockDownloader) *MegaBrowser {
+	browser, err := New(
+		WithClient(&mockClient{}),
+		WithFs(&mockFs{}),
+		WithDownloader(downloader),
+		WithChildrenFunc(mockGetVersionsChildren),
+	)
+	require.Nil(t, err)
+	browser.rootNodeHash = expRootNodeHash
+	return browser
+}
+
+// mockGetVersionsChildren returns children of the mock repository, extended with a file having two previous versions.
+func mockGetVersionsChildren(fs Fs, nodeHash string) ([]Node, error) {
+	switch nodeHash {
+	case expRootNodeHash:
+		nodes, err := mockGetChildren(fs, nodeHash)
+		return append(nodes, &mockNode{name: "asset.png", nodeType: fileType, hash: "asset-v3"}), err
+	case "asset-v3":
+		return []Node{&mockNode{name: "asset.png", nodeType: fileType, hash: "asset-v2"}}, nil
+	case "asset-v2":
+		return []Node{&mockNode{name: "asset.png", nodeType: fileType, hash: "asset-v1"}}, nil
+	case "asset-v1", expFileHash:
+		return nil, nil
+	}
+	return mockGetChildren(fs, nodeHash)
+}
@@ -0,0 +1,809 @@
+/*
+Package megatest provides an in-memory fake of a Mega account for tests of code built on megabrowser package, so that update flows can be tested deterministically without network.
+
+A Fake implements megabrowser.StorageClient, SessionClient, MFAClient, UploadClient and QuotaClient, as well as megabrowser.Fs and megabrowser.Backend, so it can replace both the client created with mega.New() from t3rm1n4l/go-mega package and its FS parameter:
+
+	fake := megatest.NewFromMap(map[string]string{"project/app.bin": "content"})
+	browser := megabrowser.NewMegaBrowser("login", "password", "project", fake, fake, megabrowser.NewMegaDownloader())
+
+Nodes of the fake are mega.Node structures without any attributes, which cannot be set outside of t3rm1n4l/go-mega package, so the fake describes them itself, see megabrowser.NodeFs. Code calling methods of mega.Node directly does not work with the fake.
+*/
+package megatest
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	megabrowser "Mic-Cie/mega-browser/MegaBrowser"
+
+	"github.com/t3rm1n4l/go-mega"
+)
+
+// chunkSize is the size of the chunks, in which the fake transfers files, reporting progress after every one.
+const chunkSize = 65536
+
+// Fake is an in-memory Mega account. It is safe for concurrent use.
+type Fake struct {
+	mu       sync.Mutex
+	entries  map[string]*entry
+	handles  map[*mega.Node]*entry
+	root     *entry
+	trash    *entry
+	lastHash int
+
+	login     string
+	password  string
+	mfaCode   string
+	loginErr  error
+	loggedIn  bool
+	latency   time.Duration
+	rateLimit int
+	storage   uint64
+	transfer  uint64
+	requests  int
+	sleep     func(time.Duration)
+	now       func() time.Time
+
+	faults      map[string]*fault
+	downloads   []string
+	transferred uint64
+}
+
+// Option configures a Fake.
+type Option func(f *Fake)
+
+// entry is a node of the fake account.
+type entry struct {
+	handle    *mega.Node
+	hash      string
+	name      string
+	nodeType  int
+	content   []byte
+	timestamp time.Time
+	parent    *entry
+	children  []*entry
+}
+
+// node is a snapshot of an entry, implementing megabrowser.Node.
+type node struct {
+	hash      string
+	parent    string
+	name      string
+	nodeType  int
+	size      int64
+	timestamp time.Time
+}
+
+// fault describes how the next downloads of a file fail. Downloads fail with err, or are truncated after size bytes, if err is nil.
+type fault struct {
+	times int
+	err   error
+	size  int
+}
+
+// New creates an empty fake account, configured with given options.
+func New(opts ...Option) *Fake {
+	f := &Fake{
+		entries: map[string]*entry{},
+		handles: map[*mega.Node]*entry{},
+		faults:  map[string]*fault{},
+		sleep:   time.Sleep,
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	f.root = f.newEntry("Cloud Drive", mega.ROOT, nil, nil)
+	f.trash = f.newEntry("Rubbish Bin", mega.TRASH, nil, nil)
+	return f
+}
+
+// NewFromMap creates a fake account containing files of given paths and contents. Paths are slash separated and relative to the cloud drive root, paths ending with a slash are empty directories.
+func NewFromMap(files map[string]string, opts ...Option) *Fake {
+	f := New(opts...)
+	paths := make([]string, 0, len(files))
+	for filePath := range files {
+		paths = append(paths, filePath)
+	}
+	sort.Strings(paths)
+	for _, filePath := range paths {
+		var err error
+		if strings.HasSuffix(filePath, "/") {
+			err = f.AddDir(filePath)
+		} else {
+			err = f.AddFile(filePath, []byte(files[filePath]))
+		}
+		if err != nil {
+			panic(err)
+		}
+	}
+	return f
+}
+
+/*
+NewFromDir creates a fake account containing a copy of every directory and regular file inside given local directory, which becomes the cloud drive root.
+
+Returns an error if the directory could not be read.
+*/
+func NewFromDir(dir string, opts ...Option) (*Fake, error) {
+	f := New(opts...)
+	err := filepath.WalkDir(dir, func(localPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, localPath)
+		if err != nil || relPath == "." {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if d.IsDir() {
+			return f.AddDir(relPath)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		content, err := os.ReadFile(localPath)
+		if err != nil {
+			return err
+		}
+		return f.AddFile(relPath, content)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// WithCredentials makes Login accept only given login and password, failing with mega.ENOENT otherwise. Any credentials are accepted by default.
+func WithCredentials(login string, password string) Option {
+	return func(f *Fake) {
+		f.login = login
+		f.password = password
+	}
+}
+
+// WithMFA makes the account require given multi-factor authentication code, so that Login fails with mega.EMFAREQUIRED and MultiFactorLogin has to be used instead.
+func WithMFA(code string) Option {
+	return func(f *Fake) {
+		f.mfaCode = code
+	}
+}
+
+// WithLatency makes every request to the fake, i.e. every client operation and download, take at least given time.
+func WithLatency(latency time.Duration) Option {
+	return func(f *Fake) {
+		f.latency = latency
+	}
+}
+
+// WithRateLimit makes every request after given number of successful requests fail with mega.ERATELIMIT, after which the count starts again, so that a retried request succeeds.
+func WithRateLimit(requests int) Option {
+	return func(f *Fake) {
+		f.rateLimit = requests
+	}
+}
+
+// WithStorageQuota sets the storage quota of the account in bytes, reported by GetQuota.
+func WithStorageQuota(total uint64) Option {
+	return func(f *Fake) {
+		f.storage = total
+	}
+}
+
+// WithTransferQuota sets the transfer quota of the account in bytes, reported by GetQuota.
+func WithTransferQuota(total uint64) Option {
+	return func(f *Fake) {
+		f.transfer = total
+	}
+}
+
+// WithClock sets function returning the current time, which is used as the timestamp of added nodes, instead of time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(f *Fake) {
+		f.now = now
+	}
+}
+
+// WithSleep sets function used to simulate latency instead of time.Sleep, e.g. to advance a fake clock.
+func WithSleep(sleep func(time.Duration)) Option {
+	return func(f *Fake) {
+		f.sleep = sleep
+	}
+}
+
+/*
+AddFile adds a file of given path and content, creating its missing parent directories. If a file of that path exists, it becomes the previous version of the new one, like Mega keeps versions of a replaced file.
+
+Returns an error if the path is empty or any of its parents is a file.
+*/
+func (f *Fake) AddFile(filePath string, content []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	dir, name := path.Split(strings.Trim(filePath, "/"))
+	if name == "" {
+		return fmt.Errorf("megatest: empty file path")
+	}
+	parent, err := f.mkdirAll(dir)
+	if err != nil {
+		return err
+	}
+	previous := child(parent, name)
+	if previous != nil && previous.nodeType != mega.FILE {
+		return fmt.Errorf("megatest: not a file: %s", filePath)
+	}
+
+	file := f.newEntry(name, mega.FILE, parent, content)
+	if previous != nil {
+		detach(previous)
+		previous.parent = file
+		file.children = append(file.children, previous)
+	}
+	return nil
+}
+
+/*
+AddDir adds a directory of given path, creating its missing parent directories. Does nothing, if the directory exists.
+
+Returns an error if any element of the path is a file.
+*/
+func (f *Fake) AddDir(dirPath string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, err := f.mkdirAll(dirPath)
+	return err
+}
+
+// FailDownload makes the next given number of downloads of a file of given path fail with err, without writing anything. Negative times makes every download fail.
+func (f *Fake) FailDownload(filePath string, times int, err error) {
+	f.setFault(filePath, &fault{times: times, err: err})
+}
+
+// TruncateDownload makes the next given number of downloads of a file of given path write only its first size bytes and fail with io.ErrUnexpectedEOF, leaving the partial file behind. Negative times truncates every download.
+func (f *Fake) TruncateDownload(filePath string, times int, size int) {
+	f.setFault(filePath, &fault{times: times, size: size})
+}
+
+// SetLoginError makes every following login fail with err, or succeed again, if err is nil.
+func (f *Fake) SetLoginError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.loginErr = err
+}
+
+// Downloads returns paths of the files downloaded so far, including failed downloads, in the order they were requested.
+func (f *Fake) Downloads() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.downloads...)
+}
+
+// Content returns content of a file of given path, e.g. uploaded by Publisher. Returns false, if there is no such file.
+func (f *Fake) Content(filePath string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := f.lookup(filePath)
+	if err != nil || file.nodeType != mega.FILE {
+		return nil, false
+	}
+	return append([]byte{}, file.content...), true
+}
+
+// Trashed returns names of the nodes in the rubbish bin, e.g. files replaced by Publisher.
+func (f *Fake) Trashed() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var names []string
+	for _, trashed := range f.trash.children {
+		names = append(names, trashed.name)
+	}
+	return names
+}
+
+// Login logs in to the account, see WithCredentials and WithMFA.
+func (f *Fake) Login(login string, pass string) error {
+	err := f.request()
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	err = f.checkCredentials(login, pass)
+	if err != nil {
+		return err
+	}
+	if f.mfaCode != "" {
+		return mega.EMFAREQUIRED
+	}
+	f.loggedIn = true
+	return nil
+}
+
+// MultiFactorLogin logs in to the account with a multi-factor authentication code, failing with mega.ENOENT if the code does not match the one given with WithMFA.
+func (f *Fake) MultiFactorLogin(login string, pass string, code string) error {
+	err := f.request()
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	err = f.checkCredentials(login, pass)
+	if err != nil {
+		return err
+	}
+	if code != f.mfaCode {
+		return mega.ENOENT
+	}
+	f.loggedIn = true
+	return nil
+}
+
+// SessionToken returns a token of the current session, which can be resumed with ResumeSession.
+func (f *Fake) SessionToken() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.loggedIn {
+		return "", mega.EACCESS
+	}
+	return "megatest-session:" + f.login, nil
+}
+
+// ResumeSession logs in with a token returned by SessionToken, failing with mega.ESID for any other token or if logins fail, see SetLoginError.
+func (f *Fake) ResumeSession(token string) error {
+	err := f.request()
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.loginErr != nil || token != "megatest-session:"+f.login {
+		return mega.ESID
+	}
+	f.loggedIn = true
+	return nil
+}
+
+// DownloadFile downloads a file node to dstpath, see Download.
+func (f *Fake) DownloadFile(src *mega.Node, dstpath string, progress *chan int) error {
+	f.mu.Lock()
+	file, ok := f.handles[src]
+	loggedIn := f.loggedIn
+	f.mu.Unlock()
+	if !ok || !loggedIn {
+		if progress != nil {
+			close(*progress)
+		}
+		if !loggedIn {
+			return mega.EACCESS
+		}
+		return mega.ENOENT
+	}
+	return f.Download(file.hash, dstpath, progress)
+}
+
+// GetQuota returns the storage quota set with WithStorageQuota and the total size of the files of the account, including previous versions and files in the rubbish bin, as well as the transfer quota set with WithTransferQuota and the bytes downloaded so far.
+func (f *Fake) GetQuota() (mega.QuotaResp, error) {
+	err := f.clientRequest()
+	if err != nil {
+		return mega.QuotaResp{}, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var used uint64
+	for _, e := range f.entries {
+		used += uint64(len(e.content))
+	}
+	return mega.QuotaResp{Mstrg: f.storage, Cstrg: used, Mxfer: f.transfer, Caxfer: f.transferred}, nil
+}
+
+// UploadFile uploads a local file into a parent directory. A file of the same name is not replaced, both files exist afterwards, like with the client from t3rm1n4l/go-mega package.
+func (f *Fake) UploadFile(srcpath string, parent *mega.Node, name string, progress *chan int) (*mega.Node, error) {
+	if progress != nil {
+		defer close(*progress)
+	}
+	err := f.clientRequest()
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(srcpath)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dir, ok := f.handles[parent]
+	if !ok || dir.nodeType == mega.FILE {
+		return nil, mega.ENOENT
+	}
+	file := f.newEntry(name, mega.FILE, dir, content)
+	if progress != nil {
+		*progress <- len(content)
+	}
+	return file.handle, nil
+}
+
+// CreateDir creates a directory of given name in a parent directory.
+func (f *Fake) CreateDir(name string, parent *mega.Node) (*mega.Node, error) {
+	err := f.clientRequest()
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dir, ok := f.handles[parent]
+	if !ok || dir.nodeType == mega.FILE {
+		return nil, mega.ENOENT
+	}
+	return f.newEntry(name, mega.FOLDER, dir, nil).handle, nil
+}
+
+// Delete moves a node to the rubbish bin, or removes it with all its children, if destroy is true.
+func (f *Fake) Delete(node *mega.Node, destroy bool) error {
+	err := f.clientRequest()
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	deleted, ok := f.handles[node]
+	if !ok || deleted.parent == nil {
+		return mega.ENOENT
+	}
+	detach(deleted)
+	if destroy {
+		f.forget(deleted)
+		return nil
+	}
+	deleted.parent = f.trash
+	f.trash.children = append(f.trash.children, deleted)
+	return nil
+}
+
+// GetChildren returns child nodes of a node, which are previous versions for a file node.
+func (f *Fake) GetChildren(n *mega.Node) ([]*mega.Node, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	parent, ok := f.handles[n]
+	if !ok {
+		return nil, mega.ENOENT
+	}
+	children := make([]*mega.Node, len(parent.children))
+	for i, child := range parent.children {
+		children[i] = child.handle
+	}
+	return children, nil
+}
+
+// GetRoot returns the root node of the cloud drive.
+func (f *Fake) GetRoot() *mega.Node {
+	return f.root.handle
+}
+
+// HashLookup returns the node of given hash, or nil if there is none.
+func (f *Fake) HashLookup(hash string) *mega.Node {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if found, ok := f.entries[hash]; ok {
+		return found.handle
+	}
+	return nil
+}
+
+// Describe returns attributes of a node of the fake, see megabrowser.NodeFs. A node not belonging to the fake has no attributes.
+func (f *Fake) Describe(n *mega.Node) megabrowser.Node {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	described, ok := f.handles[n]
+	if !ok {
+		return node{}
+	}
+	return described.snapshot()
+}
+
+// Root returns the root node of the cloud drive, see megabrowser.Backend.
+func (f *Fake) Root() megabrowser.Node {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.root.snapshot()
+}
+
+// Children returns child nodes of a node of given hash, see megabrowser.Backend.
+func (f *Fake) Children(hash string) ([]megabrowser.Node, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	parent, ok := f.entries[hash]
+	if !ok {
+		return nil, mega.ENOENT
+	}
+	children := make([]megabrowser.Node, len(parent.children))
+	for i, child := range parent.children {
+		children[i] = child.snapshot()
+	}
+	return children, nil
+}
+
+// Stat returns the node of given hash, see megabrowser.Backend.
+func (f *Fake) Stat(hash string) (megabrowser.Node, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	found, ok := f.entries[hash]
+	if !ok {
+		return nil, mega.ENOENT
+	}
+	return found.snapshot(), nil
+}
+
+/*
+Download writes content of a file of given hash to dstPath, sending the number of written bytes to progress, if it is not nil, and closing it when finished. Unlike DownloadFile, it does not require a login, see megabrowser.Backend.
+
+Returns an error if:
+
+	the request is rate limited, see WithRateLimit
+	there is no file of given hash
+	the download was made to fail, see FailDownload and TruncateDownload
+	failed to write dstPath
+*/
+func (f *Fake) Download(hash string, dstPath string, progress *chan int) error {
+	if progress != nil {
+		defer close(*progress)
+	}
+	err := f.request()
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	file, ok := f.entries[hash]
+	if !ok || file.nodeType != mega.FILE {
+		f.mu.Unlock()
+		return mega.ENOENT
+	}
+	filePath := file.path()
+	f.downloads = append(f.downloads, filePath)
+	content := file.content
+	var truncateErr error
+	if fault := f.takeFault(filePath); fault != nil {
+		if fault.err != nil {
+			f.mu.Unlock()
+			return fault.err
+		}
+		content = content[:min(fault.size, len(content))]
+		truncateErr = io.ErrUnexpectedEOF
+	}
+	f.transferred += uint64(len(content))
+	f.mu.Unlock()
+
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	for written := 0; written < len(content); {
+		n := min(chunkSize, len(content)-written)
+		_, err = dst.Write(content[written : written+n])
+		if err != nil {
+			return err
+		}
+		written += n
+		if progress != nil {
+			*progress <- n
+		}
+	}
+	return truncateErr
+}
+
+// request simulates latency of a request and fails with mega.ERATELIMIT, if the rate limit is reached.
+func (f *Fake) request() error {
+	if f.latency > 0 {
+		f.sleep(f.latency)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.rateLimit <= 0 {
+		return nil
+	}
+	if f.requests >= f.rateLimit {
+		f.requests = 0
+		return mega.ERATELIMIT
+	}
+	f.requests++
+	return nil
+}
+
+// clientRequest is a request, which requires a login.
+func (f *Fake) clientRequest() error {
+	err := f.request()
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.loggedIn {
+		return mega.EACCESS
+	}
+	return nil
+}
+
+// checkCredentials returns the login error or mega.ENOENT, if credentials do not match the ones given with WithCredentials.
+func (f *Fake) checkCredentials(login string, pass string) error {
+	if f.loginErr != nil {
+		return f.loginErr
+	}
+	if f.login != "" && (login != f.login || pass != f.password) {
+		return mega.ENOENT
+	}
+	return nil
+}
+
+// setFault sets the fault of the next downloads of a file of given path, removing it if it is made for no downloads.
+func (f *Fake) setFault(filePath string, fault *fault) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	filePath = strings.Trim(filePath, "/")
+	if fault.times == 0 {
+		delete(f.faults, filePath)
+		return
+	}
+	f.faults[filePath] = fault
+}
+
+// takeFault returns the fault of the next download of a file of given path, if there is one.
+func (f *Fake) takeFault(filePath string) *fault {
+	fault, ok := f.faults[filePath]
+	if !ok {
+		return nil
+	}
+	if fault.times > 0 {
+		fault.times--
+		if fault.times == 0 {
+			delete(f.faults, filePath)
+		}
+	}
+	return fault
+}
+
+// newEntry creates a node, appending it to children of the parent, if there is one.
+func (f *Fake) newEntry(name string, nodeType int, parent *entry, content []byte) *entry {
+	f.lastHash++
+	created := &entry{
+		handle:    &mega.Node{},
+		hash:      fmt.Sprintf("h%07d", f.lastHash),
+		name:      name,
+		nodeType:  nodeType,
+		content:   append([]byte{}, content...),
+		timestamp: f.now(),
+		parent:    parent,
+	}
+	f.entries[created.hash] = created
+	f.handles[created.handle] = created
+	if parent != nil {
+		parent.children = append(parent.children, created)
+	}
+	return created
+}
+
+// mkdirAll returns the directory of given path, creating it and its parents if they do not exist.
+func (f *Fake) mkdirAll(dirPath string) (*entry, error) {
+	dir := f.root
+	for _, name := range strings.Split(dirPath, "/") {
+		if name == "" {
+			continue
+		}
+		next := child(dir, name)
+		if next == nil {
+			next = f.newEntry(name, mega.FOLDER, dir, nil)
+		} else if next.nodeType == mega.FILE {
+			return nil, fmt.Errorf("megatest: not a directory: %s", next.path())
+		}
+		dir = next
+	}
+	return dir, nil
+}
+
+// lookup returns the node of given path relative to the cloud drive root.
+func (f *Fake) lookup(nodePath string) (*entry, error) {
+	current := f.root
+	for _, name := range strings.Split(strings.Trim(nodePath, "/"), "/") {
+		current = child(current, name)
+		if current == nil {
+			return nil, mega.ENOENT
+		}
+	}
+	return current, nil
+}
+
+// forget removes a destroyed node and all its children from the fake.
+func (f *Fake) forget(destroyed *entry) {
+	delete(f.entries, destroyed.hash)
+	delete(f.handles, destroyed.handle)
+	for _, child := range destroyed.children {
+		f.forget(child)
+	}
+}
+
+// child returns the newest child of given name, which is not a previous version of a file.
+func child(parent *entry, name string) *entry {
+	if parent.nodeType == mega.FILE {
+		return nil
+	}
+	for i := len(parent.children) - 1; i >= 0; i-- {
+		if parent.children[i].name == name {
+			return parent.children[i]
+		}
+	}
+	return nil
+}
+
+// detach removes a node from children of its parent.
+func detach(detached *entry) {
+	siblings := detached.parent.children
+	for i, sibling := range siblings {
+		if sibling == detached {
+			detached.parent.children = append(siblings[:i:i], siblings[i+1:]...)
+			return
+		}
+	}
+}
+
+// path returns path of the node relative to the cloud drive root. A previous version of a file has the path of the file.
+func (e *entry) path() string {
+	switch {
+	case e.parent == nil:
+		return ""
+	case e.parent.nodeType == mega.FILE:
+		return e.parent.path()
+	}
+	return path.Join(e.parent.path(), e.name)
+}
+
+func (e *entry) snapshot() node {
+	snapshot := node{
+		hash:      e.hash,
+		name:      e.name,
+		nodeType:  e.nodeType,
+		size:      int64(len(e.content)),
+		timestamp: e.timestamp,
+	}
+	if e.parent != nil {
+		snapshot.parent = e.parent.hash
+	}
+	return snapshot
+}
+
+func (n node) GetName() string {
+	return n.name
+}
+
+func (n node) GetType() int {
+	return n.nodeType
+}
+
+func (n node) GetHash() string {
+	return n.hash
+}
+
+func (n node) GetSize() int64 {
+	return n.size
+}
+
+func (n node) GetTimeStamp() time.Time {
+	return n.timestamp
+}
+
+func (n node) GetParentHash() string {
+	return n.parent
+}
+
+// GetPath returns an empty path, the browser resolves paths of nodes relative to the project root itself.
+func (n node) GetPath() string {
+	return ""
+}
@@ -0,0 +1,371 @@
+package megatest
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	megabrowser "Mic-Cie/mega-browser/MegaBrowser"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/t3rm1n4l/go-mega"
+)
+
+const (
+	login    = "login"
+	pass     = "password"
+	rootNode = "project"
+)
+
+var errDownload = errors.New("fake download error")
+
+// The fake replaces every client and filesystem interface of megabrowser package.
+var (
+	_ megabrowser.SessionClient = (*Fake)(nil)
+	_ megabrowser.MFAClient     = (*Fake)(nil)
+	_ megabrowser.UploadClient  = (*Fake)(nil)
+	_ megabrowser.QuotaClient   = (*Fake)(nil)
+	_ megabrowser.NodeFs        = (*Fake)(nil)
+	_ megabrowser.Backend       = (*Fake)(nil)
+)
+
+func TestInitialize(t *testing.T) {
+	tests := []struct {
+		name     string
+		fake     *Fake
+		login    string
+		opts     []megabrowser.Option
+		loginErr error
+		expErr   error
+	}{
+		{
+			name:  "should initialize, if credentials match",
+			fake:  newFakeProject(),
+			login: login,
+		},
+		{
+			name:   "should fail, if credentials do not match",
+			fake:   newFakeProject(),
+			login:  "other",
+			expErr: mega.ENOENT,
+		},
+		{
+			name:     "should fail, if login fails",
+			fake:     newFakeProject(),
+			login:    login,
+			loginErr: mega.EBLOCKED,
+			expErr:   mega.EBLOCKED,
+		},
+		{
+			name:  "should initialize, if the account requires a multi-factor code and it is given",
+			fake:  newFakeProject(WithMFA("123456")),
+			login: login,
+			opts:  []megabrowser.Option{megabrowser.WithMFACode("123456")},
+		},
+		{
+			name:   "should fail, if the account requires a multi-factor code and it is wrong",
+			fake:   newFakeProject(WithMFA("123456")),
+			login:  login,
+			opts:   []megabrowser.Option{megabrowser.WithMFACode("654321")},
+			expErr: mega.ENOENT,
+		},
+		{
+			name:   "should fail, if the account requires a multi-factor code and it is not given",
+			fake:   newFakeProject(WithMFA("123456")),
+			login:  login,
+			expErr: megabrowser.ErrMFARequired,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fake.SetLoginError(test.loginErr)
+			browser, err := megabrowser.New(append([]megabrowser.Option{
+				megabrowser.WithCredentials(test.login, pass),
+				megabrowser.WithRootNode(rootNode),
+				megabrowser.WithClient(test.fake),
+				megabrowser.WithFs(test.fake),
+			}, test.opts...)...)
+			require.Nil(t, err)
+
+			err = browser.Initialize()
+
+			assert.Equal(t, test.expErr, err)
+		})
+	}
+}
+
+func TestSync(t *testing.T) {
+	fake := newFakeProject()
+	browser := newBrowser(t, fake)
+	localDir := t.TempDir()
+
+	entries, err := browser.Sync(localDir)
+
+	require.Nil(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, []string{rootNode + "/app.bin", rootNode + "/data/config.json"}, fake.Downloads())
+	assertFileContent(t, filepath.Join(localDir, "app.bin"), "binary")
+	assertFileContent(t, filepath.Join(localDir, "data", "config.json"), "{}")
+
+	entries, err = browser.Sync(localDir)
+
+	require.Nil(t, err)
+	for _, entry := range entries {
+		assert.False(t, entry.NeedsUpdate())
+	}
+	assert.Len(t, fake.Downloads(), 2)
+}
+
+func TestSyncWithBackend(t *testing.T) {
+	fake := newFakeProject()
+	downloader := megabrowser.NewMegaDownloader()
+	browser, err := megabrowser.New(
+		megabrowser.WithRootNode(rootNode),
+		megabrowser.WithBackend(fake),
+		megabrowser.WithDownloader(downloader),
+	)
+	require.Nil(t, err)
+	require.Nil(t, browser.Initialize())
+	localDir := t.TempDir()
+
+	_, err = browser.Sync(localDir)
+
+	require.Nil(t, err)
+	assertFileContent(t, filepath.Join(localDir, "data", "config.json"), "{}")
+}
+
+func TestSyncFailures(t *testing.T) {
+	tests := []struct {
+		name         string
+		fake         func() *Fake
+		expErr       error
+		expPartial   string
+		expDownloads int
+	}{
+		{
+			name: "should fail once, if download fails once",
+			fake: func() *Fake {
+				fake := newFakeProject()
+				fake.FailDownload(rootNode+"/data/config.json", 1, errDownload)
+				return fake
+			},
+			expErr:       errDownload,
+			expDownloads: 3,
+		},
+		{
+			name: "should leave partial file, if download is truncated",
+			fake: func() *Fake {
+				fake := newFakeProject()
+				fake.TruncateDownload(rootNode+"/data/config.json", 1, 1)
+				return fake
+			},
+			expErr:       io.ErrUnexpectedEOF,
+			expPartial:   "{",
+			expDownloads: 3,
+		},
+		{
+			name: "should fail once, if rate limit is reached",
+			fake: func() *Fake {
+				// Login and the first download are allowed, the second download is rejected before it starts.
+				return newFakeProject(WithRateLimit(2))
+			},
+			expErr:       mega.ERATELIMIT,
+			expDownloads: 2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := test.fake()
+			browser := newBrowser(t, fake)
+			localDir := t.TempDir()
+
+			_, err := browser.Sync(localDir)
+
+			assert.Equal(t, test.expErr, err)
+			if test.expPartial != "" {
+				assertFileContent(t, filepath.Join(localDir, "data", "config.json"), test.expPartial)
+			}
+
+			_, err = browser.Sync(localDir)
+
+			require.Nil(t, err)
+			assertFileContent(t, filepath.Join(localDir, "data", "config.json"), "{}")
+			assert.Len(t, fake.Downloads(), test.expDownloads)
+		})
+	}
+}
+
+func TestSyncOverQuota(t *testing.T) {
+	fake := newFakeProject()
+	fake.FailDownload(rootNode+"/app.bin", 1, mega.EOVERQUOTA)
+	browser := newBrowser(t, fake)
+
+	_, err := browser.Sync(t.TempDir())
+
+	var quotaErr *megabrowser.QuotaError
+	require.ErrorAs(t, err, &quotaErr)
+	assert.ErrorIs(t, err, mega.EOVERQUOTA)
+}
+
+func TestQuota(t *testing.T) {
+	fake := newFakeProject(WithStorageQuota(1000), WithTransferQuota(5000))
+	browser := newBrowser(t, fake)
+	_, err := browser.Sync(t.TempDir())
+	require.Nil(t, err)
+
+	quota, err := browser.Quota()
+
+	require.Nil(t, err)
+	assert.Equal(t, megabrowser.Quota{
+		StorageUsed:   uint64(len("binary{}other")),
+		StorageTotal:  1000,
+		TransferUsed:  uint64(len("binary{}")),
+		TransferTotal: 5000,
+	}, quota)
+}
+
+func TestLatency(t *testing.T) {
+	var slept time.Duration
+	fake := newFakeProject(WithLatency(time.Second), WithSleep(func(d time.Duration) {
+		slept += d
+	}))
+	browser := newBrowser(t, fake)
+
+	_, err := browser.Sync(t.TempDir())
+
+	require.Nil(t, err)
+	// Login and two downloads.
+	assert.Equal(t, 3*time.Second, slept)
+}
+
+func TestListVersions(t *testing.T) {
+	fake := newFakeProject()
+	require.Nil(t, fake.AddFile(rootNode+"/app.bin", []byte("binary v2")))
+	browser := newBrowser(t, fake)
+
+	versions, err := browser.ListVersions("app.bin")
+
+	require.Nil(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, int64(len("binary v2")), versions[0].GetSize())
+	assert.Equal(t, int64(len("binary")), versions[1].GetSize())
+	assert.Equal(t, versions[0].GetHash(), versions[1].GetParentHash())
+
+	localFile := filepath.Join(t.TempDir(), "app.bin")
+	require.Nil(t, browser.UpdateFileFromVersion("app.bin", versions[1].GetHash(), localFile))
+	assertFileContent(t, localFile, "binary")
+}
+
+func TestPublish(t *testing.T) {
+	fake := newFakeProject()
+	browser := newBrowser(t, fake)
+	publisher, err := megabrowser.NewPublisher(browser, megabrowser.WithVersionFile("version.txt"))
+	require.Nil(t, err)
+	localDir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(localDir, "app.bin"), []byte("new binary"), 0666))
+
+	_, err = publisher.Publish(localDir, "2.0.0")
+
+	require.Nil(t, err)
+	content, ok := fake.Content(rootNode + "/app.bin")
+	require.True(t, ok)
+	assert.Equal(t, "new binary", string(content))
+	content, ok = fake.Content(rootNode + "/version.txt")
+	require.True(t, ok)
+	assert.Equal(t, "2.0.0\n", string(content))
+	assert.Equal(t, []string{"app.bin"}, fake.Trashed())
+}
+
+func TestSyncAfterPublishRemovingFile(t *testing.T) {
+	fake := newFakeProject()
+	publisher, err := megabrowser.NewPublisher(newBrowser(t, fake), megabrowser.WithManifestFile("manifest.json"))
+	require.Nil(t, err)
+	localDir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(localDir, "app.bin"), []byte("new binary"), 0666))
+	require.Nil(t, os.WriteFile(filepath.Join(localDir, "removed.bin"), []byte("removed"), 0666))
+	_, err = publisher.Publish(localDir, "1.0.0")
+	require.Nil(t, err)
+	require.Nil(t, os.Remove(filepath.Join(localDir, "removed.bin")))
+	_, err = publisher.Publish(localDir, "2.0.0")
+	require.Nil(t, err)
+	browser, err := megabrowser.New(
+		megabrowser.WithCredentials(login, pass),
+		megabrowser.WithRootNode(rootNode),
+		megabrowser.WithClient(fake),
+		megabrowser.WithFs(fake),
+		megabrowser.WithDownloader(megabrowser.NewMegaDownloader()),
+		megabrowser.WithManifest("manifest.json"),
+	)
+	require.Nil(t, err)
+	require.Nil(t, browser.Initialize())
+	targetDir := t.TempDir()
+
+	_, err = browser.Sync(targetDir)
+
+	require.Nil(t, err)
+	assertFileContent(t, filepath.Join(targetDir, "app.bin"), "new binary")
+	assert.NoFileExists(t, filepath.Join(targetDir, "removed.bin"))
+	assert.NoFileExists(t, filepath.Join(targetDir, "data", "config.json"))
+	entries, err := browser.Verify(targetDir)
+	require.Nil(t, err)
+	for _, entry := range entries {
+		assert.False(t, entry.NeedsUpdate(), entry.Path)
+	}
+}
+
+func TestNewFromDir(t *testing.T) {
+	dir := t.TempDir()
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, rootNode, "empty"), 0777))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, rootNode, "app.bin"), []byte("binary"), 0666))
+
+	fake, err := NewFromDir(dir)
+
+	require.Nil(t, err)
+	content, ok := fake.Content(rootNode + "/app.bin")
+	require.True(t, ok)
+	assert.Equal(t, "binary", string(content))
+	browser := newBrowser(t, fake)
+	nodes, err := browser.ListDirectory("")
+	require.Nil(t, err)
+	var names []string
+	for _, node := range nodes {
+		names = append(names, node.GetName())
+	}
+	assert.Equal(t, []string{"app.bin", "empty"}, names)
+}
+
+func TestAddFileFailsIfParentIsFile(t *testing.T) {
+	fake := newFakeProject()
+
+	err := fake.AddFile(rootNode+"/app.bin/nested", nil)
+
+	require.NotNil(t, err)
+	assert.Equal(t, "megatest: not a directory: "+rootNode+"/app.bin", err.Error())
+}
+
+// newFakeProject creates a fake account with credentials login and pass, containing project directory with app.bin and data/config.json files.
+func newFakeProject(opts ...Option) *Fake {
+	return NewFromMap(map[string]string{
+		rootNode + "/app.bin":          "binary",
+		rootNode + "/data/config.json": "{}",
+		"other/file.txt":               "other",
+	}, append([]Option{WithCredentials(login, pass)}, opts...)...)
+}
+
+// newBrowser creates an initialized browser working on the project directory of given fake account.
+func newBrowser(t *testing.T, fake *Fake) *megabrowser.MegaBrowser {
+	downloader := megabrowser.NewMegaDownloader()
+	browser := megabrowser.NewMegaBrowser(login, pass, rootNode, fake, fake, downloader)
+	require.Nil(t, browser.Initialize())
+	return browser
+}
+
+func assertFileContent(t *testing.T, filePath string, expContent string) {
+	content, err := os.ReadFile(filePath)
+	require.Nil(t, err)
+	assert.Equal(t, expContent, string(content))
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	megabrowser "Mic-Cie/mega-browser/MegaBrowser"
+)
+
+type command struct {
+	minArgs int
+	maxArgs int
+	run     func(b browser, p *printer, cfg *megabrowser.Config, args []string) error
+}
+
+var commands = map[string]command{
+	"login":       {minArgs: 0, maxArgs: 0, run: runLogin},
+	"ls":          {minArgs: 0, maxArgs: 1, run: runList},
+	"stat":        {minArgs: 1, maxArgs: 1, run: runStat},
+	"get":         {minArgs: 1, maxArgs: 2, run: runGet},
+	"versions":    {minArgs: 1, maxArgs: 1, run: runVersions},
+	"get-version": {minArgs: 2, maxArgs: 3, run: runGetVersion},
+	"plan":        {minArgs: 0, maxArgs: 1, run: runPlan},
+	"sync":        {minArgs: 0, maxArgs: 1, run: runSync},
+	"verify":      {minArgs: 0, maxArgs: 1, run: runVerify},
+	"releases":    {minArgs: 0, maxArgs: 0, run: runReleases},
+	"rollback":    {minArgs: 0, maxArgs: 1, run: runRollback},
+	"unpin":       {minArgs: 0, maxArgs: 1, run: runUnpin},
+	"share":       {minArgs: 0, maxArgs: 1, run: runShare},
+	"quota":       {minArgs: 0, maxArgs: 0, run: runQuota},
+}
+
+// errNoPeerListen is returned by the share command, if the configuration does not say where to serve files.
+var errNoPeerListen = errors.New("share requires peers listen address and url in the configuration")
+
+// peerDiscoveryWait is the time to wait for peers to answer the discovery query, before downloading anything.
+var peerDiscoveryWait = time.Second
+
+// waitForShutdown blocks until the process is interrupted or terminated.
+var waitForShutdown = func() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+}
+
+// runLogin does nothing on its own, because the browser is already initialized, which means the login succeeded.
+func runLogin(b browser, p *printer, cfg *megabrowser.Config, args []string) error {
+	return p.message("login successful")
+}
+
+func runList(b browser, p *printer, cfg *megabrowser.Config, args []string) error {
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	}
+	nodes, err := b.ListDirectory(path)
+	if err != nil {
+		return err
+	}
+	return p.nodes(nodes)
+}
+
+func runStat(b browser, p *printer, cfg *megabrowser.Config, args []string) error {
+	node, err := b.Stat(args[0])
+	if err != nil {
+		return err
+	}
+	return p.node(node)
+}
+
+func runGet(b browser, p *printer, cfg *megabrowser.Config, args []string) error {
+	local, err := localPath(args, 1)
+	if err != nil {
+		return err
+	}
+	report, err := b.UpdateFileFromPathReport(context.Background(), args[0], local)
+	return finishReport(p, cfg, report, err)
+}
+
+func runVersions(b browser, p *printer, cfg *megabrowser.Config, args []string) error {
+	versions, err := b.ListVersions(args[0])
+	if err != nil {
+		return err
+	}
+	return p.nodes(versions)
+}
+
+func runGetVersion(b browser, p *printer, cfg *megabrowser.Config, args []string) error {
+	local, err := localPath(args, 2)
+	if err != nil {
+		return err
+	}
+	report, err := b.UpdateFileFromVersionReport(args[0], args[1], local)
+	return finishReport(p, cfg, report, err)
+}
+
+func runPlan(b browser, p *printer, cfg *megabrowser.Config, args []string) error {
+	entries, err := b.Plan(targetDir(cfg, args))
+	if err != nil {
+		return err
+	}
+	return p.entries(entries)
+}
+
+func runSync(b browser, p *printer, cfg *megabrowser.Config, args []string) error {
+	stop, err := discoverPeers(b, cfg, "")
+	if err != nil {
+		return err
+	}
+	defer stop()
+	return syncTarget(b, p, cfg, args)
+}
+
+func runVerify(b browser, p *printer, cfg *megabrowser.Config, args []string) error {
+	entries, err := b.Verify(targetDir(cfg, args))
+	if err != nil {
+		return err
+	}
+	err = p.entries(entries)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.NeedsUpdate() {
+			return errVerifyFailed
+		}
+	}
+	return nil
+}
+
+func runReleases(b browser, p *printer, cfg *megabrowser.Config, args []string) error {
+	versions, err := b.ListReleases()
+	if err != nil {
+		return err
+	}
+	return p.releases(versions, b.Release())
+}
+
+func runRollback(b browser, p *printer, cfg *megabrowser.Config, args []string) error {
+	stop, err := discoverPeers(b, cfg, "")
+	if err != nil {
+		return err
+	}
+	defer stop()
+	report, err := b.RollbackReport(targetDir(cfg, args))
+	err = errors.Join(err, writeReportFile(report, cfg))
+	if err != nil {
+		if report != nil {
+			_ = p.report(report)
+		}
+		return err
+	}
+	if p.json {
+		return p.report(report)
+	}
+	return p.message(fmt.Sprintf("rolled back to release %s, %d files restored", b.Release(), report.Count(megabrowser.FileDownloaded)))
+}
+
+func runUnpin(b browser, p *printer, cfg *megabrowser.Config, args []string) error {
+	err := b.Unpin(targetDir(cfg, args))
+	if err != nil {
+		return err
+	}
+	return p.message(fmt.Sprintf("unpinned, following release %s", b.Release()))
+}
+
+// runShare synchronizes the target directory and serves its files to peers, until the process is interrupted.
+func runShare(b browser, p *printer, cfg *megabrowser.Config, args []string) error {
+	peers := b.Peers()
+	if peers == nil || cfg.Peers.Listen == "" {
+		return errNoPeerListen
+	}
+	listener, err := net.Listen("tcp", cfg.Peers.Listen)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: peers, ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+	defer server.Close()
+
+	stop, err := discoverPeers(b, cfg, cfg.Peers.URL)
+	if err != nil {
+		return err
+	}
+	defer stop()
+	err = syncTarget(b, p, cfg, args)
+	if err != nil {
+		return err
+	}
+	waitForShutdown()
+	return nil
+}
+
+func runQuota(b browser, p *printer, cfg *megabrowser.Config, args []string) error {
+	quota, err := b.Quota()
+	if err != nil {
+		return err
+	}
+	return p.quota(quota)
+}
+
+// syncTarget synchronizes the target directory, prints the report and writes it to the configured report file. The report is printed also if the synchronization fails.
+func syncTarget(b browser, p *printer, cfg *megabrowser.Config, args []string) error {
+	report, err := b.SyncReport(context.Background(), targetDir(cfg, args))
+	return finishReport(p, cfg, report, err)
+}
+
+// finishReport writes the report of an update to the configured report file and prints it, if there is one, and returns err of the update joined with errors of writing and printing.
+func finishReport(p *printer, cfg *megabrowser.Config, report *megabrowser.UpdateReport, err error) error {
+	err = errors.Join(err, writeReportFile(report, cfg))
+	if report != nil {
+		err = errors.Join(err, p.report(report))
+	}
+	return err
+}
+
+// discoverPeers starts discovery of peers, announcing given URL unless it is empty, and waits for peers to answer, if discovery is configured. The returned function stops the discovery.
+func discoverPeers(b browser, cfg *megabrowser.Config, url string) (func(), error) {
+	peers := b.Peers()
+	if peers == nil || cfg.Peers.Discovery == "" {
+		return func() {}, nil
+	}
+	discovery, err := megabrowser.NewPeerDiscovery(peers, cfg.Peers.Discovery, url, time.Duration(cfg.Peers.Interval))
+	if err != nil {
+		return nil, err
+	}
+	time.Sleep(peerDiscoveryWait)
+	return func() {
+		discovery.Close()
+	}, nil
+}
+
+// serveMetrics serves metrics of the browser at /metrics on the configured address, if there is one. The returned function stops serving them.
+func serveMetrics(b browser, cfg *megabrowser.Config) (func(), error) {
+	metrics := b.Metrics()
+	if metrics == nil || cfg.Metrics.Listen == "" {
+		return func() {}, nil
+	}
+	listener, err := net.Listen("tcp", cfg.Metrics.Listen)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+	return func() {
+		server.Close()
+	}, nil
+}
+
+// writeMetricsFile writes metrics of the browser to the configured file, if there is one.
+func writeMetricsFile(b browser, cfg *megabrowser.Config) error {
+	metrics := b.Metrics()
+	if metrics == nil || cfg.Metrics.File == "" {
+		return nil
+	}
+	return replaceFile(cfg.Metrics.File, func(w io.Writer) error {
+		_, err := metrics.WriteTo(w)
+		return err
+	})
+}
+
+// writeReportFile writes given report as JSON to the configured file, if there is one and the report is not nil.
+func writeReportFile(report *megabrowser.UpdateReport, cfg *megabrowser.Config) error {
+	if report == nil || cfg.Report.File == "" {
+		return nil
+	}
+	err := replaceFile(cfg.Report.File, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(report)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// replaceFile replaces the file at given path with content written by write at once, so that readers never see it partially written.
+func replaceFile(path string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	err = write(tmp)
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(tmp.Name(), 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// targetDir returns the directory given as the command argument, or the configured target directory if there is none.
+func targetDir(cfg *megabrowser.Config, args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return cfg.TargetDir
+}
+
+// localPath returns the local path given as the command argument of given index, or otherwise the remote path given as the first argument, relative to the working directory. Returns megabrowser.ErrUnsafePath, if the remote path would lead out of the working directory.
+func localPath(args []string, index int) (string, error) {
+	if len(args) > index {
+		return args[index], nil
+	}
+	local := filepath.FromSlash(strings.TrimLeft(args[0], "/"))
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("%w: %s leads out of the working directory", megabrowser.ErrUnsafePath, args[0])
+	}
+	return local, nil
+}
@@ -0,0 +1,204 @@
+// Command megabrowser inspects a project stored in a Mega repository and pulls its files to the local disk.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	megabrowser "Mic-Cie/mega-browser/MegaBrowser"
+)
+
+// envConfig is the environment variable holding the default configuration file path.
+const envConfig = "MEGABROWSER_CONFIG"
+
+const usage = `usage: megabrowser [flags] <command> [arguments]
+
+Commands:
+  login                  check the account or public link and the project root
+  ls [path]              list a directory of the project, the root by default
+  stat <path>            show a single file or directory of the project
+  get <path> [local]     download a file, to the same relative path by default
+  versions <path>        list versions of a file, the current one first
+  get-version <path> <hash> [local]
+                         download a previous version of a file, like get
+  plan [dir]             show which files of dir sync would update
+  sync [dir]             download every missing or outdated file into dir
+  verify [dir]           check dir against the project, exit with 1 on mismatch
+  releases               list releases of the project, newest first
+  rollback [dir]         restore dir to the previous release and pin it there
+  unpin [dir]            let dir follow the current release again
+  share [dir]            sync dir and serve its files to peers until interrupted
+  quota                  show storage and transfer used and available
+
+A dir defaults to the configured target directory. Release commands require
+releases to be enabled in the configuration. A rolled back dir keeps its
+release, when it is synchronized, until it is unpinned.
+
+Sources:
+  A public folder or file link or a local directory is browsed without logging
+  in, a file link as a folder holding only that file. An anonymous session
+  ignores any configured account, and uses only mirrors without a link or
+  directory. Mirrors are used when Mega is unavailable, and require a signed
+  manifest.
+
+Verification:
+  Downloads are verified against the manifest, if one is configured. Files it
+  does not list are skipped by plan, sync and verify, and rejected by get.
+  verify compares checksums with the manifest, or only sizes without one.
+  Files fetched from peers are always verified.
+
+Downloads:
+  Installs sharing a cache directory download every file only once. Peers are
+  listed or discovered with multicast, and serve files with share. Bandwidth
+  is limited globally and per file, with lower limits in background mode.
+  Downloads over the transfer quota fail, or wait for the quota to reset and
+  resume.
+
+Output:
+  Commands downloading files print the outcome of every file. Logs go to the
+  standard error output. Metrics use the Prometheus text format.
+
+Safety:
+  Unsafe remote names are rejected, sync never writes outside its dir, and no
+  command writes outside the download root, if it is set.
+
+Settings are read from the configuration file given with -config or
+MEGABROWSER_CONFIG (JSON, YAML or TOML), and overridden by the environment:
+  MEGA_PUBLIC_LINK         public folder or file link browsed without an account
+  MEGA_LOCAL_DIR           local directory browsed instead of an account
+  MEGA_ANONYMOUS           true to never log in
+  MEGA_LOGIN               account login
+  MEGA_PASSWORD            account password
+  MEGA_CREDENTIALS_FILE    file holding the login and the password
+  MEGA_MFA_CODE            current multi-factor authentication code
+  MEGA_SESSION_FILE        file the session is saved to
+  MEGA_SESSION_PASSPHRASE  passphrase encrypting the session file
+  MEGA_ROOT                project root node, overridden by -root
+  MEGA_TARGET_DIR          default local directory
+  MEGA_DOWNLOAD_ROOT       directory no download may write outside of
+  MEGA_CONCURRENCY         number of files downloaded at once
+  MEGA_RETRY_ATTEMPTS      attempts of every download
+  MEGA_RETRY_DELAY         delay before the first retry
+  MEGA_PROGRESS            true or false to show download progress
+  MEGA_MANIFEST            name of the manifest in the project root
+  MEGA_MANIFEST_KEY        base64 ed25519 public key of the manifest signature
+  MEGA_CACHE_DIR           shared download cache directory
+  MEGA_PEERS               comma separated peer URLs
+  MEGA_BANDWIDTH_LIMIT     download limit in bytes per second
+  MEGA_BACKGROUND          true to use the background bandwidth limits
+  MEGA_QUOTA_WAIT          longest wait for the transfer quota to reset
+  MEGA_LOG_LEVEL           debug, info, warn or error, no logs if empty
+  MEGA_LOG_FORMAT          text or json
+  MEGA_METRICS_LISTEN      address serving /metrics while a command runs
+  MEGA_METRICS_FILE        file metrics are written to after a command
+  MEGA_REPORT_FILE         file the JSON report of a download is written to
+
+Flags:
+`
+
+// errVerifyFailed is returned by the verify command, if the local directory does not match the project.
+var errVerifyFailed = errors.New("local files do not match the project")
+
+// browser is the subset of megabrowser.MegaBrowser used by the commands.
+type browser interface {
+	Initialize() error
+	ListDirectory(path string) ([]megabrowser.Node, error)
+	Stat(path string) (megabrowser.Node, error)
+	UpdateFileFromPathReport(ctx context.Context, file string, localDownloadPath string) (*megabrowser.UpdateReport, error)
+	ListVersions(path string) ([]megabrowser.Node, error)
+	UpdateFileFromVersionReport(path string, versionHash string, localDownloadPath string) (*megabrowser.UpdateReport, error)
+	Plan(localDir string) ([]megabrowser.SyncEntry, error)
+	Verify(localDir string) ([]megabrowser.SyncEntry, error)
+	SyncReport(ctx context.Context, localDir string) (*megabrowser.UpdateReport, error)
+	ListReleases() ([]string, error)
+	RollbackReport(localDir string) (*megabrowser.UpdateReport, error)
+	Unpin(localDir string) error
+	Release() string
+	Peers() *megabrowser.Peers
+	Quota() (megabrowser.Quota, error)
+	Metrics() *megabrowser.Metrics
+}
+
+type newBrowserFunc func(cfg *megabrowser.Config) (browser, error)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Getenv, os.Stdout, os.Stderr, newMegaBrowser))
+}
+
+// run executes the command given in args and returns the process exit code.
+func run(args []string, getenv func(string) string, stdout io.Writer, stderr io.Writer, newBrowser newBrowserFunc) int {
+	flags := flag.NewFlagSet("megabrowser", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	jsonOutput := flags.Bool("json", false, "print results as JSON")
+	configPath := flags.String("config", getenv(envConfig), "path to the configuration file")
+	rootNode := flags.String("root", "", "name of the directory containing the project, overrides the configuration")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok || flags.NArg()-1 < cmd.minArgs || flags.NArg()-1 > cmd.maxArgs {
+		flags.Usage()
+		return 2
+	}
+
+	if *rootNode != "" {
+		override := getenv
+		getenv = func(key string) string {
+			if key == megabrowser.EnvRootNode {
+				return *rootNode
+			}
+			return override(key)
+		}
+	}
+	cfg, err := megabrowser.LoadConfig(*configPath, getenv)
+	if err != nil {
+		fmt.Fprintf(stderr, "megabrowser: %v\n", err)
+		return 2
+	}
+	if *jsonOutput {
+		cfg.Progress.Enabled = false
+	}
+
+	b, err := newBrowser(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "megabrowser: %v\n", err)
+		return 2
+	}
+	stopMetrics, err := serveMetrics(b, cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "megabrowser: %v\n", err)
+		return 1
+	}
+	defer stopMetrics()
+
+	code := 0
+	if err := b.Initialize(); err != nil {
+		fmt.Fprintf(stderr, "megabrowser: %v\n", err)
+		code = 1
+	} else if err := cmd.run(b, newPrinter(stdout, *jsonOutput), cfg, flags.Args()[1:]); err != nil {
+		fmt.Fprintf(stderr, "megabrowser: %v\n", err)
+		code = 1
+	}
+	if err := writeMetricsFile(b, cfg); err != nil {
+		fmt.Fprintf(stderr, "megabrowser: failed to write metrics: %v\n", err)
+		code = 1
+	}
+	return code
+}
+
+func newMegaBrowser(cfg *megabrowser.Config) (browser, error) {
+	return megabrowser.NewMegaBrowserFromConfig(cfg)
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	megabrowser "Mic-Cie/mega-browser/MegaBrowser"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errMock = fmt.Errorf("mock browser error")
+
+type mockBrowser struct {
+	errInitialize error
+	entries       []megabrowser.SyncEntry
+	plannedDir    string
+	localPath     string
+	errSync       error
+	metrics       *megabrowser.Metrics
+}
+
+type mockNode struct {
+	name string
+	hash string
+	size int64
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		env           map[string]string
+		errInitialize error
+		entries       []megabrowser.SyncEntry
+		expCode       int
+		expOut        string
+	}{
+		{
+			name:    "should fail, if no command is given",
+			args:    []string{},
+			env:     validEnv(),
+			expCode: 2,
+			expOut:  "",
+		},
+		{
+			name:    "should fail, if command is unknown",
+			args:    []string{"unknown"},
+			env:     validEnv(),
+			expCode: 2,
+			expOut:  "",
+		},
+		{
+			name:    "should fail, if command has too many arguments",
+			args:    []string{"stat", "a", "b"},
+			env:     validEnv(),
+			expCode: 2,
+			expOut:  "",
+		},
+		{
+			name:    "should fail, if credentials are missing",
+			args:    []string{"login"},
+			env:     map[string]string{megabrowser.EnvRootNode: "root"},
+			expCode: 2,
+			expOut:  "",
+		},
+		{
+			name:          "should fail, if could not initialize the browser",
+			args:          []string{"login"},
+			env:           validEnv(),
+			errInitialize: errMock,
+			expCode:       1,
+			expOut:        "",
+		},
+		{
+			name:    "should accept root node from flag",
+			args:    []string{"-root", "root", "login"},
+			env:     map[string]string{megabrowser.EnvLogin: "login", megabrowser.EnvPassword: "pass"},
+			expCode: 0,
+			expOut:  "login successful\n",
+		},
+		{
+			name:    "should fail, if config file does not exist",
+			args:    []string{"-config", "missing.json", "login"},
+			env:     validEnv(),
+			expCode: 2,
+			expOut:  "",
+		},
+		{
+			name:    "should print node as JSON, if json flag is set",
+			args:    []string{"-json", "stat", "file"},
+			env:     validEnv(),
+			expCode: 0,
+			expOut:  "{\n  \"name\": \"file\",\n  \"hash\": \"hash\",\n  \"type\": \"file\",\n  \"size\": 1\n}\n",
+		},
+		{
+			name: "should succeed verify, if every file is up to date",
+			args: []string{"verify", "dir"},
+			env:  validEnv(),
+			entries: []megabrowser.SyncEntry{
+				{Path: "file", Status: megabrowser.SyncStatusUpToDate},
+			},
+			expCode: 0,
+			expOut:  "0 of 1 files need an update\n",
+		},
+		{
+			name: "should fail verify, if any file is outdated",
+			args: []string{"verify", "dir"},
+			env:  validEnv(),
+			entries: []megabrowser.SyncEntry{
+				{Path: "file", RemoteSize: 1, Status: megabrowser.SyncStatusMissing},
+			},
+			expCode: 1,
+			expOut:  "missing  1  file\n1 of 1 files need an update\n",
+		},
+		{
+			name:    "should list versions of a file",
+			args:    []string{"versions", "file"},
+			env:     validEnv(),
+			expCode: 0,
+			expOut:  "file  2  v2  file\nfile  1  v1  file\n",
+		},
+		{
+			name:    "should report the downloaded file",
+			args:    []string{"get", "/dir/file"},
+			env:     validEnv(),
+			expCode: 0,
+			expOut:  "downloaded  1  mega  /dir/file\n1 downloaded, 0 skipped, 0 failed, 0 deleted, 1 bytes in 0s\n",
+		},
+		{
+			name:    "should fail get, if remote path leads out of the working directory",
+			args:    []string{"get", "dir/../../file"},
+			env:     validEnv(),
+			expCode: 1,
+			expOut:  "",
+		},
+		{
+			name:    "should fail get-version, if remote path leads out of the working directory",
+			args:    []string{"get-version", "../file", "v1"},
+			env:     validEnv(),
+			expCode: 1,
+			expOut:  "",
+		},
+		{
+			name:    "should report the downloaded version of a file",
+			args:    []string{"get-version", "file", "v1", "local"},
+			env:     validEnv(),
+			expCode: 0,
+			expOut:  "downloaded  1  mega  file\n1 downloaded, 0 skipped, 0 failed, 0 deleted, 1 bytes in 0s\n",
+		},
+		{
+			name: "should report outcome of every synchronized file",
+			args: []string{"sync", "dir"},
+			env:  validEnv(),
+			entries: []megabrowser.SyncEntry{
+				{Path: "new", RemoteSize: 3, Status: megabrowser.SyncStatusMissing},
+				{Path: "old", RemoteSize: 2, Status: megabrowser.SyncStatusUpToDate},
+			},
+			expCode: 0,
+			expOut:  "downloaded  3  mega  new\n1 downloaded, 1 skipped, 0 failed, 0 deleted, 3 bytes in 0s\n",
+		},
+		{
+			name:    "should list releases, marking the current one",
+			args:    []string{"releases"},
+			env:     validEnv(),
+			expCode: 0,
+			expOut:  "* 1.10.0\n  1.4.0\n",
+		},
+		{
+			name: "should report rolled back release",
+			args: []string{"rollback", "dir"},
+			env:  validEnv(),
+			entries: []megabrowser.SyncEntry{
+				{Path: "file", Status: megabrowser.SyncStatusUpToDate},
+			},
+			expCode: 0,
+			expOut:  "rolled back to release 1.10.0, 1 files restored\n",
+		},
+		{
+			name:    "should report release followed after unpinning",
+			args:    []string{"unpin", "dir"},
+			env:     validEnv(),
+			expCode: 0,
+			expOut:  "unpinned, following release 1.10.0\n",
+		},
+		{
+			name:    "should show storage and transfer quota of the account",
+			args:    []string{"quota"},
+			env:     validEnv(),
+			expCode: 0,
+			expOut:  "storage: 250 of 1000 bytes used\ntransfer: 300 of 5000 bytes used\n",
+		},
+		{
+			name:    "should fail share, if peers are not configured",
+			args:    []string{"share", "dir"},
+			env:     validEnv(),
+			expCode: 1,
+			expOut:  "",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			newBrowser := func(cfg *megabrowser.Config) (browser, error) {
+				return &mockBrowser{errInitialize: test.errInitialize, entries: test.entries}, nil
+			}
+
+			code := run(test.args, mapGetenv(test.env), &stdout, &stderr, newBrowser)
+
+			assert.Equal(t, test.expCode, code)
+			assert.Equal(t, test.expOut, stdout.String())
+		})
+	}
+}
+
+func TestRunWithConfigFile(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(configPath, []byte("account:\n  login: login\n  password: pass\nrootNode: root\ntargetDir: target\n"), 0600)
+	require.Nil(t, err)
+	var stdout, stderr bytes.Buffer
+	mock := &mockBrowser{}
+	newBrowser := func(cfg *megabrowser.Config) (browser, error) {
+		return mock, nil
+	}
+
+	code := run([]string{"plan"}, mapGetenv(map[string]string{envConfig: configPath}), &stdout, &stderr, newBrowser)
+
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "target", mock.plannedDir)
+}
+
+func TestRunDownloadsToLocalPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expLocal string
+	}{
+		{
+			name:     "should download a file below the working directory, if no local path is given",
+			args:     []string{"get", "/dir/file"},
+			expLocal: "dir/file",
+		},
+		{
+			name:     "should download a file to given path",
+			args:     []string{"get", "/dir/file", "local"},
+			expLocal: "local",
+		},
+		{
+			name:     "should download a version of a file to given path",
+			args:     []string{"get-version", "file", "v1", "local"},
+			expLocal: "local",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			mock := &mockBrowser{}
+			newBrowser := func(cfg *megabrowser.Config) (browser, error) {
+				return mock, nil
+			}
+
+			code := run(test.args, mapGetenv(validEnv()), &stdout, &stderr, newBrowser)
+
+			assert.Equal(t, 0, code)
+			assert.Equal(t, test.expLocal, mock.localPath)
+		})
+	}
+}
+
+func TestRunWritesReportFile(t *testing.T) {
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	env := validEnv()
+	env[megabrowser.EnvReportFile] = reportPath
+	var stdout, stderr bytes.Buffer
+	newBrowser := func(cfg *megabrowser.Config) (browser, error) {
+		return &mockBrowser{
+			entries: []megabrowser.SyncEntry{{Path: "file", RemoteSize: 1, Status: megabrowser.SyncStatusMissing}},
+			errSync: errMock,
+		}, nil
+	}
+
+	code := run([]string{"sync"}, mapGetenv(env), &stdout, &stderr, newBrowser)
+
+	assert.Equal(t, 1, code)
+	content, err := os.ReadFile(reportPath)
+	require.Nil(t, err)
+	var report megabrowser.UpdateReport
+	require.Nil(t, json.Unmarshal(content, &report))
+	require.Len(t, report.Files, 1)
+	assert.Equal(t, megabrowser.FileReport{Path: "file", Status: megabrowser.FileDownloaded, Bytes: 1, Source: megabrowser.SourceMega}, report.Files[0])
+	assert.Contains(t, stderr.String(), errMock.Error())
+}
+
+func TestRunWritesMetricsFile(t *testing.T) {
+	metricsPath := filepath.Join(t.TempDir(), "megabrowser.prom")
+	env := validEnv()
+	env[megabrowser.EnvMetricsFile] = metricsPath
+	var stdout, stderr bytes.Buffer
+	newBrowser := func(cfg *megabrowser.Config) (browser, error) {
+		return &mockBrowser{errInitialize: errMock, metrics: megabrowser.NewMetrics()}, nil
+	}
+
+	code := run([]string{"sync"}, mapGetenv(env), &stdout, &stderr, newBrowser)
+
+	assert.Equal(t, 1, code)
+	content, err := os.ReadFile(metricsPath)
+	require.Nil(t, err)
+	assert.Contains(t, string(content), "megabrowser_files_total{result=\"failed\"} 0\n")
+}
+
+// mockReport reports entries needing an update, or every entry if all is true, as downloaded from Mega, and the other ones as skipped.
+func mockReport(entries []megabrowser.SyncEntry, all bool) *megabrowser.UpdateReport {
+	report := &megabrowser.UpdateReport{Files: []megabrowser.FileReport{}}
+	for _, entry := range entries {
+		file := megabrowser.FileReport{Path: entry.Path, Status: megabrowser.FileSkipped, Bytes: entry.RemoteSize}
+		if all || entry.NeedsUpdate() {
+			file.Status = megabrowser.FileDownloaded
+			file.Source = megabrowser.SourceMega
+		}
+		report.Add(file)
+	}
+	return report
+}
+
+func validEnv() map[string]string {
+	return map[string]string{
+		megabrowser.EnvLogin:    "login",
+		megabrowser.EnvPassword: "pass",
+		megabrowser.EnvRootNode: "root",
+	}
+}
+
+func mapGetenv(env map[string]string) func(string) string {
+	return func(key string) string {
+		return env[key]
+	}
+}
+
+func (m *mockBrowser) Initialize() error {
+	return m.errInitialize
+}
+
+func (m *mockBrowser) ListDirectory(path string) ([]megabrowser.Node, error) {
+	return []megabrowser.Node{&mockNode{name: "file", hash: "hash", size: 1}}, nil
+}
+
+func (m *mockBrowser) Stat(path string) (megabrowser.Node, error) {
+	return &mockNode{name: path, hash: "hash", size: 1}, nil
+}
+
+func (m *mockBrowser) UpdateFileFromPathReport(ctx context.Context, file string, localDownloadPath string) (*megabrowser.UpdateReport, error) {
+	m.localPath = localDownloadPath
+	return mockReport([]megabrowser.SyncEntry{{Path: file, RemoteSize: 1}}, true), nil
+}
+
+func (m *mockBrowser) ListVersions(path string) ([]megabrowser.Node, error) {
+	return []megabrowser.Node{&mockNode{name: path, hash: "v2", size: 2}, &mockNode{name: path, hash: "v1", size: 1}}, nil
+}
+
+func (m *mockBrowser) UpdateFileFromVersionReport(path string, versionHash string, localDownloadPath string) (*megabrowser.UpdateReport, error) {
+	m.localPath = localDownloadPath
+	return mockReport([]megabrowser.SyncEntry{{Path: path, RemoteSize: 1}}, true), nil
+}
+
+func (m *mockBrowser) Plan(localDir string) ([]megabrowser.SyncEntry, error) {
+	m.plannedDir = localDir
+	return m.entries, nil
+}
+
+func (m *mockBrowser) Verify(localDir string) ([]megabrowser.SyncEntry, error) {
+	return m.Plan(localDir)
+}
+
+func (m *mockBrowser) SyncReport(ctx context.Context, localDir string) (*megabrowser.UpdateReport, error) {
+	return mockReport(m.entries, false), m.errSync
+}
+
+func (m *mockBrowser) ListReleases() ([]string, error) {
+	return []string{"1.10.0", "1.4.0"}, nil
+}
+
+func (m *mockBrowser) RollbackReport(localDir string) (*megabrowser.UpdateReport, error) {
+	return mockReport(m.entries, true), nil
+}
+
+func (m *mockBrowser) Unpin(localDir string) error {
+	return nil
+}
+
+func (m *mockBrowser) Release() string {
+	return "1.10.0"
+}
+
+func (m *mockBrowser) Peers() *megabrowser.Peers {
+	return nil
+}
+
+func (m *mockBrowser) Quota() (megabrowser.Quota, error) {
+	return megabrowser.Quota{StorageUsed: 250, StorageTotal: 1000, TransferUsed: 300, TransferTotal: 5000}, nil
+}
+
+func (m *mockBrowser) Metrics() *megabrowser.Metrics {
+	return m.metrics
+}
+
+func (m *mockNode) GetName() string {
+	return m.name
+}
+
+func (m *mockNode) GetType() int {
+	return 0
+}
+
+func (m *mockNode) GetHash() string {
+	return m.hash
+}
+
+func (m *mockNode) GetSize() int64 {
+	return m.size
+}
+
+func (m *mockNode) GetTimeStamp() time.Time {
+	return time.Time{}
+}
+
+func (m *mockNode) GetParentHash() string {
+	return ""
+}
+
+func (m *mockNode) GetPath() string {
+	return ""
+}
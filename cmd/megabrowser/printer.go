@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	megabrowser "Mic-Cie/mega-browser/MegaBrowser"
+)
+
+// printer writes command results either as human readable text or as JSON.
+type printer struct {
+	out  io.Writer
+	json bool
+}
+
+// nodeInfo is the JSON representation of a node.
+type nodeInfo struct {
+	Name    string `json:"name"`
+	Hash    string `json:"hash"`
+	Type    string `json:"type"`
+	Size    int64  `json:"size"`
+	Path    string `json:"path,omitempty"`
+	Parent  string `json:"parent,omitempty"`
+	ModTime string `json:"modTime,omitempty"`
+}
+
+func newPrinter(out io.Writer, jsonOutput bool) *printer {
+	return &printer{
+		out:  out,
+		json: jsonOutput,
+	}
+}
+
+func (p *printer) message(msg string) error {
+	if p.json {
+		return p.encode(map[string]string{"message": msg})
+	}
+	_, err := fmt.Fprintln(p.out, msg)
+	return err
+}
+
+func (p *printer) node(node megabrowser.Node) error {
+	if p.json {
+		return p.encode(toNodeInfo(node))
+	}
+	return p.nodes([]megabrowser.Node{node})
+}
+
+func (p *printer) nodes(nodes []megabrowser.Node) error {
+	if p.json {
+		infos := make([]nodeInfo, len(nodes))
+		for i, node := range nodes {
+			infos[i] = toNodeInfo(node)
+		}
+		return p.encode(infos)
+	}
+	w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	for _, node := range nodes {
+		info := toNodeInfo(node)
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", info.Type, info.Size, info.Hash, info.Name)
+	}
+	return w.Flush()
+}
+
+// entries prints every entry in JSON mode, otherwise only the ones that need an update, followed by a summary line.
+func (p *printer) entries(entries []megabrowser.SyncEntry) error {
+	if p.json {
+		if entries == nil {
+			entries = []megabrowser.SyncEntry{}
+		}
+		return p.encode(entries)
+	}
+	outdated := 0
+	w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	for _, entry := range entries {
+		if entry.NeedsUpdate() {
+			outdated++
+			fmt.Fprintf(w, "%s\t%d\t%s\n", entry.Status, entry.RemoteSize, entry.Path)
+		}
+	}
+	fmt.Fprintf(w, "%d of %d files need an update\n", outdated, len(entries))
+	return w.Flush()
+}
+
+// report prints every downloaded, failed or deleted file of the report, with the error of failed ones, followed by its summary, or the whole report in JSON mode.
+func (p *printer) report(report *megabrowser.UpdateReport) error {
+	if p.json {
+		return p.encode(report)
+	}
+	w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	for _, file := range report.Files {
+		if file.Status != megabrowser.FileSkipped {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s", file.Status, file.Bytes, file.Source, file.Path)
+			if file.Error != "" {
+				fmt.Fprintf(w, "\t%s", file.Error)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	fmt.Fprintln(w, report.String())
+	return w.Flush()
+}
+
+// releases prints versions of the releases, marking the current one with an asterisk.
+func (p *printer) releases(versions []string, current string) error {
+	if p.json {
+		if versions == nil {
+			versions = []string{}
+		}
+		return p.encode(map[string]interface{}{"current": current, "releases": versions})
+	}
+	for _, version := range versions {
+		marker := " "
+		if version == current {
+			marker = "*"
+		}
+		if _, err := fmt.Fprintf(p.out, "%s %s\n", marker, version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// quota prints the storage and transfer used and available in the account. The transfer total is left out, if the account has no transfer limit.
+func (p *printer) quota(quota megabrowser.Quota) error {
+	if p.json {
+		return p.encode(quota)
+	}
+	_, err := fmt.Fprintf(p.out, "storage: %d of %d bytes used\n", quota.StorageUsed, quota.StorageTotal)
+	if err != nil {
+		return err
+	}
+	if quota.TransferTotal == 0 {
+		_, err = fmt.Fprintf(p.out, "transfer: %d bytes used\n", quota.TransferUsed)
+		return err
+	}
+	_, err = fmt.Fprintf(p.out, "transfer: %d of %d bytes used\n", quota.TransferUsed, quota.TransferTotal)
+	return err
+}
+
+func (p *printer) encode(v interface{}) error {
+	encoder := json.NewEncoder(p.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func toNodeInfo(node megabrowser.Node) nodeInfo {
+	info := nodeInfo{
+		Name:   node.GetName(),
+		Hash:   node.GetHash(),
+		Type:   megabrowser.NodeTypeName(node.GetType()),
+		Size:   node.GetSize(),
+		Path:   node.GetPath(),
+		Parent: node.GetParentHash(),
+	}
+	if modTime := node.GetTimeStamp(); !modTime.IsZero() {
+		info.ModTime = modTime.UTC().Format(time.RFC3339)
+	}
+	return info
+}
@@ -0,0 +1,5 @@
+module github.com/t3rm1n4l/go-mega
+
+require golang.org/x/crypto v0.1.0
+
+go 1.13
@@ -0,0 +1,38 @@
+package mega
+
+import "time"
+
+// Session returns the session id, the master key and the user handle
+// of a client logged in with Login or MultiFactorLogin, so that the
+// session can be restored with ResumeSession without the password.
+// The session id is empty, if the client is not logged in.
+func (m *Mega) Session() (sid string, key []byte, uh []byte) {
+	return m.sid, append([]byte(nil), m.k...), append([]byte(nil), m.uh...)
+}
+
+// ResumeSession restores a session returned by Session and loads the
+// filesystem the same way MultiFactorLogin does after authenticating.
+//
+// The client is left logged out, if the filesystem could not be
+// loaded, e.g. because the session expired, see ESID.
+func (m *Mega) ResumeSession(sid string, key []byte, uh []byte) error {
+	if sid == "" || len(key) != 16 {
+		return EARGS
+	}
+	m.sid = sid
+	m.k = append([]byte(nil), key...)
+	m.uh = append([]byte(nil), uh...)
+
+	waitEvent := m.WaitEventsStart()
+
+	err := m.getFileSystem()
+	if err != nil {
+		m.sid, m.k, m.uh = "", nil, nil
+		return err
+	}
+
+	// Wait until the all the pending events have been received
+	m.WaitEvents(waitEvent, 5*time.Second)
+
+	return nil
+}